@@ -13,6 +13,20 @@ DB_HOST=localhost
 
 VALKEY_ENDPOINT=localhost:6379
 
+# memory (single node) or valkey (fan-out across instances)
+BROADCAST_BACKEND=memory
+
+WS_WRITE_WAIT=10s
+WS_PONG_WAIT=60s
+WS_PING_INTERVAL=50s
+WS_SEND_QUEUE_SIZE=256
+WS_MAX_MESSAGE_SIZE=16384
+WS_ROOM_EVENT_LOG_SIZE=500
+WS_PRESENCE_DEBOUNCE=1s
+
+# How long authors can edit or delete their unanswered questions
+MESSAGE_EDIT_WINDOW=5m
+
 COOKIE_SECRET="fake-cookie-secret"
 ENCRYPT_KEY="0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
 
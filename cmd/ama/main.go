@@ -47,8 +47,15 @@ func main() {
 	roomService := service.NewRoomService(q)
 	messageService := service.NewMessageService(q)
 	userService := service.NewUserService(q)
-	wsService := service.NewWebSocketService()
-	h := web.NewHandler(roomService, messageService, userService, wsService)
+	importService := service.NewImportService(pool, q)
+	broadcaster, eventLog, presences := newBroadcastBackend(valkeyClient)
+	wsService := service.NewWebSocketService(broadcaster, eventLog, presences)
+	h := web.NewHandler(roomService, messageService, userService, importService, wsService)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go service.NewRoomScheduler(q, wsService).Run(ctx)
 
 	router := router.SetupRouter(h, userService, &valkeyClient)
 
@@ -70,6 +77,20 @@ func main() {
 	<-quit
 }
 
+func newBroadcastBackend(valkeyClient valkey.Client) (service.Broadcaster, service.EventLog, service.PresenceStore) {
+	logSize := service.RoomEventLogSizeFromEnv()
+
+	switch backend := os.Getenv("BROADCAST_BACKEND"); backend {
+	case "", "memory":
+		return service.NewLocalBroadcaster(), service.NewMemoryEventLog(logSize), service.NewMemoryPresenceStore()
+	case "valkey":
+		slog.Info("broadcasting websocket events through valkey")
+		return service.NewValkeyBroadcaster(valkeyClient), service.NewValkeyEventLog(valkeyClient, logSize), service.NewValkeyPresenceStore(valkeyClient)
+	default:
+		panic("invalid BROADCAST_BACKEND: " + backend)
+	}
+}
+
 func connectToDB() {
 	ctx := context.Background()
 	var err error
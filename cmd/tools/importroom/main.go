@@ -0,0 +1,97 @@
+// Command importroom imports a room exported as JSON or CSV for the user with
+// the given email, the same way the import endpoint does:
+//
+//	go run ./cmd/tools/importroom -owner host@example.com -file weekly-ama.csv
+//
+// The format is taken from the file extension unless -format is set.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/joho/godotenv"
+	"github.com/vhrboliveira/ama-go/internal/service"
+	"github.com/vhrboliveira/ama-go/internal/store/pgstore"
+)
+
+func main() {
+	file := flag.String("file", "", "path of the exported room")
+	owner := flag.String("owner", "", "email of the user the room is imported for")
+	format := flag.String("format", "", "format of the file, json or csv")
+	flag.Parse()
+
+	if *file == "" || *owner == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if *format == "" {
+		*format = strings.TrimPrefix(filepath.Ext(*file), ".")
+	}
+
+	if err := godotenv.Load(); err != nil {
+		slog.Error("error loading .env file.")
+		panic(err)
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, fmt.Sprintf(
+		"user=%s password=%s host=%s port=%s dbname=%s sslmode=disable",
+		os.Getenv("DB_USER"),
+		os.Getenv("DB_PASSWORD"),
+		os.Getenv("DB_HOST"),
+		os.Getenv("DB_PORT"),
+		os.Getenv("DB_NAME")),
+	)
+	if err != nil {
+		slog.Error("error connecting to database.")
+		panic(err)
+	}
+	defer pool.Close()
+
+	if err := run(ctx, pool, *file, *owner, *format); err != nil {
+		slog.Error("error importing room", "file", *file, "error", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, pool *pgxpool.Pool, path, owner, format string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	room, err := service.ParseRoomImport(f, format)
+	if err != nil {
+		return err
+	}
+
+	q := pgstore.New(pool)
+	user, err := q.GetUserByEmail(ctx, owner)
+	if err != nil {
+		return fmt.Errorf("finding user %s: %w", owner, err)
+	}
+
+	result, err := service.NewImportService(pool, q).Import(ctx, user.ID, room)
+	if err != nil {
+		return err
+	}
+
+	slog.Info(
+		"room imported",
+		"room_id", result.Room.ID,
+		"slug", result.Room.Slug,
+		"created", result.Created,
+		"imported", result.Imported,
+		"skipped", result.Skipped,
+	)
+	return nil
+}
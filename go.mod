@@ -14,6 +14,7 @@ require (
 	github.com/markbates/goth v1.80.0
 	github.com/stretchr/testify v1.9.0
 	github.com/valkey-io/valkey-go v1.0.45
+	github.com/vmihailenco/msgpack/v5 v5.4.1
 )
 
 require (
@@ -31,6 +32,7 @@ require (
 	github.com/jackc/puddle/v2 v2.2.1 // indirect
 	github.com/leodido/go-urn v1.4.0 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
 	golang.org/x/crypto v0.21.0 // indirect
 	golang.org/x/oauth2 v0.17.0 // indirect
 	golang.org/x/sync v0.7.0 // indirect
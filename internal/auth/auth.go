@@ -169,61 +169,84 @@ func SetSessionData(ctx context.Context, dbUser pgstore.User) error {
 
 func AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		session, err := store.Get(r, SessionName)
-		if err != nil {
-			slog.Error("error getting session", "error", err)
-			http.Error(w, "internal server error", http.StatusInternalServerError)
+		user, status, message := authenticate(r)
+		if status != http.StatusOK {
+			http.Error(w, message, status)
 			return
 		}
 
-		sessionID, ok := session.Values["sessionID"].(string)
-		if !ok {
-			slog.Error("unauthorized", "error", "sessionID not found in session")
-			http.Error(w, "unauthorized, session not found or invalid", http.StatusUnauthorized)
-			return
-		}
+		// Set user in request context
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), UserKey, user)))
+	})
+}
 
-		ctx := r.Context()
-		result := cache.Do(ctx, cache.B().Get().Key(sessionID).Build())
-		if result.Error() != nil {
-			slog.Error("unauthorized", "error", result.Error())
-			http.Error(w, "unauthorized", http.StatusUnauthorized)
-			return
-		}
+// VisitorMiddleware is AuthMiddleware for routers that also serve visitors
+// without a session. Unauthenticated requests accepted by allowVisitor go on
+// with no user in the context, and the others are rejected.
+func VisitorMiddleware(allowVisitor func(r *http.Request) bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, status, message := authenticate(r)
+			if status == http.StatusOK {
+				next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), UserKey, user)))
+				return
+			}
 
-		encryptedSession, err := result.ToString()
-		if err != nil {
-			slog.Error("Failed to convert result to string", "error", err)
-			http.Error(w, "unauthorized", http.StatusUnauthorized)
-			return
-		}
+			if status == http.StatusUnauthorized && allowVisitor(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
 
-		decryptedSession, err := Decrypt([]byte(encryptedSession))
-		if err != nil {
-			slog.Error("Failed to decrypt session", "error", err)
-			http.Error(w, "unauthorized", http.StatusUnauthorized)
-			return
-		}
+			http.Error(w, message, status)
+		})
+	}
+}
 
-		var userSessionValues pgstore.User
-		err = gob.NewDecoder(bytes.NewBuffer(decryptedSession)).Decode(&userSessionValues)
-		if err != nil {
-			slog.Error("failed to deserialize user data", "error", err)
-			http.Error(w, "unauthorized", http.StatusUnauthorized)
-			return
-		}
+// authenticate returns the user of the request session, or the status and
+// message to reject the request with.
+func authenticate(r *http.Request) (pgstore.User, int, string) {
+	session, err := store.Get(r, SessionName)
+	if err != nil {
+		slog.Error("error getting session", "error", err)
+		return pgstore.User{}, http.StatusInternalServerError, "internal server error"
+	}
 
-		if userSessionValues == (pgstore.User{}) || userSessionValues.ID == uuid.Nil {
-			http.Error(w, "unauthorized", http.StatusUnauthorized)
-			return
-		}
+	sessionID, ok := session.Values["sessionID"].(string)
+	if !ok {
+		slog.Error("unauthorized", "error", "sessionID not found in session")
+		return pgstore.User{}, http.StatusUnauthorized, "unauthorized, session not found or invalid"
+	}
 
-		// Set user in request context
-		ctx = context.WithValue(r.Context(), UserKey, userSessionValues)
-		r = r.WithContext(ctx)
+	result := cache.Do(r.Context(), cache.B().Get().Key(sessionID).Build())
+	if result.Error() != nil {
+		slog.Error("unauthorized", "error", result.Error())
+		return pgstore.User{}, http.StatusUnauthorized, "unauthorized"
+	}
 
-		next.ServeHTTP(w, r)
-	})
+	encryptedSession, err := result.ToString()
+	if err != nil {
+		slog.Error("Failed to convert result to string", "error", err)
+		return pgstore.User{}, http.StatusUnauthorized, "unauthorized"
+	}
+
+	decryptedSession, err := Decrypt([]byte(encryptedSession))
+	if err != nil {
+		slog.Error("Failed to decrypt session", "error", err)
+		return pgstore.User{}, http.StatusUnauthorized, "unauthorized"
+	}
+
+	var userSessionValues pgstore.User
+	err = gob.NewDecoder(bytes.NewBuffer(decryptedSession)).Decode(&userSessionValues)
+	if err != nil {
+		slog.Error("failed to deserialize user data", "error", err)
+		return pgstore.User{}, http.StatusUnauthorized, "unauthorized"
+	}
+
+	if userSessionValues == (pgstore.User{}) || userSessionValues.ID == uuid.Nil {
+		return pgstore.User{}, http.StatusUnauthorized, "unauthorized"
+	}
+
+	return userSessionValues, http.StatusOK, ""
 }
 
 func CallbackHandler(w http.ResponseWriter, r *http.Request) {
@@ -43,11 +43,17 @@ func SetupRouter(h *web.Handlers, userService *service.UserService, valkeyClient
 	router.Get("/logout", auth.LogoutHandler)
 
 	router.Group(func(router chi.Router) {
-		router.Use(auth.AuthMiddleware)
+		router.Use(auth.VisitorMiddleware(h.AllowsVisitor))
 
 		router.Route("/subscribe", func(router chi.Router) {
 			router.Get("/", h.SubscribeToRoomsList)
-			router.Get("/room/{room_id}", h.SubscribeToRoom)
+			router.With(h.ResolveRoomSlug(false)).Get("/room/{room_id}", h.SubscribeToRoom)
+			router.Get("/multiplex", h.SubscribeToTopics)
+
+			router.Route("/sse", func(router chi.Router) {
+				router.Get("/", h.StreamRoomsList)
+				router.With(h.ResolveRoomSlug(false)).Get("/room/{room_id}", h.StreamRoom)
+			})
 		})
 
 		router.Route("/api", func(router chi.Router) {
@@ -56,22 +62,61 @@ func SetupRouter(h *web.Handlers, userService *service.UserService, valkeyClient
 
 			router.Patch("/profile", h.UpdateProfile)
 
+			router.Post("/invites/{token}/accept", h.AcceptRoomInvite)
+
+			router.Get("/transfers", h.GetRoomTransfers)
+
+			router.Get("/follows", h.GetFollowedRooms)
+			router.Get("/notifications", h.GetNotifications)
+			router.Patch("/notifications/read", h.MarkNotificationsRead)
+
 			router.Route("/rooms", func(router chi.Router) {
 				router.Post("/", h.CreateRoom)
 				router.Get("/", h.GetRooms)
+				router.Post("/import", h.ImportRoom)
 
 				router.Route("/{room_id}", func(router chi.Router) {
+					router.Use(h.ResolveRoomSlug(true))
+
 					router.Get("/", h.GetRoom)
+					router.Patch("/", h.UpdateRoom)
+					router.Delete("/", h.DeleteRoom)
+					router.Patch("/status", h.SetRoomStatus)
+					router.Patch("/settings", h.UpdateRoomSettings)
+					router.Get("/stats", h.GetRoomStats)
+					router.Get("/export", h.ExportRoom)
 					router.Get("/reactions", h.GetRoomMessagesReactions)
+					router.Route("/members", func(router chi.Router) {
+						router.Get("/", h.GetRoomMembers)
+						router.Post("/", h.AddRoomMember)
+						router.Delete("/{user_id}", h.RemoveRoomMember)
+					})
+					router.Post("/follow", h.FollowRoom)
+					router.Delete("/follow", h.UnfollowRoom)
+					router.Route("/transfer", func(router chi.Router) {
+						router.Post("/", h.TransferRoom)
+						router.Delete("/", h.CancelRoomTransfer)
+						router.Post("/accept", h.AcceptRoomTransfer)
+					})
+					router.Route("/invites", func(router chi.Router) {
+						router.Post("/", h.CreateRoomInvite)
+						router.Get("/", h.GetRoomInvites)
+						router.Delete("/{token}", h.DeleteRoomInvite)
+					})
 					router.Route("/messages", func(router chi.Router) {
 						router.Post("/", h.CreateRoomMessage)
 						router.Get("/", h.GetRoomMessages)
 
 						router.Route("/{message_id}", func(router chi.Router) {
 							router.Get("/", h.GetRoomMessage)
+							router.Patch("/", h.UpdateRoomMessage)
+							router.Delete("/", h.DeleteRoomMessage)
 							router.Patch("/react", h.ReactionToMessage)
 							router.Delete("/react", h.RemoveReactionFromMessage)
 							router.Patch("/answer", h.SetMessageToAnswered)
+							router.Patch("/hide", h.SetMessageHidden)
+							router.Patch("/pin", h.PinMessage)
+							router.Delete("/pin", h.UnpinMessage)
 						})
 					})
 				})
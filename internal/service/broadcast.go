@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/valkey-io/valkey-go"
+	"github.com/vhrboliveira/ama-go/internal/types"
+)
+
+const (
+	valkeyBroadcastChannel = "ama:events"
+	valkeyRetryInterval    = time.Second
+)
+
+// Broadcaster fans out messages to the subscribers of every instance. Publish
+// sends the message to the backend and Listen registers the callback that
+// delivers received messages to the local subscribers of their topic.
+type Broadcaster interface {
+	Publish(ctx context.Context, msg types.Message) error
+	Listen(ctx context.Context, deliver func(types.Message))
+}
+
+// LocalBroadcaster delivers messages straight to the subscribers of the current
+// process. It is the default for single-node deployments.
+type LocalBroadcaster struct {
+	mutex   sync.RWMutex
+	deliver func(types.Message)
+}
+
+func NewLocalBroadcaster() *LocalBroadcaster {
+	return &LocalBroadcaster{}
+}
+
+func (b *LocalBroadcaster) Publish(_ context.Context, msg types.Message) error {
+	b.mutex.RLock()
+	deliver := b.deliver
+	b.mutex.RUnlock()
+
+	if deliver != nil {
+		deliver(msg)
+	}
+
+	return nil
+}
+
+func (b *LocalBroadcaster) Listen(_ context.Context, deliver func(types.Message)) {
+	b.mutex.Lock()
+	b.deliver = deliver
+	b.mutex.Unlock()
+}
+
+// ValkeyBroadcaster publishes messages to a Valkey channel so every instance
+// subscribed to it, including the publisher, delivers them to its clients.
+type ValkeyBroadcaster struct {
+	client  valkey.Client
+	channel string
+}
+
+type valkeyEnvelope struct {
+	Topic  string          `json:"topic"`
+	RoomID int64           `json:"room_id"`
+	Seq    int64           `json:"seq"`
+	Kind   string          `json:"kind"`
+	Value  json.RawMessage `json:"value"`
+}
+
+func NewValkeyBroadcaster(client valkey.Client) *ValkeyBroadcaster {
+	return &ValkeyBroadcaster{
+		client:  client,
+		channel: valkeyBroadcastChannel,
+	}
+}
+
+func (b *ValkeyBroadcaster) Publish(ctx context.Context, msg types.Message) error {
+	value, err := json.Marshal(msg.Value)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(valkeyEnvelope{
+		Topic:  msg.Topic,
+		RoomID: msg.RoomID,
+		Seq:    msg.Seq,
+		Kind:   msg.Kind,
+		Value:  value,
+	})
+	if err != nil {
+		return err
+	}
+
+	return b.client.Do(ctx, b.client.B().Publish().Channel(b.channel).Message(string(payload)).Build()).Error()
+}
+
+func (b *ValkeyBroadcaster) Listen(ctx context.Context, deliver func(types.Message)) {
+	go func() {
+		for {
+			err := b.client.Receive(ctx, b.client.B().Subscribe().Channel(b.channel).Build(), func(msg valkey.PubSubMessage) {
+				var envelope valkeyEnvelope
+				if err := json.Unmarshal([]byte(msg.Message), &envelope); err != nil {
+					slog.Error("failed to decode broadcast message", "error", err)
+					return
+				}
+
+				deliver(types.Message{
+					Kind:   envelope.Kind,
+					Value:  envelope.Value,
+					RoomID: envelope.RoomID,
+					Topic:  envelope.Topic,
+					Seq:    envelope.Seq,
+				})
+			})
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(valkeyRetryInterval):
+				slog.Error("broadcast subscription interrupted, reconnecting", "channel", b.channel, "error", err)
+			}
+		}
+	}()
+}
@@ -0,0 +1,177 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"github.com/vhrboliveira/ama-go/internal/types"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// WebSocket subprotocols a client can negotiate to pick the wire format.
+// Clients that do not ask for one get JSON.
+const (
+	SubprotocolJSON    = "ama.json"
+	SubprotocolMsgpack = "ama.msgpack"
+)
+
+// codec is a wire format for the messages sent to and the commands received
+// from a client.
+type codec struct {
+	messageType int
+	marshal     func(msg types.Message) ([]byte, error)
+	unmarshal   func(data []byte, cmd *types.Command) error
+}
+
+var (
+	jsonCodec = &codec{
+		messageType: websocket.TextMessage,
+		marshal: func(msg types.Message) ([]byte, error) {
+			return json.Marshal(msg)
+		},
+		unmarshal: func(data []byte, cmd *types.Command) error {
+			return json.Unmarshal(data, cmd)
+		},
+	}
+
+	msgpackCodec = &codec{
+		messageType: websocket.BinaryMessage,
+		marshal:     marshalMsgpack,
+		unmarshal:   unmarshalMsgpack,
+	}
+)
+
+func codecFor(subprotocol string) *codec {
+	if subprotocol == SubprotocolMsgpack {
+		return msgpackCodec
+	}
+
+	return jsonCodec
+}
+
+// marshalMsgpack encodes the message with the same field names as its JSON
+// form, so both formats share one schema.
+func marshalMsgpack(msg types.Message) ([]byte, error) {
+	// Messages received from the Valkey broadcaster carry their value as raw
+	// JSON, which msgpack would otherwise encode as an opaque byte string
+	if raw, ok := msg.Value.(json.RawMessage); ok {
+		var value any
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return nil, err
+		}
+		msg.Value = value
+	}
+
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+	enc.UseCompactInts(true)
+
+	if err := enc.Encode(msg); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// unmarshalMsgpack decodes a command and turns its value back into JSON, which
+// is what the command handlers decode.
+func unmarshalMsgpack(data []byte, cmd *types.Command) error {
+	var frame struct {
+		Kind      string `json:"kind"`
+		RequestID string `json:"request_id"`
+		Value     any    `json:"value"`
+	}
+
+	dec := msgpack.NewDecoder(bytes.NewReader(data))
+	dec.SetCustomStructTag("json")
+
+	if err := dec.Decode(&frame); err != nil {
+		return err
+	}
+
+	value, err := json.Marshal(frame.Value)
+	if err != nil {
+		return err
+	}
+
+	cmd.Kind = frame.Kind
+	cmd.RequestID = frame.RequestID
+	cmd.Value = value
+	return nil
+}
+
+// Frame is a message on its way to one or more subscribers. A broadcast
+// shares a single Frame between every subscriber, so the message is encoded
+// at most once per wire format however large the audience is.
+type Frame struct {
+	Message types.Message
+	// closeReason, when set, makes the frame end the connection instead of
+	// carrying a message.
+	closeReason string
+
+	mu       sync.Mutex
+	encoded  map[*codec][]byte
+	prepared map[*codec]*websocket.PreparedMessage
+}
+
+func newFrame(msg types.Message) *Frame {
+	return &Frame{Message: msg}
+}
+
+// encode returns the message in the codec format.
+func (f *Frame) encode(c *codec) ([]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.encodeLocked(c)
+}
+
+func (f *Frame) encodeLocked(c *codec) ([]byte, error) {
+	if data, ok := f.encoded[c]; ok {
+		return data, nil
+	}
+
+	data, err := c.marshal(f.Message)
+	if err != nil {
+		return nil, err
+	}
+
+	if f.encoded == nil {
+		f.encoded = make(map[*codec][]byte)
+	}
+	f.encoded[c] = data
+
+	return data, nil
+}
+
+// prepare returns the message as a WebSocket frame in the codec format. The
+// prepared message also caches the compressed frame, so permessage-deflate
+// runs once per format rather than once per connection.
+func (f *Frame) prepare(c *codec) (*websocket.PreparedMessage, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if pm, ok := f.prepared[c]; ok {
+		return pm, nil
+	}
+
+	data, err := f.encodeLocked(c)
+	if err != nil {
+		return nil, err
+	}
+
+	pm, err := websocket.NewPreparedMessage(c.messageType, data)
+	if err != nil {
+		return nil, err
+	}
+
+	if f.prepared == nil {
+		f.prepared = make(map[*codec]*websocket.PreparedMessage)
+	}
+	f.prepared[c] = pm
+
+	return pm, nil
+}
@@ -0,0 +1,251 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/valkey-io/valkey-go"
+	"github.com/vhrboliveira/ama-go/internal/types"
+)
+
+const (
+	defaultRoomEventLogSize = 500
+	valkeyEventLogTTL       = 24 * time.Hour
+)
+
+// EventLog assigns a monotonic sequence number to every room event and keeps
+// the most recent ones so reconnecting clients can replay what they missed.
+type EventLog interface {
+	// Append stamps the message with the next sequence of its room and stores it.
+	Append(ctx context.Context, msg types.Message) (types.Message, error)
+	// Since returns the room events after the given sequence. complete is false
+	// when some of those events are no longer in the log and the client must
+	// refetch the room state instead of replaying.
+	Since(ctx context.Context, roomID, seq int64) (events []types.Message, complete bool, err error)
+	// Current returns the last sequence assigned to the room.
+	Current(ctx context.Context, roomID int64) (int64, error)
+	// Drop forgets the sequence and events of a deleted room.
+	Drop(ctx context.Context, roomID int64) error
+}
+
+func RoomEventLogSizeFromEnv() int {
+	return intFromEnv("WS_ROOM_EVENT_LOG_SIZE", defaultRoomEventLogSize)
+}
+
+type memoryRoomLog struct {
+	seq    int64
+	events []types.Message
+}
+
+// MemoryEventLog keeps a bounded per-room event log in process memory.
+type MemoryEventLog struct {
+	mutex sync.Mutex
+	size  int
+	rooms map[int64]*memoryRoomLog
+}
+
+func NewMemoryEventLog(size int) *MemoryEventLog {
+	return &MemoryEventLog{
+		size:  size,
+		rooms: make(map[int64]*memoryRoomLog),
+	}
+}
+
+func (l *MemoryEventLog) Append(_ context.Context, msg types.Message) (types.Message, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	room, ok := l.rooms[msg.RoomID]
+	if !ok {
+		room = &memoryRoomLog{}
+		l.rooms[msg.RoomID] = room
+	}
+
+	room.seq++
+	msg.Seq = room.seq
+
+	room.events = append(room.events, msg)
+	if len(room.events) > l.size {
+		room.events = room.events[len(room.events)-l.size:]
+	}
+
+	return msg, nil
+}
+
+func (l *MemoryEventLog) Since(_ context.Context, roomID, seq int64) ([]types.Message, bool, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	room, ok := l.rooms[roomID]
+	if !ok {
+		return eventsSince(nil, 0, seq)
+	}
+
+	return eventsSince(room.events, room.seq, seq)
+}
+
+func (l *MemoryEventLog) Current(_ context.Context, roomID int64) (int64, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	room, ok := l.rooms[roomID]
+	if !ok {
+		return 0, nil
+	}
+
+	return room.seq, nil
+}
+
+func (l *MemoryEventLog) Drop(_ context.Context, roomID int64) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	delete(l.rooms, roomID)
+	return nil
+}
+
+// ValkeyEventLog shares sequences and the per-room event log between instances.
+type ValkeyEventLog struct {
+	client valkey.Client
+	size   int
+}
+
+// appendEventScript increments the room sequence and pushes the event in a
+// single step, so the log is always ordered by sequence.
+var appendEventScript = valkey.NewLuaScript(`
+local seq = redis.call('INCR', KEYS[1])
+redis.call('RPUSH', KEYS[2], seq .. ':' .. ARGV[1])
+redis.call('LTRIM', KEYS[2], -tonumber(ARGV[2]), -1)
+redis.call('EXPIRE', KEYS[2], ARGV[3])
+return seq
+`)
+
+type valkeyLoggedEvent struct {
+	Kind  string          `json:"kind"`
+	Value json.RawMessage `json:"value"`
+}
+
+func NewValkeyEventLog(client valkey.Client, size int) *ValkeyEventLog {
+	return &ValkeyEventLog{
+		client: client,
+		size:   size,
+	}
+}
+
+func roomSeqKey(roomID int64) string {
+	return "ama:room:" + strconv.FormatInt(roomID, 10) + ":seq"
+}
+
+func roomEventsKey(roomID int64) string {
+	return "ama:room:" + strconv.FormatInt(roomID, 10) + ":events"
+}
+
+func (l *ValkeyEventLog) Append(ctx context.Context, msg types.Message) (types.Message, error) {
+	value, err := json.Marshal(msg.Value)
+	if err != nil {
+		return msg, err
+	}
+
+	payload, err := json.Marshal(valkeyLoggedEvent{Kind: msg.Kind, Value: value})
+	if err != nil {
+		return msg, err
+	}
+
+	seq, err := appendEventScript.Exec(ctx, l.client,
+		[]string{roomSeqKey(msg.RoomID), roomEventsKey(msg.RoomID)},
+		[]string{string(payload), strconv.Itoa(l.size), strconv.Itoa(int(valkeyEventLogTTL.Seconds()))},
+	).AsInt64()
+	if err != nil {
+		return msg, err
+	}
+
+	msg.Seq = seq
+	return msg, nil
+}
+
+func (l *ValkeyEventLog) Since(ctx context.Context, roomID, seq int64) ([]types.Message, bool, error) {
+	results := l.client.DoMulti(ctx,
+		l.client.B().Get().Key(roomSeqKey(roomID)).Build(),
+		l.client.B().Lrange().Key(roomEventsKey(roomID)).Start(0).Stop(-1).Build(),
+	)
+
+	current, err := results[0].AsInt64()
+	if err != nil && !valkey.IsValkeyNil(err) {
+		return nil, false, err
+	}
+
+	entries, err := results[1].AsStrSlice()
+	if err != nil && !valkey.IsValkeyNil(err) {
+		return nil, false, err
+	}
+
+	events := make([]types.Message, 0, len(entries))
+	for _, entry := range entries {
+		rawSeq, payload, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, false, errors.New("malformed room event")
+		}
+
+		eventSeq, err := strconv.ParseInt(rawSeq, 10, 64)
+		if err != nil {
+			return nil, false, err
+		}
+
+		var logged valkeyLoggedEvent
+		if err := json.Unmarshal([]byte(payload), &logged); err != nil {
+			return nil, false, err
+		}
+
+		events = append(events, types.Message{
+			Kind:   logged.Kind,
+			Value:  logged.Value,
+			RoomID: roomID,
+			Topic:  types.RoomTopic(roomID),
+			Seq:    eventSeq,
+		})
+
+		current = max(current, eventSeq)
+	}
+
+	return eventsSince(events, current, seq)
+}
+
+func (l *ValkeyEventLog) Current(ctx context.Context, roomID int64) (int64, error) {
+	seq, err := l.client.Do(ctx, l.client.B().Get().Key(roomSeqKey(roomID)).Build()).AsInt64()
+	if valkey.IsValkeyNil(err) {
+		return 0, nil
+	}
+
+	return seq, err
+}
+
+func (l *ValkeyEventLog) Drop(ctx context.Context, roomID int64) error {
+	return l.client.Do(ctx, l.client.B().Del().Key(roomSeqKey(roomID), roomEventsKey(roomID)).Build()).Error()
+}
+
+// eventsSince picks the events after seq from a log ordered by sequence, where
+// current is the last sequence assigned to the room.
+func eventsSince(events []types.Message, current, seq int64) ([]types.Message, bool, error) {
+	if seq > current {
+		return []types.Message{}, false, nil
+	}
+
+	i := sort.Search(len(events), func(i int) bool {
+		return events[i].Seq > seq
+	})
+
+	missed := make([]types.Message, len(events)-i)
+	copy(missed, events[i:])
+
+	if seq < current && (len(missed) == 0 || missed[0].Seq != seq+1) {
+		return missed, false, nil
+	}
+
+	return missed, true, nil
+}
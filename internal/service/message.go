@@ -5,32 +5,56 @@ import (
 	"errors"
 	"log/slog"
 	"net/http"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/vhrboliveira/ama-go/internal/store/pgstore"
 )
 
+const defaultMessageEditWindow = 5 * time.Minute
+
 type MessageService struct {
 	Queries *pgstore.Queries
+	// EditWindow is how long after asking a question its author can still
+	// change or withdraw it.
+	EditWindow time.Duration
 }
 
 func NewMessageService(queries *pgstore.Queries) *MessageService {
-	return &MessageService{Queries: queries}
+	return &MessageService{
+		Queries:    queries,
+		EditWindow: durationFromEnv("MESSAGE_EDIT_WINDOW", defaultMessageEditWindow),
+	}
 }
 
-func (s *MessageService) CreateMessage(ctx context.Context, roomID int64, msg string) (pgstore.InsertMessageRow, error) {
+// CreateMessage records the user as the author of the message. The author of
+// an anonymous message is kept for moderation but only shown to them and the
+// room hosts.
+func (s *MessageService) CreateMessage(ctx context.Context, roomID int64, userID uuid.UUID, msg string, anonymous bool) (pgstore.InsertMessageRow, error) {
 	message, err := s.Queries.InsertMessage(ctx, pgstore.InsertMessageParams{
-		RoomID:  roomID,
-		Message: msg,
+		RoomID:    roomID,
+		Message:   msg,
+		UserID:    pgtype.UUID{Bytes: userID, Valid: true},
+		Anonymous: anonymous,
 	})
 
 	return message, err
 }
 
-func (s *MessageService) GetMessages(ctx context.Context, roomID int64) ([]pgstore.GetRoomMessagesRow, error) {
-	roomMessages, err := s.Queries.GetRoomMessages(ctx, roomID)
+// GetMessages returns the room messages as the viewer sees them. The room
+// hosts, for whom canModerate is set, also get the hidden messages and the
+// authors of the anonymous ones, which are otherwise only shown to their
+// authors.
+func (s *MessageService) GetMessages(ctx context.Context, roomID int64, viewerID uuid.UUID, canModerate bool) ([]pgstore.GetRoomMessagesRow, error) {
+	roomMessages, err := s.Queries.GetRoomMessages(ctx, pgstore.GetRoomMessagesParams{
+		RoomID:          roomID,
+		ViewerID:        pgtype.UUID{Bytes: viewerID, Valid: viewerID != uuid.Nil},
+		RevealAnonymous: canModerate,
+		IncludeHidden:   canModerate,
+	})
 
 	if roomMessages == nil {
 		roomMessages = []pgstore.GetRoomMessagesRow{}
@@ -52,8 +76,9 @@ func (s *MessageService) GetMessage(ctx context.Context, messageID uuid.UUID) (p
 	return message, err
 }
 
-func (s *MessageService) CheckMessageExists(ctx context.Context, messageID uuid.UUID) (int, error) {
-	_, err := s.Queries.GetMessage(ctx, messageID)
+// CheckMessageExists reports whether the message exists in the room.
+func (s *MessageService) CheckMessageExists(ctx context.Context, roomID int64, messageID uuid.UUID) (int, error) {
+	message, err := s.Queries.GetMessage(ctx, messageID)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			slog.Error("message not found", "error", err)
@@ -64,6 +89,11 @@ func (s *MessageService) CheckMessageExists(ctx context.Context, messageID uuid.
 		return http.StatusInternalServerError, errors.New("error validating message ID")
 	}
 
+	if message.RoomID != roomID {
+		slog.Error("message not found in the room", "room_id", roomID, "message_id", messageID)
+		return http.StatusNotFound, errors.New("message not found")
+	}
+
 	return http.StatusOK, nil
 }
 
@@ -134,13 +164,125 @@ func (s *MessageService) GetRoomMessagesReactions(ctx context.Context, roomID in
 	return ids, err
 }
 
+// AnswerMessage answers the message and notifies the followers of its room
+// who reacted to it. A message is only answered once.
 func (s *MessageService) AnswerMessage(ctx context.Context, messageID uuid.UUID, answer string) error {
 	params := pgstore.AnswerMessageParams{
 		ID:     messageID,
 		Answer: answer,
 	}
 
-	_, err := s.Queries.AnswerMessage(ctx, params)
+	if _, err := s.Queries.AnswerMessage(ctx, params); err != nil {
+		return err
+	}
+
+	if _, err := s.Queries.InsertMessageAnsweredNotifications(ctx, messageID); err != nil {
+		slog.Error("error notifying the room followers", "message_id", messageID, "error", err)
+	}
+
+	return nil
+}
+
+func (s *MessageService) HideMessage(ctx context.Context, messageID uuid.UUID, hidden bool) error {
+	_, err := s.Queries.SetMessageHidden(ctx, pgstore.SetMessageHiddenParams{
+		Hidden: hidden,
+		ID:     messageID,
+	})
 
 	return err
 }
+
+// PinMessage makes the message the one the hosts of its room are answering
+// now, in place of the one pinned before. Hidden messages cannot be pinned.
+func (s *MessageService) PinMessage(ctx context.Context, messageID, userID uuid.UUID) (int, error) {
+	_, err := s.Queries.PinMessage(ctx, pgstore.PinMessageParams{PinnedBy: userID, MessageID: messageID})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			slog.Error("hidden messages cannot be pinned", "message_id", messageID)
+			return http.StatusBadRequest, errors.New("hidden questions cannot be pinned")
+		}
+
+		slog.Error("error pinning message", "message_id", messageID, "error", err)
+		return http.StatusInternalServerError, errors.New("error pinning message")
+	}
+
+	return http.StatusOK, nil
+}
+
+// UpdateOwnMessage changes the text of a question of the user. Authors can
+// only change their questions within the edit window and while they are
+// unanswered.
+func (s *MessageService) UpdateOwnMessage(ctx context.Context, roomID int64, messageID, userID uuid.UUID, msg string) (pgstore.Message, int, error) {
+	message, err := s.Queries.UpdateOwnMessage(ctx, pgstore.UpdateOwnMessageParams{
+		Message:           msg,
+		ID:                messageID,
+		RoomID:            roomID,
+		UserID:            pgtype.UUID{Bytes: userID, Valid: true},
+		EditWindowSeconds: int32(s.EditWindow / time.Second),
+	})
+	if err == nil {
+		return message, http.StatusOK, nil
+	}
+
+	if !errors.Is(err, pgx.ErrNoRows) {
+		slog.Error("error updating message", "message_id", messageID, "error", err)
+		return pgstore.Message{}, http.StatusInternalServerError, errors.New("error updating message")
+	}
+
+	status, err := s.lockedMessageError(ctx, roomID, messageID, userID)
+	return pgstore.Message{}, status, err
+}
+
+// DeleteOwnMessage withdraws a question of the user, under the same rules as
+// UpdateOwnMessage, and reports whether it was the pinned one.
+func (s *MessageService) DeleteOwnMessage(ctx context.Context, roomID int64, messageID, userID uuid.UUID) (bool, int, error) {
+	pinned, err := s.Queries.DeleteOwnMessage(ctx, pgstore.DeleteOwnMessageParams{
+		ID:                messageID,
+		RoomID:            roomID,
+		UserID:            pgtype.UUID{Bytes: userID, Valid: true},
+		EditWindowSeconds: int32(s.EditWindow / time.Second),
+	})
+	if err == nil {
+		return pinned, http.StatusNoContent, nil
+	}
+
+	if !errors.Is(err, pgx.ErrNoRows) {
+		slog.Error("error deleting message", "message_id", messageID, "error", err)
+		return false, http.StatusInternalServerError, errors.New("error deleting message")
+	}
+
+	status, err := s.lockedMessageError(ctx, roomID, messageID, userID)
+	return false, status, err
+}
+
+// lockedMessageError tells why the user cannot change the message anymore.
+// Hidden messages are not found, as their authors no longer see them.
+func (s *MessageService) lockedMessageError(ctx context.Context, roomID int64, messageID, userID uuid.UUID) (int, error) {
+	message, err := s.Queries.GetMessage(ctx, messageID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		slog.Error("error checking if message exists", "error", err)
+		return http.StatusInternalServerError, errors.New("error validating message ID")
+	}
+
+	switch {
+	case err != nil || message.RoomID != roomID || message.Hidden:
+		slog.Error("message not found in the room", "room_id", roomID, "message_id", messageID)
+		return http.StatusNotFound, errors.New("message not found")
+	case !message.UserID.Valid || uuid.UUID(message.UserID.Bytes) != userID:
+		slog.Error("the user is not the author of the message", "message_id", messageID, "user_id", userID)
+		return http.StatusForbidden, errors.New("only the author can change the question")
+	case message.Answered:
+		slog.Error("the message has already been answered", "message_id", messageID)
+		return http.StatusConflict, errors.New("answered questions cannot be changed")
+	default:
+		slog.Error("the edit window of the message is over", "message_id", messageID)
+		return http.StatusConflict, errors.New("the time to change the question is over")
+	}
+}
+
+// UnpinMessage clears the pin of the room of the message, if it is the pinned
+// one, and reports whether it was.
+func (s *MessageService) UnpinMessage(ctx context.Context, messageID uuid.UUID) (bool, error) {
+	unpinned, err := s.Queries.UnpinMessage(ctx, messageID)
+	return unpinned > 0, err
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vhrboliveira/ama-go/internal/store/pgstore"
+	"github.com/vhrboliveira/ama-go/internal/types"
+)
+
+const defaultPresenceDebounce = time.Second
+
+// newViewer is how a subscriber is listed in the room presence. The photo is
+// left out unless the user chose to show it on their profile, and visitors
+// without a session are not listed at all.
+func newViewer(user pgstore.User) types.Viewer {
+	if user.ID == uuid.Nil {
+		return types.Viewer{}
+	}
+
+	viewer := types.Viewer{
+		ID:   user.ID.String(),
+		Name: user.Name,
+	}
+
+	if user.EnablePicture {
+		viewer.Photo = user.Photo
+	}
+
+	return viewer
+}
+
+// PresenceCount returns how many connections on every instance are watching
+// the room.
+func (w *WebSocketService) PresenceCount(ctx context.Context, roomID int64) (int, error) {
+	counts, err := w.Presences.Counts(ctx, []int64{roomID})
+	return counts[roomID], err
+}
+
+// PresenceCounts is PresenceCount for a page of rooms, in a single round trip.
+func (w *WebSocketService) PresenceCounts(ctx context.Context, roomIDs []int64) (map[int64]int, error) {
+	return w.Presences.Counts(ctx, roomIDs)
+}
+
+// localPresence returns the number of connections on this instance watching
+// the room and the users behind them. Visitors are only counted.
+func (w *WebSocketService) localPresence(roomID int64) types.PresenceChanged {
+	w.Mutex.RLock()
+	defer w.Mutex.RUnlock()
+
+	subscribers := w.Subscribers[types.RoomTopic(roomID)]
+	presence := types.PresenceChanged{
+		Count:   len(subscribers),
+		Viewers: make([]types.Viewer, 0, len(subscribers)),
+	}
+
+	for sub := range subscribers {
+		if sub.viewer.ID == "" {
+			continue
+		}
+		presence.Viewers = append(presence.Viewers, sub.viewer)
+	}
+
+	return mergePresence([]types.PresenceChanged{presence})
+}
+
+// localPresenceRooms returns the rooms this instance has viewers in.
+func (w *WebSocketService) localPresenceRooms() []int64 {
+	w.Mutex.RLock()
+	defer w.Mutex.RUnlock()
+
+	rooms := make([]int64, 0)
+	for topic, subscribers := range w.Subscribers {
+		if roomID, err := types.ParseTopic(topic); err == nil && roomID != 0 && len(subscribers) > 0 {
+			rooms = append(rooms, roomID)
+		}
+	}
+
+	return rooms
+}
+
+// presenceChanged schedules a presence_changed message for the room. Joins and
+// leaves within PresenceDebounce of each other are reported once, after the
+// room has settled.
+func (w *WebSocketService) presenceChanged(roomID int64) {
+	w.presenceMutex.Lock()
+	defer w.presenceMutex.Unlock()
+
+	if timer, ok := w.presenceTimers[roomID]; ok {
+		timer.Reset(w.PresenceDebounce)
+		return
+	}
+
+	w.presenceTimers[roomID] = time.AfterFunc(w.PresenceDebounce, func() {
+		w.presenceMutex.Lock()
+		delete(w.presenceTimers, roomID)
+		w.presenceMutex.Unlock()
+
+		w.notifyPresence(roomID)
+	})
+}
+
+// reportPresence hands the local presence of the room to the shared store.
+// Reports are serialized so an older snapshot never overwrites a newer one.
+func (w *WebSocketService) reportPresence(ctx context.Context, roomID int64) error {
+	w.reportMutex.Lock()
+	defer w.reportMutex.Unlock()
+
+	return w.Presences.Report(ctx, roomID, w.localPresence(roomID))
+}
+
+// notifyPresence reports the local presence and publishes the presence of the
+// room across all instances to its subscribers. It is not kept in the event
+// log: a reconnecting client gets a fresh one when it joins.
+func (w *WebSocketService) notifyPresence(roomID int64) {
+	ctx := context.Background()
+
+	if err := w.reportPresence(ctx, roomID); err != nil {
+		slog.Error("failed to report room presence", "room_id", roomID, "error", err)
+		return
+	}
+
+	presence, err := w.Presences.Room(ctx, roomID)
+	if err != nil {
+		slog.Error("failed to get room presence", "room_id", roomID, "error", err)
+		return
+	}
+
+	w.publish(types.Message{
+		Kind:   types.MessageKindPresenceChanged,
+		RoomID: roomID,
+		Topic:  types.RoomTopic(roomID),
+		Value:  presence,
+	})
+}
+
+// keepPresence reports the rooms this instance has viewers in again before
+// the store considers those reports stale, until ctx is done.
+func (w *WebSocketService) keepPresence(ctx context.Context) {
+	ticker := time.NewTicker(presenceRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, roomID := range w.localPresenceRooms() {
+				if err := w.reportPresence(ctx, roomID); err != nil {
+					slog.Error("failed to refresh room presence", "room_id", roomID, "error", err)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/valkey-io/valkey-go"
+	"github.com/vhrboliveira/ama-go/internal/types"
+)
+
+const (
+	valkeyPresenceTTL       = time.Minute
+	presenceRefreshInterval = 20 * time.Second
+)
+
+// PresenceStore merges the room presence reported by every instance, so the
+// viewer counts do not depend on which instance a client is connected to.
+type PresenceStore interface {
+	// Report replaces what this instance last reported for the room. An empty
+	// presence withdraws it.
+	Report(ctx context.Context, roomID int64, presence types.PresenceChanged) error
+	// Room returns the presence of the room across all instances.
+	Room(ctx context.Context, roomID int64) (types.PresenceChanged, error)
+	// Counts returns how many connections watch each of the rooms across all
+	// instances. Rooms nobody watches are left out.
+	Counts(ctx context.Context, roomIDs []int64) (map[int64]int, error)
+}
+
+// mergePresence adds up the reports of several instances, listing each user
+// once however many instances they are connected to.
+func mergePresence(reports []types.PresenceChanged) types.PresenceChanged {
+	presence := types.PresenceChanged{Viewers: []types.Viewer{}}
+
+	seen := make(map[string]struct{})
+	for _, report := range reports {
+		presence.Count += report.Count
+
+		for _, viewer := range report.Viewers {
+			if _, ok := seen[viewer.ID]; ok {
+				continue
+			}
+			seen[viewer.ID] = struct{}{}
+			presence.Viewers = append(presence.Viewers, viewer)
+		}
+	}
+
+	slices.SortFunc(presence.Viewers, func(a, b types.Viewer) int {
+		return cmp.Or(cmp.Compare(a.Name, b.Name), cmp.Compare(a.ID, b.ID))
+	})
+
+	return presence
+}
+
+// MemoryPresenceStore keeps the presence of a single instance in process
+// memory.
+type MemoryPresenceStore struct {
+	mutex sync.RWMutex
+	rooms map[int64]types.PresenceChanged
+}
+
+func NewMemoryPresenceStore() *MemoryPresenceStore {
+	return &MemoryPresenceStore{
+		rooms: make(map[int64]types.PresenceChanged),
+	}
+}
+
+func (s *MemoryPresenceStore) Report(_ context.Context, roomID int64, presence types.PresenceChanged) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if presence.Count == 0 {
+		delete(s.rooms, roomID)
+		return nil
+	}
+
+	s.rooms[roomID] = presence
+	return nil
+}
+
+func (s *MemoryPresenceStore) Room(_ context.Context, roomID int64) (types.PresenceChanged, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return mergePresence([]types.PresenceChanged{s.rooms[roomID]}), nil
+}
+
+func (s *MemoryPresenceStore) Counts(_ context.Context, roomIDs []int64) (map[int64]int, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	counts := make(map[int64]int, len(roomIDs))
+	for _, roomID := range roomIDs {
+		if presence, ok := s.rooms[roomID]; ok {
+			counts[roomID] = presence.Count
+		}
+	}
+
+	return counts, nil
+}
+
+// ValkeyPresenceStore keeps a hash per room with one field per instance.
+// Instances refresh their fields while they have viewers, and fields that were
+// not refreshed within valkeyPresenceTTL, left behind by an instance that
+// stopped, are ignored.
+type ValkeyPresenceStore struct {
+	client   valkey.Client
+	instance string
+}
+
+type valkeyPresenceReport struct {
+	types.PresenceChanged
+	ReportedAt int64 `json:"reported_at"`
+}
+
+func NewValkeyPresenceStore(client valkey.Client) *ValkeyPresenceStore {
+	return &ValkeyPresenceStore{
+		client:   client,
+		instance: uuid.NewString(),
+	}
+}
+
+func roomPresenceKey(roomID int64) string {
+	return "ama:room:" + strconv.FormatInt(roomID, 10) + ":presence"
+}
+
+func (s *ValkeyPresenceStore) Report(ctx context.Context, roomID int64, presence types.PresenceChanged) error {
+	key := roomPresenceKey(roomID)
+
+	if presence.Count == 0 {
+		return s.client.Do(ctx, s.client.B().Hdel().Key(key).Field(s.instance).Build()).Error()
+	}
+
+	payload, err := json.Marshal(valkeyPresenceReport{
+		PresenceChanged: presence,
+		ReportedAt:      time.Now().Unix(),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, result := range s.client.DoMulti(ctx,
+		s.client.B().Hset().Key(key).FieldValue().FieldValue(s.instance, string(payload)).Build(),
+		s.client.B().Expire().Key(key).Seconds(int64(valkeyPresenceTTL.Seconds())).Build(),
+	) {
+		if err := result.Error(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *ValkeyPresenceStore) Room(ctx context.Context, roomID int64) (types.PresenceChanged, error) {
+	fields, err := s.client.Do(ctx, s.client.B().Hgetall().Key(roomPresenceKey(roomID)).Build()).AsStrMap()
+	if err != nil {
+		return types.PresenceChanged{}, err
+	}
+
+	reports, err := freshPresenceReports(fields)
+	if err != nil {
+		return types.PresenceChanged{}, err
+	}
+
+	return mergePresence(reports), nil
+}
+
+func (s *ValkeyPresenceStore) Counts(ctx context.Context, roomIDs []int64) (map[int64]int, error) {
+	counts := make(map[int64]int, len(roomIDs))
+	if len(roomIDs) == 0 {
+		return counts, nil
+	}
+
+	cmds := make(valkey.Commands, 0, len(roomIDs))
+	for _, roomID := range roomIDs {
+		cmds = append(cmds, s.client.B().Hgetall().Key(roomPresenceKey(roomID)).Build())
+	}
+
+	for i, result := range s.client.DoMulti(ctx, cmds...) {
+		fields, err := result.AsStrMap()
+		if err != nil {
+			return nil, err
+		}
+
+		reports, err := freshPresenceReports(fields)
+		if err != nil {
+			return nil, err
+		}
+
+		if presence := mergePresence(reports); presence.Count > 0 {
+			counts[roomIDs[i]] = presence.Count
+		}
+	}
+
+	return counts, nil
+}
+
+// freshPresenceReports decodes the reports of a room hash, skipping those of
+// instances that stopped refreshing them.
+func freshPresenceReports(fields map[string]string) ([]types.PresenceChanged, error) {
+	oldest := time.Now().Add(-valkeyPresenceTTL).Unix()
+
+	reports := make([]types.PresenceChanged, 0, len(fields))
+	for _, payload := range fields {
+		var report valkeyPresenceReport
+		if err := json.Unmarshal([]byte(payload), &report); err != nil {
+			return nil, err
+		}
+
+		if report.ReportedAt < oldest {
+			continue
+		}
+		reports = append(reports, report.PresenceChanged)
+	}
+
+	return reports, nil
+}
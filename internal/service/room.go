@@ -3,14 +3,78 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/vhrboliveira/ama-go/internal/store/pgstore"
 )
 
+// Who can find and join a room. Public rooms are listed for everyone, unlisted
+// rooms are open to anyone with the link, and private rooms only to the owner
+// and the users who accepted one of its invites.
+const (
+	RoomVisibilityPublic   = "public"
+	RoomVisibilityUnlisted = "unlisted"
+	RoomVisibilityPrivate  = "private"
+)
+
+var RoomVisibilities = []string{RoomVisibilityPublic, RoomVisibilityUnlisted, RoomVisibilityPrivate}
+
+// The lifecycle of a room. Draft rooms are still being prepared by their
+// owner, scheduled rooms open on their own at opens_at, open rooms take
+// questions and reactions until their closes_at, if any, and closed and
+// archived rooms can only be read.
+const (
+	RoomStatusDraft     = "draft"
+	RoomStatusScheduled = "scheduled"
+	RoomStatusOpen      = "open"
+	RoomStatusClosed    = "closed"
+	RoomStatusArchived  = "archived"
+)
+
+var RoomStatuses = []string{RoomStatusDraft, RoomStatusScheduled, RoomStatusOpen, RoomStatusClosed, RoomStatusArchived}
+
+// RoomSchedule is the status of a room and the times the scheduler opens and
+// closes it at, nil when it does not.
+type RoomSchedule struct {
+	Status   string
+	OpensAt  *time.Time
+	ClosesAt *time.Time
+}
+
+func toTimestamp(t *time.Time) pgtype.Timestamp {
+	if t == nil {
+		return pgtype.Timestamp{}
+	}
+
+	return pgtype.Timestamp{Time: t.UTC(), Valid: true}
+}
+
+// currentRoomStatus is the status the room is in right now. The scheduler only
+// runs every so often, so a room may be past its opens_at or closes_at before
+// its status is updated.
+func currentRoomStatus(status string, opensAt, closesAt pgtype.Timestamp, now time.Time) string {
+	if status == RoomStatusScheduled && opensAt.Valid && !now.Before(opensAt.Time) {
+		status = RoomStatusOpen
+	}
+
+	if status == RoomStatusOpen && closesAt.Valid && !now.Before(closesAt.Time) {
+		status = RoomStatusClosed
+	}
+
+	return status
+}
+
+// likeEscaper makes the wildcards of a search term match literally.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
 type RoomService struct {
 	Queries *pgstore.Queries
 }
@@ -19,50 +83,296 @@ func NewRoomService(queries *pgstore.Queries) *RoomService {
 	return &RoomService{Queries: queries}
 }
 
-func (s *RoomService) CreateRoom(ctx context.Context, name string, userID uuid.UUID, description string) (pgstore.InsertRoomRow, error) {
-	room, err := s.Queries.InsertRoom(ctx, pgstore.InsertRoomParams{
+// CreateRoom inserts the room with a unique slug generated from its name.
+func (s *RoomService) CreateRoom(
+	ctx context.Context,
+	name string,
+	userID uuid.UUID,
+	description, visibility string,
+	schedule RoomSchedule,
+) (pgstore.InsertRoomRow, string, error) {
+	params := pgstore.InsertRoomParams{
 		Name:        name,
 		UserID:      userID,
 		Description: description,
-	})
+		Visibility:  visibility,
+		Status:      schedule.Status,
+		OpensAt:     toTimestamp(schedule.OpensAt),
+		ClosesAt:    toTimestamp(schedule.ClosesAt),
+	}
 
-	return room, err
+	for attempt := 1; ; attempt++ {
+		slug, err := s.uniqueSlug(ctx, Slugify(name), 0)
+		if err != nil {
+			return pgstore.InsertRoomRow{}, "", err
+		}
+		params.Slug = slug
+
+		room, err := s.Queries.InsertRoom(ctx, params)
+		if err != nil && isSlugViolation(err) && attempt < slugAttempts {
+			continue
+		}
+
+		return room, slug, err
+	}
 }
 
-func (s *RoomService) GetRooms(ctx context.Context) ([]pgstore.GetRoomsRow, error) {
-	rooms, err := s.Queries.GetRooms(ctx)
+// RoomsQuery filters and orders a page of the rooms list. Cursor is the next
+// cursor returned with the previous page, empty for the first one. Besides the
+// public rooms, the viewer sees the rooms they own or were invited to.
+type RoomsQuery struct {
+	ViewerID      uuid.UUID
+	Search        string
+	CreatorID     *uuid.UUID
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	Sort          string
+	Cursor        string
+	Limit         int32
+}
+
+// GetRooms returns a page of the rooms list and the cursor of the next page,
+// which is empty on the last one.
+func (s *RoomService) GetRooms(ctx context.Context, query RoomsQuery) ([]pgstore.GetRoomsRow, string, error) {
+	params := pgstore.GetRoomsParams{
+		ViewerID: query.ViewerID,
+		Sort:     query.Sort,
+		// One extra room tells whether there is a next page
+		PageSize: query.Limit + 1,
+	}
+
+	if query.Search != "" {
+		params.Search = pgtype.Text{String: likeEscaper.Replace(query.Search), Valid: true}
+	}
+	if query.CreatorID != nil {
+		params.CreatorID = pgtype.UUID{Bytes: *query.CreatorID, Valid: true}
+	}
+	if query.CreatedAfter != nil {
+		params.CreatedAfter = pgtype.Timestamp{Time: query.CreatedAfter.UTC(), Valid: true}
+	}
+	if query.CreatedBefore != nil {
+		params.CreatedBefore = pgtype.Timestamp{Time: query.CreatedBefore.UTC(), Valid: true}
+	}
+
+	if query.Cursor != "" {
+		cursor, err := decodeRoomsCursor(query.Cursor, query.Sort)
+		if err != nil {
+			return nil, "", err
+		}
+
+		params.CursorID = pgtype.Int8{Int64: cursor.ID, Valid: true}
+		if cursor.Time != nil {
+			params.CursorTime = pgtype.Timestamp{Time: *cursor.Time, Valid: true}
+		}
+		if cursor.Count != nil {
+			params.CursorCount = pgtype.Int8{Int64: *cursor.Count, Valid: true}
+		}
+	}
+
+	rooms, err := s.Queries.GetRooms(ctx, params)
+	if err != nil {
+		return nil, "", err
+	}
 
 	if rooms == nil {
 		rooms = []pgstore.GetRoomsRow{}
 	}
 
-	return rooms, err
+	var next string
+	if len(rooms) > int(query.Limit) {
+		rooms = rooms[:query.Limit]
+		next = newRoomsCursor(query.Sort, rooms[len(rooms)-1]).encode()
+	}
+
+	return rooms, next, nil
 }
 
-func (s *RoomService) GetRoom(ctx context.Context, roomID int64) (pgstore.GetRoomWithUserRow, error) {
+// GetRoom returns the room as seen by the user: a private room or a draft the
+// user has no access to is returned empty, as if it did not exist.
+func (s *RoomService) GetRoom(ctx context.Context, roomID int64, userID uuid.UUID) (pgstore.GetRoomWithUserRow, error) {
 	room, err := s.Queries.GetRoomWithUser(ctx, roomID)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			slog.Error("room not found", "error", err)
-			room = pgstore.GetRoomWithUserRow{}
-			err = nil
+			return pgstore.GetRoomWithUserRow{}, nil
 		}
+
+		return room, err
 	}
 
-	return room, err
+	if (room.Visibility != RoomVisibilityPrivate && room.Status != RoomStatusDraft) || (room.UserID.Valid && room.UserID.Bytes == userID) {
+		return room, nil
+	}
+
+	access, err := s.Queries.GetRoomAccess(ctx, pgstore.GetRoomAccessParams{ID: roomID, UserID: userID})
+	if err != nil {
+		return pgstore.GetRoomWithUserRow{}, err
+	}
+
+	if !canAccessRoom(access, userID) {
+		slog.Error("the user has no access to the room", "room_id", roomID, "user_id", userID)
+		return pgstore.GetRoomWithUserRow{}, nil
+	}
+
+	return room, nil
+}
+
+// CheckRoomAccess reports whether the room exists and the user can read and
+// post in it. Private rooms the user was not invited to, and drafts the user
+// does not host, are reported as not found, so their existence is not
+// disclosed.
+func (s *RoomService) CheckRoomAccess(ctx context.Context, roomID int64, userID uuid.UUID) (int, error) {
+	_, status, err := s.roomAccess(ctx, roomID, userID)
+	return status, err
 }
 
-func (s *RoomService) CheckRoomExists(ctx context.Context, roomID int64) (int, error) {
-	_, err := s.Queries.GetRoom(ctx, roomID)
+// CheckRoomOpen is CheckRoomAccess for the users adding input to the room,
+// which is only accepted while the room is open. input names what is being
+// added, for the error message.
+func (s *RoomService) CheckRoomOpen(ctx context.Context, roomID int64, userID uuid.UUID, input string) (int, error) {
+	access, status, err := s.roomAccess(ctx, roomID, userID)
+	if err != nil {
+		return status, err
+	}
+
+	return checkRoomOpen(access, roomID, input)
+}
+
+// checkRoomOpen rejects input to the room unless it is open, taking the
+// opening and closing times into account even if the scheduler is late.
+func checkRoomOpen(access pgstore.GetRoomAccessRow, roomID int64, input string) (int, error) {
+	if status := currentRoomStatus(access.Status, access.OpensAt, access.ClosesAt, time.Now().UTC()); status != RoomStatusOpen {
+		slog.Error("the room is not open", "room_id", roomID, "status", status)
+		return http.StatusConflict, fmt.Errorf("the room is %s and is not accepting %s", status, input)
+	}
+
+	return http.StatusOK, nil
+}
+
+func (s *RoomService) roomAccess(ctx context.Context, roomID int64, userID uuid.UUID) (pgstore.GetRoomAccessRow, int, error) {
+	access, err := s.Queries.GetRoomAccess(ctx, pgstore.GetRoomAccessParams{ID: roomID, UserID: userID})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			slog.Error("room not found", "error", err)
+			return access, http.StatusBadRequest, errors.New("room not found")
+		}
+
+		slog.Error("error checking room access", "error", err)
+		return access, http.StatusInternalServerError, errors.New("error validating room ID")
+	}
+
+	if !canAccessRoom(access, userID) {
+		slog.Error("the user has no access to the room", "room_id", roomID, "user_id", userID)
+		return access, http.StatusBadRequest, errors.New("room not found")
+	}
+
+	return access, http.StatusOK, nil
+}
+
+// canAccessRoom reports whether the user can see the room: anyone can see the
+// public and unlisted rooms, and the private ones are open to their owner,
+// members and guests. Drafts are only open to the hosts of the room, its owner
+// and members.
+func canAccessRoom(access pgstore.GetRoomAccessRow, userID uuid.UUID) bool {
+	if roomRole(access, userID) != "" {
+		return true
+	}
+
+	if access.Status == RoomStatusDraft {
+		return false
+	}
+
+	return access.Visibility != RoomVisibilityPrivate || access.IsGuest
+}
+
+// CheckRoomOwner reports whether the room exists and was created by the user.
+func (s *RoomService) CheckRoomOwner(ctx context.Context, roomID int64, userID uuid.UUID) (int, error) {
+	room, err := s.Queries.GetRoom(ctx, roomID)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			slog.Error("room not found", "error", err)
 			return http.StatusBadRequest, errors.New("room not found")
 		}
 
-		slog.Error("error checking if room exists", "error", err)
+		slog.Error("error checking room owner", "error", err)
 		return http.StatusInternalServerError, errors.New("error validating room ID")
 	}
 
+	if room.UserID != userID {
+		slog.Error("the user is not the room owner", "room_id", roomID, "user_id", userID)
+		return http.StatusForbidden, errors.New("only the room owner can change the room")
+	}
+
 	return http.StatusOK, nil
 }
+
+// RoomChanges holds the room fields to update, nil for the ones to keep.
+type RoomChanges struct {
+	Name        *string
+	Description *string
+	Visibility  *string
+	Slug        *string
+}
+
+// UpdateRoom changes the fields that are not nil and leaves the others as
+// they are.
+func (s *RoomService) UpdateRoom(ctx context.Context, roomID int64, changes RoomChanges) (pgstore.Room, error) {
+	params := pgstore.UpdateRoomParams{ID: roomID}
+	if changes.Name != nil {
+		params.Name = pgtype.Text{String: *changes.Name, Valid: true}
+	}
+	if changes.Description != nil {
+		params.Description = pgtype.Text{String: *changes.Description, Valid: true}
+	}
+	if changes.Visibility != nil {
+		params.Visibility = pgtype.Text{String: *changes.Visibility, Valid: true}
+	}
+	if changes.Slug != nil {
+		taken, err := s.Queries.GetTakenRoomSlugs(ctx, pgstore.GetTakenRoomSlugsParams{Slug: *changes.Slug, RoomID: roomID})
+		if err != nil {
+			return pgstore.Room{}, err
+		}
+		if slices.Contains(taken, *changes.Slug) {
+			return pgstore.Room{}, ErrRoomSlugTaken
+		}
+		params.Slug = pgtype.Text{String: *changes.Slug, Valid: true}
+	}
+
+	room, err := s.Queries.UpdateRoom(ctx, params)
+	if err != nil && isSlugViolation(err) {
+		return room, ErrRoomSlugTaken
+	}
+
+	return room, err
+}
+
+// SetRoomStatus moves the room to the status of the schedule and replaces its
+// opening and closing times. The followers of the room are notified when it
+// opens.
+func (s *RoomService) SetRoomStatus(ctx context.Context, roomID int64, schedule RoomSchedule) (pgstore.Room, error) {
+	previous, err := s.Queries.GetRoom(ctx, roomID)
+	if err != nil {
+		return pgstore.Room{}, err
+	}
+
+	room, err := s.Queries.UpdateRoomStatus(ctx, pgstore.UpdateRoomStatusParams{
+		Status:   schedule.Status,
+		OpensAt:  toTimestamp(schedule.OpensAt),
+		ClosesAt: toTimestamp(schedule.ClosesAt),
+		ID:       roomID,
+	})
+	if err != nil {
+		return room, err
+	}
+
+	if room.Status == RoomStatusOpen && previous.Status != RoomStatusOpen {
+		notifyRoomOpened(ctx, s.Queries, roomID)
+	}
+
+	return room, nil
+}
+
+// DeleteRoom removes the room and returns the visibility it had.
+func (s *RoomService) DeleteRoom(ctx context.Context, roomID int64) (string, error) {
+	return s.Queries.DeleteRoom(ctx, roomID)
+}
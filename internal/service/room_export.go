@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vhrboliveira/ama-go/internal/store/pgstore"
+)
+
+// The formats a room can be exported to.
+const (
+	ExportFormatJSON     = "json"
+	ExportFormatCSV      = "csv"
+	ExportFormatMarkdown = "md"
+)
+
+var ExportFormats = []string{ExportFormatJSON, ExportFormatCSV, ExportFormatMarkdown}
+
+var exportContentTypes = map[string]string{
+	ExportFormatJSON:     "application/json",
+	ExportFormatCSV:      "text/csv; charset=utf-8",
+	ExportFormatMarkdown: "text/markdown; charset=utf-8",
+}
+
+var exportCSVHeader = []string{
+	"room_id", "room_slug", "room_name", "id", "message", "answer", "answered", "created_at", "updated_at", "reaction_count",
+}
+
+type exportedRoom struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Slug        string `json:"slug"`
+	Status      string `json:"status"`
+	CreatorName string `json:"creator_name"`
+	CreatedAt   string `json:"created_at"`
+	ExportedAt  string `json:"exported_at"`
+}
+
+type exportedMessage struct {
+	ID            string `json:"id"`
+	Message       string `json:"message"`
+	Answer        string `json:"answer"`
+	Answered      bool   `json:"answered"`
+	CreatedAt     string `json:"created_at"`
+	UpdatedAt     string `json:"updated_at"`
+	ReactionCount int64  `json:"reaction_count"`
+}
+
+func newExportedMessage(message pgstore.ExportRoomMessagesRow) exportedMessage {
+	return exportedMessage{
+		ID:            message.ID.String(),
+		Message:       message.Message,
+		Answer:        message.Answer,
+		Answered:      message.Answered,
+		CreatedAt:     message.CreatedAt.Time.Format(time.RFC3339),
+		UpdatedAt:     message.UpdatedAt.Time.Format(time.RFC3339),
+		ReactionCount: message.ReactionCount,
+	}
+}
+
+// ExportContentType returns the Content-Type of an export format.
+func ExportContentType(format string) string {
+	return exportContentTypes[format]
+}
+
+// ExportRoom writes the room and its visible messages to w in the format.
+// Messages are written as they are read from the database, the most reacted
+// first for the Markdown transcript and in the order they were asked for the
+// other formats. Once anything is written, errors can only stop the export.
+func (s *RoomService) ExportRoom(ctx context.Context, w io.Writer, room pgstore.GetRoomWithUserRow, format string) error {
+	exported := exportedRoom{
+		ID:          room.ID,
+		Name:        room.Name,
+		Description: room.Description,
+		Slug:        room.Slug,
+		Status:      currentRoomStatus(room.Status, room.OpensAt, room.ClosesAt, time.Now().UTC()),
+		CreatorName: room.CreatorName.String,
+		CreatedAt:   room.CreatedAt.Time.Format(time.RFC3339),
+		ExportedAt:  time.Now().UTC().Format(time.RFC3339),
+	}
+
+	params := pgstore.ExportRoomMessagesParams{RoomID: room.ID, ByReactions: format == ExportFormatMarkdown}
+
+	switch format {
+	case ExportFormatJSON:
+		return s.exportJSON(ctx, w, exported, params)
+	case ExportFormatCSV:
+		return s.exportCSV(ctx, w, exported, params)
+	case ExportFormatMarkdown:
+		return s.exportMarkdown(ctx, w, exported, params)
+	default:
+		return fmt.Errorf("unknown export format %q", format)
+	}
+}
+
+func (s *RoomService) exportJSON(ctx context.Context, w io.Writer, room exportedRoom, params pgstore.ExportRoomMessagesParams) error {
+	data, err := json.Marshal(room)
+	if err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintf(w, `{"room":%s,"messages":[`, data); err != nil {
+		return err
+	}
+
+	separator := ""
+	err = s.Queries.StreamExportRoomMessages(ctx, params, func(message pgstore.ExportRoomMessagesRow) error {
+		data, err := json.Marshal(newExportedMessage(message))
+		if err != nil {
+			return err
+		}
+
+		_, err = fmt.Fprintf(w, "%s%s", separator, data)
+		separator = ","
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, "]}\n")
+	return err
+}
+
+func (s *RoomService) exportCSV(ctx context.Context, w io.Writer, room exportedRoom, params pgstore.ExportRoomMessagesParams) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(exportCSVHeader); err != nil {
+		return err
+	}
+
+	roomID := strconv.FormatInt(room.ID, 10)
+	err := s.Queries.StreamExportRoomMessages(ctx, params, func(row pgstore.ExportRoomMessagesRow) error {
+		message := newExportedMessage(row)
+		return cw.Write([]string{
+			roomID,
+			room.Slug,
+			room.Name,
+			message.ID,
+			message.Message,
+			message.Answer,
+			strconv.FormatBool(message.Answered),
+			message.CreatedAt,
+			message.UpdatedAt,
+			strconv.FormatInt(message.ReactionCount, 10),
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func (s *RoomService) exportMarkdown(ctx context.Context, w io.Writer, room exportedRoom, params pgstore.ExportRoomMessagesParams) error {
+	var header strings.Builder
+	fmt.Fprintf(&header, "# %s\n\n", singleLine(room.Name))
+	if room.Description != "" {
+		fmt.Fprintf(&header, "%s\n\n", room.Description)
+	}
+	if room.CreatorName != "" {
+		fmt.Fprintf(&header, "Hosted by %s. ", room.CreatorName)
+	}
+	fmt.Fprintf(&header, "Exported at %s.\n", room.ExportedAt)
+
+	if _, err := io.WriteString(w, header.String()); err != nil {
+		return err
+	}
+
+	number := 0
+	return s.Queries.StreamExportRoomMessages(ctx, params, func(row pgstore.ExportRoomMessagesRow) error {
+		number++
+		message := newExportedMessage(row)
+
+		var entry strings.Builder
+		fmt.Fprintf(&entry, "\n## %d. %s\n\n", number, singleLine(message.Message))
+
+		reactions := "reactions"
+		if message.ReactionCount == 1 {
+			reactions = "reaction"
+		}
+		fmt.Fprintf(&entry, "_%d %s, asked at %s_\n\n", message.ReactionCount, reactions, message.CreatedAt)
+
+		if message.Answered {
+			fmt.Fprintf(&entry, "> %s\n", strings.ReplaceAll(strings.TrimSpace(message.Answer), "\n", "\n> "))
+		} else {
+			entry.WriteString("Not answered.\n")
+		}
+
+		_, err := io.WriteString(w, entry.String())
+		return err
+	})
+}
+
+// singleLine joins the lines of a text, for the Markdown headings.
+func singleLine(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vhrboliveira/ama-go/internal/store/pgstore"
+	"github.com/vhrboliveira/ama-go/internal/types"
+)
+
+// The kinds of notifications the followers of a room get.
+const (
+	NotificationKindRoomOpened      = "room_opened"
+	NotificationKindMessageAnswered = "message_answered"
+)
+
+// FollowRoom makes the user a follower of the room. Following a room twice is
+// the same as following it once.
+func (s *RoomService) FollowRoom(ctx context.Context, roomID int64, userID uuid.UUID) error {
+	return s.Queries.FollowRoom(ctx, pgstore.FollowRoomParams{RoomID: roomID, UserID: userID})
+}
+
+func (s *RoomService) UnfollowRoom(ctx context.Context, roomID int64, userID uuid.UUID) error {
+	return s.Queries.UnfollowRoom(ctx, pgstore.UnfollowRoomParams{RoomID: roomID, UserID: userID})
+}
+
+// GetFollowedRooms returns the rooms the user follows and can still access,
+// the most recently followed first.
+func (s *RoomService) GetFollowedRooms(ctx context.Context, userID uuid.UUID) ([]pgstore.GetFollowedRoomsRow, error) {
+	rooms, err := s.Queries.GetFollowedRooms(ctx, userID)
+
+	if rooms == nil {
+		rooms = []pgstore.GetFollowedRoomsRow{}
+	}
+
+	return rooms, err
+}
+
+// GetUnreadNotifications returns the latest notifications the user has not
+// read yet, the newest first.
+func (s *RoomService) GetUnreadNotifications(ctx context.Context, userID uuid.UUID) ([]types.Notification, error) {
+	rows, err := s.Queries.GetUnreadNotifications(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	notifications := make([]types.Notification, 0, len(rows))
+	for _, row := range rows {
+		notification := types.Notification{
+			ID:        row.ID.String(),
+			Kind:      row.Kind,
+			RoomID:    row.RoomID,
+			RoomName:  row.RoomName,
+			RoomSlug:  row.RoomSlug,
+			CreatedAt: row.CreatedAt.Time.Format(time.RFC3339),
+		}
+
+		if row.MessageID.Valid {
+			messageID := uuid.UUID(row.MessageID.Bytes).String()
+			notification.MessageID = &messageID
+		}
+
+		if row.Message.Valid {
+			notification.Message = &row.Message.String
+		}
+
+		notifications = append(notifications, notification)
+	}
+
+	return notifications, nil
+}
+
+func (s *RoomService) MarkNotificationsRead(ctx context.Context, userID uuid.UUID) (int64, error) {
+	return s.Queries.MarkNotificationsRead(ctx, userID)
+}
+
+// notifyRoomOpened records a room_opened notification for the followers of
+// the room. A failure only costs the notifications, so it is logged and the
+// room opens anyway.
+func notifyRoomOpened(ctx context.Context, queries *pgstore.Queries, roomID int64) {
+	if _, err := queries.InsertRoomOpenedNotifications(ctx, roomID); err != nil {
+		slog.Error("error notifying the room followers", "room_id", roomID, "error", err)
+	}
+}
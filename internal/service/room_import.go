@@ -0,0 +1,296 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/vhrboliveira/ama-go/internal/store/pgstore"
+)
+
+// The formats a room can be imported from, the same a room is exported to
+// except the Markdown transcript.
+var ImportFormats = []string{ExportFormatJSON, ExportFormatCSV}
+
+// TxBeginner starts the transaction an import runs in, a *pgxpool.Pool outside
+// of the tests.
+type TxBeginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// ImportedRoom is a room read from an export, to be imported.
+type ImportedRoom struct {
+	Name        string
+	Description string
+	Slug        string
+	Messages    []ImportedMessage
+}
+
+type ImportedMessage struct {
+	Message   string
+	Answer    string
+	Answered  bool
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// RoomImport is the room an import went into and how many of its questions
+// were inserted and skipped as duplicates.
+type RoomImport struct {
+	Room     pgstore.Room
+	Created  bool
+	Imported int64
+	Skipped  int64
+}
+
+type ImportService struct {
+	DB      TxBeginner
+	Queries *pgstore.Queries
+}
+
+func NewImportService(db TxBeginner, queries *pgstore.Queries) *ImportService {
+	return &ImportService{DB: db, Queries: queries}
+}
+
+// ParseRoomImport reads a room exported as JSON or CSV. The errors describe
+// what is wrong with the input. CSV columns are found by their header, so
+// only room_name and message are required.
+func ParseRoomImport(r io.Reader, format string) (ImportedRoom, error) {
+	switch format {
+	case ExportFormatJSON:
+		return parseJSONImport(r)
+	case ExportFormatCSV:
+		return parseCSVImport(r)
+	default:
+		return ImportedRoom{}, fmt.Errorf("unknown import format %q", format)
+	}
+}
+
+func parseJSONImport(r io.Reader) (ImportedRoom, error) {
+	var export struct {
+		Room     exportedRoom      `json:"room"`
+		Messages []exportedMessage `json:"messages"`
+	}
+
+	if err := json.NewDecoder(r).Decode(&export); err != nil {
+		return ImportedRoom{}, fmt.Errorf("invalid json: %w", err)
+	}
+
+	return newImportedRoom(export.Room, export.Messages)
+}
+
+func parseCSVImport(r io.Reader) (ImportedRoom, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return ImportedRoom{}, fmt.Errorf("invalid csv: %w", err)
+	}
+
+	if len(records) < 2 {
+		return ImportedRoom{}, errors.New("csv must have a header and at least one question")
+	}
+
+	columns := make(map[string]int, len(records[0]))
+	for i, name := range records[0] {
+		columns[name] = i
+	}
+
+	for _, required := range []string{"room_name", "message"} {
+		if _, ok := columns[required]; !ok {
+			return ImportedRoom{}, fmt.Errorf("csv is missing the %s column", required)
+		}
+	}
+
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok {
+			return record[i]
+		}
+		return ""
+	}
+
+	room := exportedRoom{Name: field(records[1], "room_name"), Slug: field(records[1], "room_slug")}
+	messages := make([]exportedMessage, 0, len(records)-1)
+	for i, record := range records[1:] {
+		message := exportedMessage{
+			Message:   field(record, "message"),
+			Answer:    field(record, "answer"),
+			Answered:  field(record, "answer") != "",
+			CreatedAt: field(record, "created_at"),
+			UpdatedAt: field(record, "updated_at"),
+		}
+
+		if answered := field(record, "answered"); answered != "" {
+			message.Answered, err = strconv.ParseBool(answered)
+			if err != nil {
+				return ImportedRoom{}, fmt.Errorf("question %d has an invalid answered value", i+1)
+			}
+		}
+
+		messages = append(messages, message)
+	}
+
+	return newImportedRoom(room, messages)
+}
+
+// newImportedRoom validates an exported room and its questions. Questions
+// without a created_at are asked at the time of the import, and those without
+// an updated_at were last updated when asked.
+func newImportedRoom(room exportedRoom, messages []exportedMessage) (ImportedRoom, error) {
+	if room.Name == "" {
+		return ImportedRoom{}, errors.New("room name is required")
+	}
+
+	if utf8.RuneCountInString(room.Name) > 255 {
+		return ImportedRoom{}, errors.New("room name must have at most 255 characters")
+	}
+
+	if utf8.RuneCountInString(room.Description) > 255 {
+		return ImportedRoom{}, errors.New("room description must have at most 255 characters")
+	}
+
+	imported := ImportedRoom{
+		Name:        room.Name,
+		Description: room.Description,
+		Slug:        room.Slug,
+		Messages:    make([]ImportedMessage, 0, len(messages)),
+	}
+
+	now := time.Now().UTC()
+	for i, message := range messages {
+		if message.Message == "" {
+			return ImportedRoom{}, fmt.Errorf("question %d is empty", i+1)
+		}
+
+		if utf8.RuneCountInString(message.Message) > MaxQuestionLengthLimit {
+			return ImportedRoom{}, fmt.Errorf("question %d must have at most %d characters", i+1, MaxQuestionLengthLimit)
+		}
+
+		createdAt, err := parseImportedTime(message.CreatedAt, now)
+		if err != nil {
+			return ImportedRoom{}, fmt.Errorf("question %d has an invalid created_at, it must be an RFC 3339 time", i+1)
+		}
+
+		updatedAt, err := parseImportedTime(message.UpdatedAt, createdAt)
+		if err != nil {
+			return ImportedRoom{}, fmt.Errorf("question %d has an invalid updated_at, it must be an RFC 3339 time", i+1)
+		}
+
+		imported.Messages = append(imported.Messages, ImportedMessage{
+			Message:   message.Message,
+			Answer:    message.Answer,
+			Answered:  message.Answered,
+			CreatedAt: createdAt,
+			UpdatedAt: updatedAt,
+		})
+	}
+
+	return imported, nil
+}
+
+func parseImportedTime(value string, fallback time.Time) (time.Time, error) {
+	if value == "" {
+		return fallback, nil
+	}
+
+	return time.Parse(time.RFC3339, value)
+}
+
+// Import inserts the questions of the room, with their original times and
+// answers, into a new draft room of the user. Importing the same room again
+// goes into the room the first import created, found by its slug or name, and
+// skips the questions the room already has. It all runs in one transaction,
+// so a failed import leaves nothing behind.
+func (s *ImportService) Import(ctx context.Context, userID uuid.UUID, room ImportedRoom) (RoomImport, error) {
+	tx, err := s.DB.Begin(ctx)
+	if err != nil {
+		return RoomImport{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.Queries.WithTx(tx)
+
+	result, err := importTargetRoom(ctx, qtx, userID, room)
+	if err != nil {
+		return RoomImport{}, err
+	}
+
+	for _, message := range room.Messages {
+		inserted, err := qtx.ImportMessage(ctx, pgstore.ImportMessageParams{
+			RoomID:    result.Room.ID,
+			Message:   message.Message,
+			Answer:    message.Answer,
+			Answered:  message.Answered,
+			CreatedAt: toTimestamp(&message.CreatedAt),
+			UpdatedAt: toTimestamp(&message.UpdatedAt),
+		})
+		if err != nil {
+			return RoomImport{}, err
+		}
+
+		result.Imported += inserted
+	}
+	result.Skipped = int64(len(room.Messages)) - result.Imported
+
+	if err := tx.Commit(ctx); err != nil {
+		return RoomImport{}, err
+	}
+
+	return result, nil
+}
+
+// importTargetRoom finds the room a previous import of the room created or
+// creates it. Rooms are looked up by the exported slug, or the slug of the
+// name when there is none, among the current and previous slugs of the rooms
+// of the user. A room created when the slug was taken got a suffixed slug,
+// which is only matched along with the name.
+func importTargetRoom(ctx context.Context, qtx *pgstore.Queries, userID uuid.UUID, room ImportedRoom) (RoomImport, error) {
+	slug := room.Slug
+	if !IsRoomSlug(slug) {
+		slug = Slugify(room.Name)
+	}
+
+	existing, err := qtx.GetImportTargetRoom(ctx, pgstore.GetImportTargetRoomParams{UserID: userID, Slug: slug, Name: room.Name})
+	if err == nil {
+		return RoomImport{Room: existing}, nil
+	}
+
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return RoomImport{}, err
+	}
+
+	slug, err = (&RoomService{Queries: qtx}).uniqueSlug(ctx, slug, 0)
+	if err != nil {
+		return RoomImport{}, err
+	}
+
+	inserted, err := qtx.InsertRoom(ctx, pgstore.InsertRoomParams{
+		Name:        room.Name,
+		UserID:      userID,
+		Description: room.Description,
+		Visibility:  RoomVisibilityPublic,
+		Status:      RoomStatusDraft,
+		Slug:        slug,
+	})
+	if err != nil {
+		// The transaction cannot go on after a failed insert, so unlike
+		// CreateRoom there is no retry when another room takes the slug
+		if isSlugViolation(err) {
+			return RoomImport{}, ErrRoomSlugTaken
+		}
+		return RoomImport{}, err
+	}
+
+	created, err := qtx.GetRoom(ctx, inserted.ID)
+	if err != nil {
+		return RoomImport{}, err
+	}
+
+	return RoomImport{Room: created, Created: true}, nil
+}
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/vhrboliveira/ama-go/internal/store/pgstore"
+)
+
+const inviteTokenSize = 24
+
+func newInviteToken() (string, error) {
+	token := make([]byte, inviteTokenSize)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(token), nil
+}
+
+// NormalizeEmailDomain turns "@Example.com " into "example.com", the form the
+// invites store their allowed domains in.
+func NormalizeEmailDomain(domain string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(domain), "@"))
+}
+
+func emailDomain(email string) string {
+	_, domain, _ := strings.Cut(email, "@")
+	return NormalizeEmailDomain(domain)
+}
+
+// CreateInvite generates an invite to the room. A nil expiresAt or maxUses
+// leaves the invite valid forever or for any number of users, and an empty
+// emailDomains lets users of any domain accept it.
+func (s *RoomService) CreateInvite(
+	ctx context.Context,
+	roomID int64,
+	createdBy uuid.UUID,
+	expiresAt *time.Time,
+	maxUses *int32,
+	emailDomains []string,
+) (pgstore.RoomInvite, error) {
+	token, err := newInviteToken()
+	if err != nil {
+		return pgstore.RoomInvite{}, err
+	}
+
+	params := pgstore.InsertRoomInviteParams{
+		Token:        token,
+		RoomID:       roomID,
+		CreatedBy:    createdBy,
+		EmailDomains: emailDomains,
+	}
+	if expiresAt != nil {
+		params.ExpiresAt = pgtype.Timestamp{Time: expiresAt.UTC(), Valid: true}
+	}
+	if maxUses != nil {
+		params.MaxUses = pgtype.Int4{Int32: *maxUses, Valid: true}
+	}
+	if params.EmailDomains == nil {
+		params.EmailDomains = []string{}
+	}
+
+	return s.Queries.InsertRoomInvite(ctx, params)
+}
+
+func (s *RoomService) GetInvites(ctx context.Context, roomID int64) ([]pgstore.RoomInvite, error) {
+	invites, err := s.Queries.GetRoomInvites(ctx, roomID)
+
+	if invites == nil {
+		invites = []pgstore.RoomInvite{}
+	}
+
+	return invites, err
+}
+
+func (s *RoomService) DeleteInvite(ctx context.Context, roomID int64, token string) (int, error) {
+	_, err := s.Queries.DeleteRoomInvite(ctx, pgstore.DeleteRoomInviteParams{Token: token, RoomID: roomID})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			slog.Error("invite not found", "room_id", roomID, "error", err)
+			return http.StatusNotFound, errors.New("invite not found")
+		}
+
+		slog.Error("error deleting invite", "room_id", roomID, "error", err)
+		return http.StatusInternalServerError, errors.New("error deleting invite")
+	}
+
+	return http.StatusNoContent, nil
+}
+
+// AcceptInvite gives the user access to the room of the invite and returns the
+// room ID. Users who already have access do not use up the invite, and are
+// told so even if it is no longer valid.
+func (s *RoomService) AcceptInvite(ctx context.Context, token string, user pgstore.User) (int64, int, error) {
+	invite, err := s.Queries.GetRoomInvite(ctx, token)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			slog.Error("invite not found", "error", err)
+			return 0, http.StatusNotFound, errors.New("invite not found")
+		}
+
+		slog.Error("error getting invite", "error", err)
+		return 0, http.StatusInternalServerError, errors.New("error accepting invite")
+	}
+
+	access, err := s.Queries.GetRoomAccess(ctx, pgstore.GetRoomAccessParams{ID: invite.RoomID, UserID: user.ID})
+	if err != nil {
+		slog.Error("error checking room access", "error", err)
+		return 0, http.StatusInternalServerError, errors.New("error accepting invite")
+	}
+
+	if roomRole(access, user.ID) != "" || access.IsGuest {
+		return invite.RoomID, http.StatusOK, nil
+	}
+
+	if invite.ExpiresAt.Valid && !invite.ExpiresAt.Time.After(time.Now().UTC()) {
+		return 0, http.StatusGone, errors.New("the invite has expired")
+	}
+
+	if invite.MaxUses.Valid && invite.Uses >= invite.MaxUses.Int32 {
+		return 0, http.StatusGone, errors.New("the invite has reached its usage limit")
+	}
+
+	if len(invite.EmailDomains) > 0 && !slices.Contains(invite.EmailDomains, emailDomain(user.Email)) {
+		slog.Error("email domain not allowed by the invite", "room_id", invite.RoomID, "user_id", user.ID)
+		return 0, http.StatusForbidden, errors.New("your email domain is not allowed to accept this invite")
+	}
+
+	roomID, err := s.Queries.RedeemRoomInvite(ctx, pgstore.RedeemRoomInviteParams{Token: token, UserID: user.ID})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return s.inviteNotRedeemed(ctx, invite.RoomID, user.ID)
+		}
+
+		slog.Error("error redeeming invite", "error", err)
+		return 0, http.StatusInternalServerError, errors.New("error accepting invite")
+	}
+
+	return roomID, http.StatusOK, nil
+}
+
+// inviteNotRedeemed tells apart the two reasons for an invite not to be
+// redeemed: the user was let in by a concurrent request, or another user took
+// the last use or the invite expired meanwhile.
+func (s *RoomService) inviteNotRedeemed(ctx context.Context, roomID int64, userID uuid.UUID) (int64, int, error) {
+	access, err := s.Queries.GetRoomAccess(ctx, pgstore.GetRoomAccessParams{ID: roomID, UserID: userID})
+	if err != nil {
+		slog.Error("error checking room access", "error", err)
+		return 0, http.StatusInternalServerError, errors.New("error accepting invite")
+	}
+
+	if access.IsGuest {
+		return roomID, http.StatusOK, nil
+	}
+
+	return 0, http.StatusGone, errors.New("the invite is no longer valid")
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/vhrboliveira/ama-go/internal/store/pgstore"
+)
+
+// The roles users can have in a room. The owner is the user who created the
+// room, while the moderators and panelists are added to it by the owner. Users
+// with any of these roles host the room: they answer, hide and pin its
+// questions.
+const (
+	RoomRoleOwner     = "owner"
+	RoomRoleModerator = "moderator"
+	RoomRolePanelist  = "panelist"
+)
+
+// RoomMemberRoles are the roles the owner can give to other users.
+var RoomMemberRoles = []string{RoomRoleModerator, RoomRolePanelist}
+
+// roomRole is the role of the user in the room, empty when the user has none.
+func roomRole(access pgstore.GetRoomAccessRow, userID uuid.UUID) string {
+	if access.UserID == userID {
+		return RoomRoleOwner
+	}
+
+	return access.Role
+}
+
+// CanModerateRoom reports whether the user has a role in the room, so it can
+// see its hidden questions. Errors are reported as the user having no role.
+func (s *RoomService) CanModerateRoom(ctx context.Context, roomID int64, userID uuid.UUID) bool {
+	access, err := s.Queries.GetRoomAccess(ctx, pgstore.GetRoomAccessParams{ID: roomID, UserID: userID})
+	if err != nil {
+		slog.Error("error getting room role", "room_id", roomID, "error", err)
+		return false
+	}
+
+	return roomRole(access, userID) != ""
+}
+
+// CheckRoomModerator is CheckRoomAccess for the room hosts. action describes
+// what the user is trying to do, for the error message.
+func (s *RoomService) CheckRoomModerator(ctx context.Context, roomID int64, userID uuid.UUID, action string) (int, error) {
+	access, status, err := s.roomAccess(ctx, roomID, userID)
+	if err != nil {
+		return status, err
+	}
+
+	if roomRole(access, userID) == "" {
+		slog.Error("the user has no role in the room", "room_id", roomID, "user_id", userID)
+		return http.StatusForbidden, fmt.Errorf("only the room owner, moderators and panelists can %s", action)
+	}
+
+	return http.StatusOK, nil
+}
+
+func (s *RoomService) GetMembers(ctx context.Context, roomID int64) ([]pgstore.GetRoomMembersRow, error) {
+	members, err := s.Queries.GetRoomMembers(ctx, roomID)
+
+	if members == nil {
+		members = []pgstore.GetRoomMembersRow{}
+	}
+
+	return members, err
+}
+
+// AddMember gives the user with the email a role in the room, replacing the
+// role the user had before, if any.
+func (s *RoomService) AddMember(ctx context.Context, roomID int64, email, role string) (pgstore.RoomMember, int, error) {
+	user, err := s.Queries.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			slog.Error("user not found", "error", err)
+			return pgstore.RoomMember{}, http.StatusNotFound, errors.New("user not found")
+		}
+
+		slog.Error("error getting user by email", "error", err)
+		return pgstore.RoomMember{}, http.StatusInternalServerError, errors.New("error adding member")
+	}
+
+	room, err := s.Queries.GetRoom(ctx, roomID)
+	if err != nil {
+		slog.Error("error getting room", "room_id", roomID, "error", err)
+		return pgstore.RoomMember{}, http.StatusInternalServerError, errors.New("error adding member")
+	}
+
+	if room.UserID == user.ID {
+		return pgstore.RoomMember{}, http.StatusBadRequest, errors.New("the room owner cannot be added as a member")
+	}
+
+	member, err := s.Queries.UpsertRoomMember(ctx, pgstore.UpsertRoomMemberParams{
+		RoomID: roomID,
+		UserID: user.ID,
+		Role:   role,
+	})
+	if err != nil {
+		slog.Error("error adding member", "room_id", roomID, "error", err)
+		return pgstore.RoomMember{}, http.StatusInternalServerError, errors.New("error adding member")
+	}
+
+	return member, http.StatusCreated, nil
+}
+
+func (s *RoomService) RemoveMember(ctx context.Context, roomID int64, userID uuid.UUID) (int, error) {
+	_, err := s.Queries.DeleteRoomMember(ctx, pgstore.DeleteRoomMemberParams{RoomID: roomID, UserID: userID})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			slog.Error("member not found", "room_id", roomID, "error", err)
+			return http.StatusNotFound, errors.New("member not found")
+		}
+
+		slog.Error("error removing member", "room_id", roomID, "error", err)
+		return http.StatusInternalServerError, errors.New("error removing member")
+	}
+
+	return http.StatusNoContent, nil
+}
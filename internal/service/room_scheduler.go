@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/vhrboliveira/ama-go/internal/store/pgstore"
+	"github.com/vhrboliveira/ama-go/internal/types"
+)
+
+const defaultRoomSchedulerInterval = 15 * time.Second
+
+// RoomScheduler opens the scheduled rooms and closes the open ones when their
+// time comes. Each transition is a single UPDATE, so several instances of the
+// server can run it at once without a room changing status twice.
+type RoomScheduler struct {
+	Queries          *pgstore.Queries
+	WebsocketService *WebSocketService
+	Interval         time.Duration
+}
+
+func NewRoomScheduler(queries *pgstore.Queries, websocketService *WebSocketService) *RoomScheduler {
+	return &RoomScheduler{
+		Queries:          queries,
+		WebsocketService: websocketService,
+		Interval:         durationFromEnv("ROOM_SCHEDULER_INTERVAL", defaultRoomSchedulerInterval),
+	}
+}
+
+// Run applies the due transitions every Interval until the context is done.
+func (s *RoomScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.RunDue(ctx); err != nil {
+			slog.Error("error running the room scheduler", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunDue opens and closes the rooms that are due and notifies their
+// subscribers, and the followers of the rooms that opened. A room past both
+// its opens_at and closes_at goes through both transitions.
+func (s *RoomScheduler) RunDue(ctx context.Context) error {
+	opened, openErr := s.Queries.OpenDueRooms(ctx)
+	for _, room := range opened {
+		notifyRoomOpened(ctx, s.Queries, room.ID)
+	}
+
+	closed, closeErr := s.Queries.CloseDueRooms(ctx)
+
+	for _, room := range append(opened, closed...) {
+		slog.Info("room status changed", "room_id", room.ID, "status", room.Status)
+		s.WebsocketService.NotifyRoomStatusChanged(room)
+	}
+
+	return errors.Join(openErr, closeErr)
+}
+
+// RoomStatusChangedValue is the room_status_changed event of the room.
+func RoomStatusChangedValue(room pgstore.Room) types.RoomStatusChanged {
+	return types.RoomStatusChanged{
+		ID:        room.ID,
+		Status:    room.Status,
+		OpensAt:   formatTimestamp(room.OpensAt),
+		ClosesAt:  formatTimestamp(room.ClosesAt),
+		UpdatedAt: room.UpdatedAt.Time.Format(time.RFC3339),
+	}
+}
+
+func formatTimestamp(ts pgtype.Timestamp) *string {
+	if !ts.Valid {
+		return nil
+	}
+
+	formatted := ts.Time.Format(time.RFC3339)
+	return &formatted
+}
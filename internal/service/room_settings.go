@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/vhrboliveira/ama-go/internal/store/pgstore"
+)
+
+// The limits of the room settings, matching the checks on the rooms table.
+const (
+	MaxQuestionLengthLimit = 2000
+	MaxSlowModeSeconds     = 3600
+)
+
+// SlowModeError rejects a question asked before the slow mode interval of the
+// room has passed since the previous question of the user.
+type SlowModeError struct {
+	RetryAfter int32
+}
+
+func (e SlowModeError) Error() string {
+	return fmt.Sprintf("slow mode is on, wait %d seconds before asking another question", e.RetryAfter)
+}
+
+// RoomSettingsChanges holds the room settings to update, nil for the ones to
+// keep.
+type RoomSettingsChanges struct {
+	AnonymousQuestions *bool
+	MaxQuestionLength  *int32
+	SlowModeSeconds    *int32
+	ReactionsAllowed   *bool
+	PublicRead         *bool
+}
+
+func (s *RoomService) UpdateSettings(ctx context.Context, roomID int64, changes RoomSettingsChanges) (pgstore.Room, error) {
+	params := pgstore.UpdateRoomSettingsParams{ID: roomID}
+	if changes.AnonymousQuestions != nil {
+		params.AnonymousQuestions = pgtype.Bool{Bool: *changes.AnonymousQuestions, Valid: true}
+	}
+	if changes.MaxQuestionLength != nil {
+		params.MaxQuestionLength = pgtype.Int4{Int32: *changes.MaxQuestionLength, Valid: true}
+	}
+	if changes.SlowModeSeconds != nil {
+		params.SlowModeSeconds = pgtype.Int4{Int32: *changes.SlowModeSeconds, Valid: true}
+	}
+	if changes.ReactionsAllowed != nil {
+		params.ReactionsAllowed = pgtype.Bool{Bool: *changes.ReactionsAllowed, Valid: true}
+	}
+	if changes.PublicRead != nil {
+		params.PublicRead = pgtype.Bool{Bool: *changes.PublicRead, Valid: true}
+	}
+
+	return s.Queries.UpdateRoomSettings(ctx, params)
+}
+
+// CheckQuestion is CheckRoomOpen for a question, which must also fit the
+// maximum question length of the room and respect its slow mode, and can only
+// be asked anonymously in the rooms that allow it. The hosts of the room are
+// not slowed down. It reports whether the user is, in which case the cooldown
+// is started with StartQuestionCooldown once the question is inserted.
+func (s *RoomService) CheckQuestion(ctx context.Context, roomID int64, userID uuid.UUID, message string, anonymous bool) (bool, int, error) {
+	access, status, err := s.roomAccess(ctx, roomID, userID)
+	if err != nil {
+		return false, status, err
+	}
+
+	if status, err := checkRoomOpen(access, roomID, "questions"); err != nil {
+		return false, status, err
+	}
+
+	if utf8.RuneCountInString(message) > int(access.MaxQuestionLength) {
+		return false, http.StatusBadRequest, fmt.Errorf("validation failed: message must have at most %d characters", access.MaxQuestionLength)
+	}
+
+	if anonymous && !access.AnonymousQuestions {
+		return false, http.StatusBadRequest, errors.New("validation failed: this room does not accept anonymous questions")
+	}
+
+	if access.SlowModeSeconds == 0 || roomRole(access, userID) != "" {
+		return false, http.StatusOK, nil
+	}
+
+	retryAfter, err := s.Queries.GetQuestionCooldown(ctx, pgstore.GetQuestionCooldownParams{
+		RoomID:          roomID,
+		UserID:          userID,
+		SlowModeSeconds: access.SlowModeSeconds,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return true, http.StatusOK, nil
+		}
+
+		slog.Error("error getting question cooldown", "room_id", roomID, "error", err)
+		return false, http.StatusInternalServerError, errors.New("error inserting message")
+	}
+
+	if retryAfter <= 0 {
+		return true, http.StatusOK, nil
+	}
+
+	slog.Error("the user is in slow mode", "room_id", roomID, "user_id", userID)
+	return false, http.StatusTooManyRequests, SlowModeError{RetryAfter: retryAfter}
+}
+
+// StartQuestionCooldown starts the slow mode interval of the user in the room,
+// once their question was inserted.
+func (s *RoomService) StartQuestionCooldown(ctx context.Context, roomID int64, userID uuid.UUID) error {
+	return s.Queries.StartQuestionCooldown(ctx, pgstore.StartQuestionCooldownParams{RoomID: roomID, UserID: userID})
+}
+
+// CheckQuestionEdit is CheckRoomOpen for the new text of a question, which
+// must fit the maximum question length of the room as when it was asked.
+func (s *RoomService) CheckQuestionEdit(ctx context.Context, roomID int64, userID uuid.UUID, message string) (int, error) {
+	access, status, err := s.roomAccess(ctx, roomID, userID)
+	if err != nil {
+		return status, err
+	}
+
+	if status, err := checkRoomOpen(access, roomID, "questions"); err != nil {
+		return status, err
+	}
+
+	if utf8.RuneCountInString(message) > int(access.MaxQuestionLength) {
+		return http.StatusBadRequest, fmt.Errorf("validation failed: message must have at most %d characters", access.MaxQuestionLength)
+	}
+
+	return http.StatusOK, nil
+}
+
+// CheckRoomReactions is CheckRoomOpen for reactions, which the owner can also
+// turn off for the room.
+func (s *RoomService) CheckRoomReactions(ctx context.Context, roomID int64, userID uuid.UUID) (int, error) {
+	access, status, err := s.roomAccess(ctx, roomID, userID)
+	if err != nil {
+		return status, err
+	}
+
+	if status, err := checkRoomOpen(access, roomID, "reactions"); err != nil {
+		return status, err
+	}
+
+	if !access.ReactionsAllowed {
+		slog.Error("reactions are disabled in the room", "room_id", roomID)
+		return http.StatusForbidden, errors.New("reactions are disabled in this room")
+	}
+
+	return http.StatusOK, nil
+}
+
+// AllowsVisitors reports whether users without a session can read the room of
+// the raw room ID or slug, which its owner must allow. Private rooms and drafts
+// are never open to visitors.
+func (s *RoomService) AllowsVisitors(ctx context.Context, rawRoomID string) bool {
+	roomID, err := strconv.ParseInt(rawRoomID, 10, 64)
+	if err != nil {
+		if !IsRoomSlug(rawRoomID) {
+			return false
+		}
+
+		resolved, _, err := s.ResolveRoomSlug(ctx, rawRoomID)
+		if err != nil {
+			return false
+		}
+		roomID = resolved.ID
+	}
+
+	room, err := s.Queries.GetRoom(ctx, roomID)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			slog.Error("error checking room public read", "room_id", roomID, "error", err)
+		}
+		return false
+	}
+
+	return room.PublicRead && room.Visibility != RoomVisibilityPrivate && room.Status != RoomStatusDraft
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/vhrboliveira/ama-go/internal/store/pgstore"
+)
+
+const (
+	slugMaxLength = 60
+	// Attempts to insert a room when another one takes the same slug at the
+	// same time
+	slugAttempts = 3
+)
+
+var (
+	roomSlugPattern  = regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+)*$`)
+	ErrRoomSlugTaken = errors.New("slug is already taken")
+)
+
+// Slugify turns a room name into the lowercase, dash separated form used in
+// the room URLs. Slugs made only of digits would be read as room IDs, so they
+// get a "room" prefix.
+func Slugify(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		default:
+			dash = true
+		}
+	}
+
+	slug := strings.TrimRight(b.String()[:min(b.Len(), slugMaxLength)], "-")
+	if slug == "" {
+		return "room"
+	}
+	if _, err := strconv.ParseInt(slug, 10, 64); err == nil {
+		return "room-" + slug
+	}
+
+	return slug
+}
+
+// IsRoomSlug tells whether the value can be used as a room slug. Values made
+// only of digits are room IDs instead.
+func IsRoomSlug(value string) bool {
+	if len(value) > slugMaxLength || !roomSlugPattern.MatchString(value) {
+		return false
+	}
+
+	_, err := strconv.ParseInt(value, 10, 64)
+	return err != nil
+}
+
+func isSlugViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == "rooms_slug_key"
+}
+
+// uniqueSlug returns the base slug, or the base with the first free numeric
+// suffix, that no other room uses or used to use.
+func (s *RoomService) uniqueSlug(ctx context.Context, base string, roomID int64) (string, error) {
+	taken, err := s.Queries.GetTakenRoomSlugs(ctx, pgstore.GetTakenRoomSlugsParams{Slug: base, RoomID: roomID})
+	if err != nil {
+		return "", err
+	}
+
+	slug := base
+	for i := 2; slices.Contains(taken, slug); i++ {
+		suffix := "-" + strconv.Itoa(i)
+		slug = strings.TrimRight(base[:min(len(base), slugMaxLength-len(suffix))], "-") + suffix
+	}
+
+	return slug, nil
+}
+
+// ResolveRoomSlug finds the room of a slug, either its current one or one it
+// used before being renamed, and returns the room ID and current slug.
+func (s *RoomService) ResolveRoomSlug(ctx context.Context, slug string) (pgstore.ResolveRoomSlugRow, int, error) {
+	room, err := s.Queries.ResolveRoomSlug(ctx, slug)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			slog.Error("room not found", "slug", slug, "error", err)
+			return room, http.StatusNotFound, errors.New("room not found")
+		}
+
+		slog.Error("error resolving room slug", "slug", slug, "error", err)
+		return room, http.StatusInternalServerError, errors.New("error validating room ID")
+	}
+
+	return room, http.StatusOK, nil
+}
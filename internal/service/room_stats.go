@@ -0,0 +1,40 @@
+package service
+
+import (
+	"context"
+
+	"github.com/vhrboliveira/ama-go/internal/store/pgstore"
+)
+
+// topReactedMessagesLimit is how many of the most reacted questions the room
+// stats list.
+const topReactedMessagesLimit = 5
+
+// RoomStats sums up the questions and reactions of a room for its owner.
+type RoomStats struct {
+	Totals             pgstore.GetRoomStatsRow
+	TopReacted         []pgstore.GetRoomTopReactedMessagesRow
+	QuestionsPerMinute []pgstore.GetRoomQuestionsPerMinuteRow
+}
+
+func (s *RoomService) GetStats(ctx context.Context, roomID int64) (RoomStats, error) {
+	totals, err := s.Queries.GetRoomStats(ctx, roomID)
+	if err != nil {
+		return RoomStats{}, err
+	}
+
+	topReacted, err := s.Queries.GetRoomTopReactedMessages(ctx, pgstore.GetRoomTopReactedMessagesParams{
+		RoomID: roomID,
+		Limit:  topReactedMessagesLimit,
+	})
+	if err != nil {
+		return RoomStats{}, err
+	}
+
+	perMinute, err := s.Queries.GetRoomQuestionsPerMinute(ctx, roomID)
+	if err != nil {
+		return RoomStats{}, err
+	}
+
+	return RoomStats{Totals: totals, TopReacted: topReacted, QuestionsPerMinute: perMinute}, nil
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/vhrboliveira/ama-go/internal/store/pgstore"
+)
+
+// CreateTransfer offers the room to the user with the email, who becomes its
+// owner on accepting. A room has at most one pending transfer, so offering it
+// again replaces the previous offer.
+func (s *RoomService) CreateTransfer(ctx context.Context, roomID int64, ownerID uuid.UUID, email string) (pgstore.RoomTransfer, int, error) {
+	user, err := s.Queries.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			slog.Error("user not found", "error", err)
+			return pgstore.RoomTransfer{}, http.StatusNotFound, errors.New("user not found")
+		}
+
+		slog.Error("error getting user by email", "error", err)
+		return pgstore.RoomTransfer{}, http.StatusInternalServerError, errors.New("error transferring room")
+	}
+
+	if user.ID == ownerID {
+		return pgstore.RoomTransfer{}, http.StatusBadRequest, errors.New("the room already belongs to this user")
+	}
+
+	transfer, err := s.Queries.UpsertRoomTransfer(ctx, pgstore.UpsertRoomTransferParams{
+		RoomID:     roomID,
+		FromUserID: ownerID,
+		ToUserID:   user.ID,
+	})
+	if err != nil {
+		slog.Error("error transferring room", "room_id", roomID, "error", err)
+		return pgstore.RoomTransfer{}, http.StatusInternalServerError, errors.New("error transferring room")
+	}
+
+	return transfer, http.StatusCreated, nil
+}
+
+// CancelTransfer removes the pending transfer of the room, which either the
+// owner who offered it or the user it was offered to can do.
+func (s *RoomService) CancelTransfer(ctx context.Context, roomID int64, userID uuid.UUID) (int, error) {
+	_, err := s.Queries.DeleteRoomTransfer(ctx, pgstore.DeleteRoomTransferParams{RoomID: roomID, FromUserID: userID})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			slog.Error("transfer not found", "room_id", roomID, "user_id", userID)
+			return http.StatusNotFound, errors.New("transfer not found")
+		}
+
+		slog.Error("error cancelling transfer", "room_id", roomID, "error", err)
+		return http.StatusInternalServerError, errors.New("error cancelling transfer")
+	}
+
+	return http.StatusNoContent, nil
+}
+
+// AcceptTransfer makes the user the owner of the room offered to them. A user
+// who was a moderator or panelist of the room loses that role, as the owner
+// already hosts it. Transfers offered by a user who no longer owns the room
+// are dropped.
+func (s *RoomService) AcceptTransfer(ctx context.Context, roomID int64, userID uuid.UUID) (pgstore.Room, int, error) {
+	room, err := s.Queries.AcceptRoomTransfer(ctx, pgstore.AcceptRoomTransferParams{RoomID: roomID, UserID: userID})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			slog.Error("transfer not found", "room_id", roomID, "user_id", userID)
+			return pgstore.Room{}, http.StatusNotFound, errors.New("transfer not found")
+		}
+
+		slog.Error("error accepting transfer", "room_id", roomID, "error", err)
+		return pgstore.Room{}, http.StatusInternalServerError, errors.New("error accepting transfer")
+	}
+
+	return room, http.StatusOK, nil
+}
+
+// GetIncomingTransfers returns the rooms offered to the user that are still
+// owned by the users who offered them, the newest offers first.
+func (s *RoomService) GetIncomingTransfers(ctx context.Context, userID uuid.UUID) ([]pgstore.GetIncomingRoomTransfersRow, error) {
+	transfers, err := s.Queries.GetIncomingRoomTransfers(ctx, userID)
+
+	if transfers == nil {
+		transfers = []pgstore.GetIncomingRoomTransfersRow{}
+	}
+
+	return transfers, err
+}
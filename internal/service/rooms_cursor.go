@@ -0,0 +1,81 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/vhrboliveira/ama-go/internal/store/pgstore"
+)
+
+// Orders the rooms list can be sorted by.
+const (
+	RoomsSortNewest         = "newest"
+	RoomsSortOldest         = "oldest"
+	RoomsSortMostQuestions  = "most_questions"
+	RoomsSortRecentActivity = "recent_activity"
+)
+
+var RoomsSorts = []string{RoomsSortNewest, RoomsSortOldest, RoomsSortMostQuestions, RoomsSortRecentActivity}
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// roomsCursor points at the last room of a page, by the sort key of the
+// listing and the room ID that breaks the ties. It is handed to clients as an
+// opaque string.
+type roomsCursor struct {
+	Sort  string     `json:"s"`
+	ID    int64      `json:"id"`
+	Time  *time.Time `json:"t,omitempty"`
+	Count *int64     `json:"c,omitempty"`
+}
+
+func newRoomsCursor(sort string, room pgstore.GetRoomsRow) roomsCursor {
+	cursor := roomsCursor{Sort: sort, ID: room.ID}
+
+	switch sort {
+	case RoomsSortMostQuestions:
+		cursor.Count = &room.MessageCount
+	case RoomsSortRecentActivity:
+		cursor.Time = &room.LastActivityAt.Time
+	default:
+		cursor.Time = &room.CreatedAt.Time
+	}
+
+	return cursor
+}
+
+func (c roomsCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeRoomsCursor parses a cursor returned by a previous page. A cursor is
+// only valid for the sort it was created with.
+func decodeRoomsCursor(raw, sort string) (roomsCursor, error) {
+	var cursor roomsCursor
+
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return cursor, ErrInvalidCursor
+	}
+
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return cursor, ErrInvalidCursor
+	}
+
+	if cursor.Sort != sort || cursor.ID <= 0 {
+		return cursor, ErrInvalidCursor
+	}
+
+	if sort == RoomsSortMostQuestions {
+		if cursor.Count == nil {
+			return cursor, ErrInvalidCursor
+		}
+	} else if cursor.Time == nil {
+		return cursor, ErrInvalidCursor
+	}
+
+	return cursor, nil
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// sseTransport streams messages as Server-Sent Events. Each message is sent as
+// a data line holding the same JSON as the WebSocket frames, with the room
+// sequence as the event ID so EventSource can resume through Last-Event-ID.
+type sseTransport struct {
+	w         http.ResponseWriter
+	rc        *http.ResponseController
+	writeWait time.Duration
+}
+
+func newSSETransport(w http.ResponseWriter, writeWait time.Duration) (*sseTransport, error) {
+	t := &sseTransport{
+		w:         w,
+		rc:        http.NewResponseController(w),
+		writeWait: writeWait,
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if err := t.rc.Flush(); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (t *sseTransport) Write(f *Frame) error {
+	data, err := f.encode(jsonCodec)
+	if err != nil {
+		return err
+	}
+
+	if f.Message.Seq != 0 {
+		return t.send(fmt.Sprintf("id: %d\ndata: %s\n\n", f.Message.Seq, data))
+	}
+
+	return t.send(fmt.Sprintf("data: %s\n\n", data))
+}
+
+func (t *sseTransport) Ping() error {
+	return t.send(": ping\n\n")
+}
+
+// Evict has nothing to send: SSE has no close frame and writing here would
+// race with writePump, so the stream simply ends once the subscriber is
+// cancelled.
+func (t *sseTransport) Evict(string) error {
+	return nil
+}
+
+// Close has nothing to send either: the stream ends once the subscriber is
+// cancelled.
+func (t *sseTransport) Close(string) error {
+	return nil
+}
+
+// Listen returns right away: the client cannot send anything over SSE and a
+// disconnect cancels the request context.
+func (t *sseTransport) Listen(func(data []byte)) {}
+
+func (t *sseTransport) send(frame string) error {
+	t.rc.SetWriteDeadline(time.Now().Add(t.writeWait))
+
+	if _, err := t.w.Write([]byte(frame)); err != nil {
+		return err
+	}
+
+	return t.rc.Flush()
+}
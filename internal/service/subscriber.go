@@ -0,0 +1,278 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/vhrboliveira/ama-go/internal/types"
+)
+
+// Transport is the connection a subscriber receives its messages through.
+type Transport interface {
+	// Write sends a single message to the client.
+	Write(f *Frame) error
+	// Ping keeps the connection alive and detects dead peers.
+	Ping() error
+	// Evict tells the client it is being disconnected. It may be called
+	// concurrently with Write and Ping.
+	Evict(reason string) error
+	// Close ends the connection normally once everything before it was sent.
+	Close(reason string) error
+	// Listen blocks reading from the client until it goes away, passing each
+	// data frame to handle, if the transport supports reading at all.
+	Listen(handle func(data []byte))
+}
+
+// CommandHandler runs a command sent by the client on sub and returns the
+// reply.
+type CommandHandler func(ctx context.Context, sub *Subscriber, cmd types.Command) types.Message
+
+// SnapshotFunc builds the current state of a room for a new subscriber.
+type SnapshotFunc func(ctx context.Context) (any, error)
+
+// NotificationsFunc returns the unread notifications of the user of a new
+// rooms list subscriber.
+type NotificationsFunc func(ctx context.Context) ([]types.Notification, error)
+
+// Subscriber is a single client connection with its own outbound queue and
+// the set of topics it is subscribed to. Only writePump writes to the
+// transport, so broadcasting never blocks on network I/O.
+type Subscriber struct {
+	transport Transport
+	codec     *codec
+	send      chan *Frame
+	ctx       context.Context
+	cancel    context.CancelFunc
+	viewer    types.Viewer
+	ip        string
+	evictOnce sync.Once
+	commands  CommandHandler
+	// dedicated is set for connections opened for a single topic, which end
+	// along with it.
+	dedicated bool
+
+	mu     sync.Mutex
+	topics map[string]struct{}
+	// pending holds the live messages of the topics still being replayed, so
+	// they are sent after the missed events instead of interleaved with them.
+	pending map[string][]*Frame
+}
+
+func newSubscriber(t Transport, c *codec, ctx context.Context, cancel context.CancelFunc, viewer types.Viewer, ip string, queueSize int) *Subscriber {
+	return &Subscriber{
+		transport: t,
+		codec:     c,
+		send:      make(chan *Frame, queueSize),
+		ctx:       ctx,
+		cancel:    cancel,
+		viewer:    viewer,
+		ip:        ip,
+		topics:    make(map[string]struct{}),
+		pending:   make(map[string][]*Frame),
+	}
+}
+
+// Topics returns the topics the subscriber is currently subscribed to.
+func (s *Subscriber) Topics() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	topics := make([]string, 0, len(s.topics))
+	for topic := range s.topics {
+		topics = append(topics, topic)
+	}
+
+	return topics
+}
+
+// enqueue adds the message to the outbound queue without blocking and
+// reports whether there was room for it.
+func (s *Subscriber) enqueue(f *Frame) bool {
+	select {
+	case s.send <- f:
+		return true
+	default:
+		return false
+	}
+}
+
+// enqueueWait adds the message to the outbound queue, waiting for room until
+// the subscription is cancelled.
+func (s *Subscriber) enqueueWait(f *Frame) bool {
+	select {
+	case s.send <- f:
+		return true
+	case <-s.ctx.Done():
+		return false
+	}
+}
+
+// deliver queues a live message, or holds it back while its topic is being
+// replayed.
+func (s *Subscriber) deliver(f *Frame) bool {
+	topic := f.Message.Topic
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.topics[topic]; !ok {
+		return true
+	}
+
+	if pending, ok := s.pending[topic]; ok {
+		s.pending[topic] = append(pending, f)
+		return true
+	}
+
+	return s.enqueue(f)
+}
+
+func (s *Subscriber) writePump(pingInterval time.Duration) {
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.transport.Ping(); err != nil {
+				slog.Error("failed to ping client", "client_IP", s.ip, "error", err)
+				s.cancel()
+				return
+			}
+		case f := <-s.send:
+			if f.closeReason != "" {
+				if err := s.transport.Close(f.closeReason); err != nil {
+					slog.Error("failed to send close message to client", "client_IP", s.ip, "error", err)
+				}
+				s.cancel()
+				return
+			}
+
+			if err := s.transport.Write(f); err != nil {
+				slog.Error("failed to write message to client", "client_IP", s.ip, "error", err)
+				s.cancel()
+				return
+			}
+		}
+	}
+}
+
+// handleFrame runs the command in the frame and queues its reply, so replies
+// go through writePump like every other message.
+func (s *Subscriber) handleFrame(data []byte) {
+	var cmd types.Command
+	var reply types.Message
+
+	switch err := s.codec.unmarshal(data, &cmd); {
+	case err != nil:
+		slog.Error("failed to decode command", "client_IP", s.ip, "error", err)
+		reply = CommandErrorMessage(cmd, http.StatusBadRequest, errors.New("invalid command"))
+	case s.commands == nil:
+		reply = CommandErrorMessage(cmd, http.StatusBadRequest, errors.New("commands are not supported on this subscription"))
+	default:
+		reply = s.commands(s.ctx, s, cmd)
+	}
+
+	if !s.enqueue(newFrame(reply)) {
+		go s.evict(slowConsumerReason)
+	}
+}
+
+func CommandErrorMessage(cmd types.Command, status int, err error) types.Message {
+	return types.Message{
+		Kind: types.MessageKindCommandError,
+		Value: types.CommandError{
+			RequestID: cmd.RequestID,
+			Status:    status,
+			Error:     err.Error(),
+		},
+	}
+}
+
+// evict disconnects the client. It is safe to call concurrently with
+// writePump and only acts once.
+func (s *Subscriber) evict(reason string) {
+	s.evictOnce.Do(func() {
+		slog.Warn("evicting client", "client_IP", s.ip, "reason", reason)
+
+		if err := s.transport.Evict(reason); err != nil {
+			slog.Error("failed to send close message to client", "client_IP", s.ip, "error", err)
+		}
+
+		s.cancel()
+	})
+}
+
+// close disconnects the client once the messages already queued were sent.
+func (s *Subscriber) close(reason string) {
+	if !s.enqueue(&Frame{closeReason: reason}) {
+		go s.evict(slowConsumerReason)
+	}
+}
+
+type wsTransport struct {
+	conn           *websocket.Conn
+	codec          *codec
+	cancel         context.CancelFunc
+	ip             string
+	writeWait      time.Duration
+	pongWait       time.Duration
+	maxMessageSize int64
+}
+
+func (t *wsTransport) Write(f *Frame) error {
+	pm, err := f.prepare(t.codec)
+	if err != nil {
+		return err
+	}
+
+	t.conn.SetWriteDeadline(time.Now().Add(t.writeWait))
+	return t.conn.WritePreparedMessage(pm)
+}
+
+func (t *wsTransport) Ping() error {
+	t.conn.SetWriteDeadline(time.Now().Add(t.writeWait))
+	return t.conn.WriteMessage(websocket.PingMessage, nil)
+}
+
+func (t *wsTransport) Close(reason string) error {
+	msg := websocket.FormatCloseMessage(websocket.CloseNormalClosure, reason)
+	return t.conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(t.writeWait))
+}
+
+func (t *wsTransport) Evict(reason string) error {
+	msg := websocket.FormatCloseMessage(websocket.ClosePolicyViolation, reason)
+	return t.conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(t.writeWait))
+}
+
+// Listen consumes incoming frames so control frames (ping, pong and close)
+// are processed, and cancels the subscription as soon as the peer goes away or
+// stops answering pings within pongWait.
+func (t *wsTransport) Listen(handle func(data []byte)) {
+	defer t.cancel()
+
+	t.conn.SetReadLimit(t.maxMessageSize)
+	t.conn.SetReadDeadline(time.Now().Add(t.pongWait))
+	t.conn.SetPongHandler(func(string) error {
+		return t.conn.SetReadDeadline(time.Now().Add(t.pongWait))
+	})
+
+	for {
+		_, data, err := t.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				slog.Warn("websocket connection lost", "client_IP", t.ip, "error", err)
+			}
+			return
+		}
+
+		handle(data)
+	}
+}
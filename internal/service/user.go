@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"log/slog"
+	"net/http"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -97,3 +98,31 @@ func (u *UserService) UpdateUser(ctx context.Context, userID uuid.UUID, name str
 func (u *UserService) DeleteUserInfo(ctx context.Context, userID uuid.UUID) (uuid.UUID, error) {
 	return u.q.DeleteUser(ctx, userID)
 }
+
+// DeleteUserWithSuccessor deletes the user after handing their rooms to the
+// user with the successor email, instead of deleting the rooms with the user.
+// It returns the IDs of the rooms handed over.
+func (u *UserService) DeleteUserWithSuccessor(ctx context.Context, userID uuid.UUID, successorEmail string) (uuid.UUID, []int64, int, error) {
+	successor, err := u.q.GetUserByEmail(ctx, successorEmail)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			slog.Error("successor not found", "error", err)
+			return uuid.Nil, nil, http.StatusNotFound, errors.New("successor not found")
+		}
+
+		slog.Error("error getting successor", "error", err)
+		return uuid.Nil, nil, http.StatusInternalServerError, errors.New("error deleting user info")
+	}
+
+	if successor.ID == userID {
+		return uuid.Nil, nil, http.StatusBadRequest, errors.New("validation failed: the successor must be another user")
+	}
+
+	deleted, err := u.q.DeleteUserWithSuccessor(ctx, pgstore.DeleteUserWithSuccessorParams{SuccessorID: successor.ID, ID: userID})
+	if err != nil {
+		slog.Error("error deleting user info", "error", err, "userID", userID)
+		return uuid.Nil, nil, http.StatusInternalServerError, errors.New("error deleting user info")
+	}
+
+	return deleted.ID, deleted.RoomIds, http.StatusNoContent, nil
+}
@@ -2,23 +2,58 @@ package service
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"net/http"
 	"os"
+	"strconv"
 	"sync"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
+	"github.com/vhrboliveira/ama-go/internal/store/pgstore"
 	"github.com/vhrboliveira/ama-go/internal/types"
 )
 
+const (
+	defaultWriteWait      = 10 * time.Second
+	defaultPongWait       = 60 * time.Second
+	defaultPingInterval   = 50 * time.Second
+	defaultSendQueueSize  = 256
+	defaultMaxMessageSize = 16384
+
+	slowConsumerReason = "client is too slow to consume messages"
+)
+
+var (
+	ErrAlreadySubscribed = errors.New("already subscribed to topic")
+	ErrNotSubscribed     = errors.New("not subscribed to topic")
+)
+
+// WebSocketService tracks every client connection and the topics it is
+// subscribed to. Subscribers indexes the connections by topic so a broadcast
+// only visits the clients interested in it.
 type WebSocketService struct {
-	Upgrader             websocket.Upgrader
-	RoomSubscribers      map[int64]map[*websocket.Conn]context.CancelFunc
-	RoomsListSubscribers map[*websocket.Conn]context.CancelFunc
-	Mutex                *sync.RWMutex
+	Upgrader       websocket.Upgrader
+	Subscribers    map[string]map[*Subscriber]struct{}
+	Mutex          *sync.RWMutex
+	Broadcaster    Broadcaster
+	Events         EventLog
+	Presences      PresenceStore
+	WriteWait      time.Duration
+	PongWait       time.Duration
+	PingInterval   time.Duration
+	SendQueueSize  int
+	MaxMessageSize int64
+
+	PresenceDebounce time.Duration
+	presenceMutex    sync.Mutex
+	presenceTimers   map[int64]*time.Timer
+	reportMutex      sync.Mutex
 }
 
-func NewWebSocketService() *WebSocketService {
+func NewWebSocketService(broadcaster Broadcaster, events EventLog, presences PresenceStore) *WebSocketService {
 	url := os.Getenv("SITE_URL")
 	if url == "" {
 		panic("SITE_URL is not set")
@@ -29,75 +64,481 @@ func NewWebSocketService() *WebSocketService {
 		env = "dev"
 	}
 
-	return &WebSocketService{
-		Upgrader: websocket.Upgrader{CheckOrigin: func(r *http.Request) bool {
-			if env != "production" {
-				return true
-			}
-			return r.Header.Get("Origin") == url
-		}},
-		RoomSubscribers:      make(map[int64]map[*websocket.Conn]context.CancelFunc),
-		RoomsListSubscribers: make(map[*websocket.Conn]context.CancelFunc),
-		Mutex:                &sync.RWMutex{},
+	w := &WebSocketService{
+		Upgrader: websocket.Upgrader{
+			CheckOrigin: func(r *http.Request) bool {
+				if env != "production" {
+					return true
+				}
+				return r.Header.Get("Origin") == url
+			},
+			Subprotocols:      []string{SubprotocolJSON, SubprotocolMsgpack},
+			EnableCompression: true,
+		},
+		Subscribers:    make(map[string]map[*Subscriber]struct{}),
+		Mutex:          &sync.RWMutex{},
+		Broadcaster:    broadcaster,
+		Events:         events,
+		Presences:      presences,
+		WriteWait:      durationFromEnv("WS_WRITE_WAIT", defaultWriteWait),
+		PongWait:       durationFromEnv("WS_PONG_WAIT", defaultPongWait),
+		PingInterval:   durationFromEnv("WS_PING_INTERVAL", defaultPingInterval),
+		SendQueueSize:  intFromEnv("WS_SEND_QUEUE_SIZE", defaultSendQueueSize),
+		MaxMessageSize: int64(intFromEnv("WS_MAX_MESSAGE_SIZE", defaultMaxMessageSize)),
+
+		PresenceDebounce: durationFromEnv("WS_PRESENCE_DEBOUNCE", defaultPresenceDebounce),
+		presenceTimers:   make(map[int64]*time.Timer),
+	}
+
+	if w.PingInterval >= w.PongWait {
+		slog.Warn("WS_PING_INTERVAL must be shorter than WS_PONG_WAIT, adjusting it", "ping_interval", w.PingInterval, "pong_wait", w.PongWait)
+		w.PingInterval = w.PongWait * 9 / 10
 	}
+
+	broadcaster.Listen(context.Background(), w.deliver)
+	go w.keepPresence(context.Background())
+
+	return w
 }
 
-func (w *WebSocketService) SubscribeToRoom(c *websocket.Conn, ctx context.Context, cancel context.CancelFunc, roomID int64, ip string) {
-	w.Mutex.Lock()
-	if _, ok := w.RoomSubscribers[roomID]; !ok {
-		w.RoomSubscribers[roomID] = make(map[*websocket.Conn]context.CancelFunc)
+func (w *WebSocketService) newWSSubscriber(c *websocket.Conn, ctx context.Context, cancel context.CancelFunc, user pgstore.User, ip string) *Subscriber {
+	codec := codecFor(c.Subprotocol())
+	t := &wsTransport{
+		conn:           c,
+		codec:          codec,
+		cancel:         cancel,
+		ip:             ip,
+		writeWait:      w.WriteWait,
+		pongWait:       w.PongWait,
+		maxMessageSize: w.MaxMessageSize,
 	}
-	slog.Info("new client connected", "room_id", roomID, "client_IP", ip)
-	w.RoomSubscribers[roomID][c] = cancel
-	w.Mutex.Unlock()
 
-	<-ctx.Done()
+	return newSubscriber(t, codec, ctx, cancel, newViewer(user), ip, w.SendQueueSize)
+}
+
+func (w *WebSocketService) newSSESubscriber(rw http.ResponseWriter, ctx context.Context, cancel context.CancelFunc, user pgstore.User, ip string) (*Subscriber, error) {
+	t, err := newSSETransport(rw, w.WriteWait)
+	if err != nil {
+		return nil, err
+	}
+
+	return newSubscriber(t, jsonCodec, ctx, cancel, newViewer(user), ip, w.SendQueueSize), nil
+}
+
+// SubscribeToRoom streams the room events to the connection. When since is
+// set, the events the client missed after that sequence are replayed before
+// switching to live delivery, otherwise the room snapshot is sent first.
+// Frames sent by the client run through commands.
+func (w *WebSocketService) SubscribeToRoom(c *websocket.Conn, ctx context.Context, cancel context.CancelFunc, roomID int64, user pgstore.User, ip string, since *int64, snapshot SnapshotFunc, commands CommandHandler) {
+	sub := w.newWSSubscriber(c, ctx, cancel, user, ip)
+	sub.commands = commands
+
+	w.serve(sub, types.RoomTopic(roomID), since, snapshot)
+}
+
+// SubscribeToRoomsList streams the rooms list events to the connection, after
+// the unread notifications of the user returned by notifications.
+func (w *WebSocketService) SubscribeToRoomsList(c *websocket.Conn, ctx context.Context, cancel context.CancelFunc, user pgstore.User, ip string, notifications NotificationsFunc) {
+	sub := w.newWSSubscriber(c, ctx, cancel, user, ip)
+	w.sendNotifications(sub, notifications)
+
+	w.serve(sub, types.TopicRooms, nil, nil)
+}
+
+// SubscribeToTopics serves a connection that starts without topics and
+// manages them through the subscribe and unsubscribe commands it sends.
+func (w *WebSocketService) SubscribeToTopics(c *websocket.Conn, ctx context.Context, cancel context.CancelFunc, user pgstore.User, ip string, commands CommandHandler) {
+	sub := w.newWSSubscriber(c, ctx, cancel, user, ip)
+	sub.commands = commands
+
+	w.serve(sub, "", nil, nil)
+}
+
+// StreamRoom is the Server-Sent Events counterpart of SubscribeToRoom.
+func (w *WebSocketService) StreamRoom(rw http.ResponseWriter, ctx context.Context, cancel context.CancelFunc, roomID int64, user pgstore.User, ip string, since *int64, snapshot SnapshotFunc) error {
+	sub, err := w.newSSESubscriber(rw, ctx, cancel, user, ip)
+	if err != nil {
+		return err
+	}
+
+	w.serve(sub, types.RoomTopic(roomID), since, snapshot)
+	return nil
+}
+
+// StreamRoomsList is the Server-Sent Events counterpart of SubscribeToRoomsList.
+func (w *WebSocketService) StreamRoomsList(rw http.ResponseWriter, ctx context.Context, cancel context.CancelFunc, user pgstore.User, ip string, notifications NotificationsFunc) error {
+	sub, err := w.newSSESubscriber(rw, ctx, cancel, user, ip)
+	if err != nil {
+		return err
+	}
+	w.sendNotifications(sub, notifications)
+
+	w.serve(sub, types.TopicRooms, nil, nil)
+	return nil
+}
+
+// sendNotifications queues the unread notifications of a new subscriber, if it
+// has any, ahead of every event. Failing to read them does not keep the
+// subscriber from getting the events, they are sent on its next connection.
+func (w *WebSocketService) sendNotifications(sub *Subscriber, notifications NotificationsFunc) {
+	unread, err := notifications(sub.ctx)
+	if err != nil {
+		slog.Error("failed to read unread notifications", "client_IP", sub.ip, "error", err)
+		return
+	}
+
+	if len(unread) == 0 {
+		return
+	}
+
+	sub.enqueue(newFrame(types.Message{
+		Kind:  types.MessageKindNotifications,
+		Topic: types.TopicRooms,
+		Value: types.Notifications{Notifications: unread},
+	}))
+}
+
+// serve subscribes the connection to its initial topic, if any, and blocks
+// until the subscription is cancelled, either by the request context, a failed
+// write, a missed pong or the peer closing the connection. The subscription
+// and the reads run beside writePump so a long replay can drain as it is
+// queued.
+func (w *WebSocketService) serve(sub *Subscriber, topic string, since *int64, snapshot SnapshotFunc) {
+	defer w.unsubscribeAll(sub)
+
+	sub.dedicated = topic != ""
+
+	go func() {
+		if topic != "" {
+			if err := w.Subscribe(sub, topic, since, snapshot); err != nil {
+				slog.Error("failed to subscribe client", "topic", topic, "client_IP", sub.ip, "error", err)
+				sub.cancel()
+				return
+			}
+		}
+
+		sub.transport.Listen(sub.handleFrame)
+	}()
+
+	sub.writePump(w.PingInterval)
+}
+
+// Subscribe adds the topic to the subscriber. For room topics, the events
+// missed after since are queued before any live event of the topic, or, when
+// since is not set, a room_snapshot built by snapshot is.
+func (w *WebSocketService) Subscribe(sub *Subscriber, topic string, since *int64, snapshot SnapshotFunc) error {
+	roomID, err := types.ParseTopic(topic)
+	if err != nil {
+		return err
+	}
+
+	replay := roomID != 0 && (since != nil || snapshot != nil)
+
+	sub.mu.Lock()
+	if _, ok := sub.topics[topic]; ok {
+		sub.mu.Unlock()
+		return ErrAlreadySubscribed
+	}
+	sub.topics[topic] = struct{}{}
+	if replay {
+		sub.pending[topic] = []*Frame{}
+	}
+	sub.mu.Unlock()
 
 	w.Mutex.Lock()
-	delete(w.RoomSubscribers[roomID], c)
-	slog.Info("client disconnected", "room_id", roomID, "client_IP", ip)
+	// A cancelled subscriber has already been removed from every topic, so
+	// registering it now would leak it.
+	if sub.ctx.Err() != nil {
+		w.Mutex.Unlock()
+		return sub.ctx.Err()
+	}
+	if _, ok := w.Subscribers[topic]; !ok {
+		w.Subscribers[topic] = make(map[*Subscriber]struct{})
+	}
+	slog.Info("new client subscribed", "topic", topic, "client_IP", sub.ip)
+	w.Subscribers[topic][sub] = struct{}{}
 	w.Mutex.Unlock()
+
+	if roomID != 0 {
+		w.presenceChanged(roomID)
+	}
+
+	switch {
+	case replay && since != nil:
+		w.replay(sub, topic, roomID, *since)
+	case replay:
+		if err := w.sendSnapshot(sub, topic, roomID, snapshot); err != nil {
+			w.Unsubscribe(sub, topic)
+			return err
+		}
+	}
+
+	return nil
 }
 
-func (w *WebSocketService) SubscribeToRoomsList(c *websocket.Conn, ctx context.Context, cancel context.CancelFunc, ip string) {
+// Unsubscribe removes the topic from the subscriber.
+func (w *WebSocketService) Unsubscribe(sub *Subscriber, topic string) error {
+	sub.mu.Lock()
+	if _, ok := sub.topics[topic]; !ok {
+		sub.mu.Unlock()
+		return ErrNotSubscribed
+	}
+	delete(sub.topics, topic)
+	delete(sub.pending, topic)
+	sub.mu.Unlock()
+
 	w.Mutex.Lock()
-	slog.Info("new client connected to rooms list", "client_IP", ip)
-	w.RoomsListSubscribers[c] = cancel
+	w.removeSubscriber(sub, topic)
 	w.Mutex.Unlock()
 
-	<-ctx.Done()
+	return nil
+}
+
+func (w *WebSocketService) unsubscribeAll(sub *Subscriber) {
+	topics := sub.Topics()
 
 	w.Mutex.Lock()
-	delete(w.RoomsListSubscribers, c)
-	slog.Info("client disconnected to rooms list", "client_IP", ip)
+	for _, topic := range topics {
+		w.removeSubscriber(sub, topic)
+	}
 	w.Mutex.Unlock()
 }
 
-func (w *WebSocketService) NotifyRoomClient(msg types.Message) {
-	w.Mutex.Lock()
-	defer w.Mutex.Unlock()
+// removeSubscriber must be called with the mutex held.
+func (w *WebSocketService) removeSubscriber(sub *Subscriber, topic string) {
+	if _, ok := w.Subscribers[topic][sub]; !ok {
+		return
+	}
+
+	delete(w.Subscribers[topic], sub)
+	if len(w.Subscribers[topic]) == 0 {
+		delete(w.Subscribers, topic)
+	}
+	slog.Info("client unsubscribed", "topic", topic, "client_IP", sub.ip)
+
+	if roomID, _ := types.ParseTopic(topic); roomID != 0 {
+		w.presenceChanged(roomID)
+	}
+}
+
+// replay queues the missed room events, then releases the live events held
+// back meanwhile.
+func (w *WebSocketService) replay(sub *Subscriber, topic string, roomID, since int64) {
+	events, complete, err := w.Events.Since(sub.ctx, roomID, since)
+	if err != nil {
+		slog.Error("failed to read room events", "room_id", roomID, "since", since, "error", err)
+		complete = false
+	}
+
+	if !complete {
+		events = []types.Message{{
+			Kind:   types.MessageKindResyncRequired,
+			RoomID: roomID,
+			Topic:  topic,
+			Value:  types.ResyncRequired{Since: since},
+		}}
+	}
 
-	subscribers, ok := w.RoomSubscribers[msg.RoomID]
-	if !ok || len(subscribers) == 0 {
+	var replayedUntil int64
+	for _, msg := range events {
+		if !sub.enqueueWait(newFrame(msg)) {
+			return
+		}
+
+		replayedUntil = max(replayedUntil, msg.Seq)
+	}
+
+	w.release(sub, topic, replayedUntil)
+}
+
+// sendSnapshot queues the room state as a room_snapshot, then releases the
+// live events held back meanwhile. The room sequence is read before the state,
+// so every change missing from the snapshot comes after it as a live event.
+// A change may show up in both, which clients handle as every event is
+// idempotent.
+func (w *WebSocketService) sendSnapshot(sub *Subscriber, topic string, roomID int64, snapshot SnapshotFunc) error {
+	seq, err := w.Events.Current(sub.ctx, roomID)
+	if err != nil {
+		slog.Error("failed to read room sequence", "room_id", roomID, "error", err)
+		return errors.New("error getting room snapshot")
+	}
+
+	value, err := snapshot(sub.ctx)
+	if err != nil {
+		slog.Error("failed to build room snapshot", "room_id", roomID, "error", err)
+		return errors.New("error getting room snapshot")
+	}
+
+	msg := types.Message{
+		Kind:   types.MessageKindRoomSnapshot,
+		RoomID: roomID,
+		Topic:  topic,
+		Seq:    seq,
+		Value:  value,
+	}
+	if !sub.enqueueWait(newFrame(msg)) {
+		return sub.ctx.Err()
+	}
+
+	w.release(sub, topic, seq)
+	return nil
+}
+
+// release queues the live events of the topic held back while it was being
+// replayed, skipping the ones already covered up to seq.
+func (w *WebSocketService) release(sub *Subscriber, topic string, seq int64) {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+
+	pending, ok := sub.pending[topic]
+	if !ok {
 		return
 	}
+	delete(sub.pending, topic)
+
+	for _, f := range pending {
+		if f.Message.Seq != 0 && f.Message.Seq <= seq {
+			continue
+		}
+
+		if !sub.enqueue(f) {
+			go sub.evict(slowConsumerReason)
+			return
+		}
+	}
+}
+
+func (w *WebSocketService) NotifyRoomClient(msg types.Message) {
+	msg.Topic = types.RoomTopic(msg.RoomID)
+
+	msg, err := w.Events.Append(context.Background(), msg)
+	if err != nil {
+		slog.Error("failed to append room event", "room_id", msg.RoomID, "kind", msg.Kind, "error", err)
+	}
+
+	w.publish(msg)
 
-	for conn, cancel := range subscribers {
-		if err := conn.WriteJSON(msg); err != nil {
-			slog.Error("failed to write room message to client", "error", err)
-			cancel()
+	// Nobody can subscribe to a deleted room again, so its log is of no use
+	if msg.Kind == types.MessageKindRoomDeleted {
+		if err := w.Events.Drop(context.Background(), msg.RoomID); err != nil {
+			slog.Error("failed to drop room event log", "room_id", msg.RoomID, "error", err)
 		}
 	}
 }
 
 func (w *WebSocketService) NotifyRoomsListClients(msg types.Message) {
+	msg.Topic = types.TopicRooms
+
+	w.publish(msg)
+}
+
+// NotifyRoomStatusChanged tells the room subscribers that its status changed,
+// and the rooms list subscribers too when the room is public.
+func (w *WebSocketService) NotifyRoomStatusChanged(room pgstore.Room) {
+	msg := types.Message{
+		Kind:   types.MessageKindRoomStatusChanged,
+		RoomID: room.ID,
+		Value:  RoomStatusChangedValue(room),
+	}
+
+	w.NotifyRoomClient(msg)
+	if room.Visibility == RoomVisibilityPublic {
+		w.NotifyRoomsListClients(msg)
+	}
+}
+
+// NotifyRoomOwnerChanged tells the room subscribers who owns the room now, and
+// the rooms list subscribers too when the room is public.
+func (w *WebSocketService) NotifyRoomOwnerChanged(room pgstore.GetRoomWithUserRow) {
+	msg := types.Message{
+		Kind:   types.MessageKindRoomOwnerChanged,
+		RoomID: room.ID,
+		Value: types.RoomOwnerChanged{
+			ID:          room.ID,
+			UserID:      uuid.UUID(room.UserID.Bytes).String(),
+			CreatorName: room.CreatorName.String,
+			UpdatedAt:   room.UpdatedAt.Time.Format(time.RFC3339),
+		},
+	}
+
+	w.NotifyRoomClient(msg)
+	if room.Visibility == RoomVisibilityPublic {
+		w.NotifyRoomsListClients(msg)
+	}
+}
+
+func (w *WebSocketService) publish(msg types.Message) {
+	if err := w.Broadcaster.Publish(context.Background(), msg); err != nil {
+		slog.Error("failed to publish message", "topic", msg.Topic, "kind", msg.Kind, "error", err)
+	}
+}
+
+// deliver hands the message to the local subscribers of its topic. They all
+// share the same frame, so it is encoded once per wire format.
+func (w *WebSocketService) deliver(msg types.Message) {
+	f := newFrame(msg)
+
+	w.Mutex.RLock()
+	for sub := range w.Subscribers[msg.Topic] {
+		if !sub.deliver(f) {
+			go sub.evict(slowConsumerReason)
+		}
+	}
+	w.Mutex.RUnlock()
+
+	if msg.Kind == types.MessageKindRoomDeleted && msg.Topic != types.TopicRooms {
+		w.endTopic(msg.Topic, "room deleted")
+	}
+}
+
+// endTopic removes every subscriber from a topic that will not have further
+// messages. Connections opened for that topic alone are closed once they
+// have sent what is already queued.
+func (w *WebSocketService) endTopic(topic, reason string) {
 	w.Mutex.Lock()
 	defer w.Mutex.Unlock()
 
-	for conn, cancel := range w.RoomsListSubscribers {
-		if err := conn.WriteJSON(msg); err != nil {
-			slog.Error("failed to write room list message to client", "error", err)
-			cancel()
+	for sub := range w.Subscribers[topic] {
+		sub.mu.Lock()
+		delete(sub.topics, topic)
+		delete(sub.pending, topic)
+		sub.mu.Unlock()
+
+		if sub.dedicated {
+			sub.close(reason)
 		}
 	}
+
+	delete(w.Subscribers, topic)
+	slog.Info("topic ended", "topic", topic, "reason", reason)
+}
+
+func durationFromEnv(key string, fallback time.Duration) time.Duration {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return fallback
+	}
+
+	value, err := time.ParseDuration(raw)
+	if err != nil || value <= 0 {
+		slog.Warn("invalid duration, using default", "key", key, "value", raw, "default", fallback)
+		return fallback
+	}
+
+	return value
+}
+
+func intFromEnv(key string, fallback int) int {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return fallback
+	}
+
+	value, err := strconv.Atoi(raw)
+	if err != nil || value <= 0 {
+		slog.Warn("invalid number, using default", "key", key, "value", raw, "default", fallback)
+		return fallback
+	}
+
+	return value
 }
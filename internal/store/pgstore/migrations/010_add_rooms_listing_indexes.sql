@@ -0,0 +1,13 @@
+CREATE INDEX IF NOT EXISTS idx_rooms_created_at_id ON rooms (created_at, id);
+
+CREATE INDEX IF NOT EXISTS idx_rooms_user_id ON rooms (user_id);
+
+CREATE INDEX IF NOT EXISTS idx_messages_room_id ON messages (room_id);
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS idx_messages_room_id;
+
+DROP INDEX IF EXISTS idx_rooms_user_id;
+
+DROP INDEX IF EXISTS idx_rooms_created_at_id;
@@ -0,0 +1,39 @@
+ALTER TABLE rooms
+ADD COLUMN "visibility" VARCHAR(16) NOT NULL DEFAULT 'public'
+CHECK (visibility IN ('public', 'unlisted', 'private'));
+
+CREATE TABLE IF NOT EXISTS room_invites (
+  "token" VARCHAR(64) PRIMARY KEY NOT NULL,
+  "room_id" BIGINT NOT NULL,
+  "created_by" uuid NOT NULL,
+  "expires_at" TIMESTAMP,
+  "max_uses" INT,
+  "uses" INT NOT NULL DEFAULT 0,
+  "email_domains" TEXT[] NOT NULL DEFAULT '{}',
+  "created_at" TIMESTAMP NOT NULL DEFAULT now(),
+
+  FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE ON UPDATE CASCADE,
+  FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_room_invites_room_id ON room_invites (room_id);
+
+CREATE TABLE IF NOT EXISTS room_guests (
+  "room_id" BIGINT NOT NULL,
+  "user_id" uuid NOT NULL,
+  "created_at" TIMESTAMP NOT NULL DEFAULT now(),
+
+  FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE ON UPDATE CASCADE,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE ON UPDATE CASCADE,
+
+  PRIMARY KEY (room_id, user_id)
+);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS room_guests;
+
+DROP TABLE IF EXISTS room_invites;
+
+ALTER TABLE rooms
+DROP COLUMN visibility;
@@ -0,0 +1,18 @@
+ALTER TABLE rooms
+ADD COLUMN "status" VARCHAR(16) NOT NULL DEFAULT 'open'
+CHECK (status IN ('draft', 'scheduled', 'open', 'closed', 'archived')),
+ADD COLUMN "opens_at" TIMESTAMP,
+ADD COLUMN "closes_at" TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_rooms_opens_at ON rooms (opens_at) WHERE status = 'scheduled';
+CREATE INDEX IF NOT EXISTS idx_rooms_closes_at ON rooms (closes_at) WHERE status = 'open';
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS idx_rooms_closes_at;
+DROP INDEX IF EXISTS idx_rooms_opens_at;
+
+ALTER TABLE rooms
+DROP COLUMN closes_at,
+DROP COLUMN opens_at,
+DROP COLUMN status;
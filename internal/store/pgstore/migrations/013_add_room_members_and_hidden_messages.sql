@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS room_members (
+  "room_id" BIGINT NOT NULL,
+  "user_id" uuid NOT NULL,
+  "role" VARCHAR(16) NOT NULL CHECK (role IN ('moderator', 'panelist')),
+  "created_at" TIMESTAMP NOT NULL DEFAULT now(),
+
+  FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE ON UPDATE CASCADE,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE ON UPDATE CASCADE,
+
+  PRIMARY KEY (room_id, user_id)
+);
+
+ALTER TABLE messages
+ADD COLUMN "hidden" BOOLEAN NOT NULL DEFAULT false;
+
+---- create above / drop below ----
+
+ALTER TABLE messages
+DROP COLUMN hidden;
+
+DROP TABLE IF EXISTS room_members;
@@ -0,0 +1,28 @@
+ALTER TABLE rooms
+ADD COLUMN "slug" VARCHAR(80);
+
+-- The name is cut so that the slug with its id suffix fits in 60 characters
+UPDATE rooms
+SET slug = COALESCE(NULLIF(TRIM(BOTH '-' FROM LEFT(REGEXP_REPLACE(LOWER(name), '[^a-z0-9]+', '-', 'g'), 60 - 1 - length(id::text))), ''), 'room') || '-' || id;
+
+ALTER TABLE rooms
+ALTER COLUMN slug SET NOT NULL,
+ADD CONSTRAINT rooms_slug_key UNIQUE (slug);
+
+CREATE TABLE IF NOT EXISTS room_slug_redirects (
+  "slug" VARCHAR(80) PRIMARY KEY NOT NULL,
+  "room_id" BIGINT NOT NULL,
+  "created_at" TIMESTAMP NOT NULL DEFAULT now(),
+
+  FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_room_slug_redirects_room_id ON room_slug_redirects (room_id);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS room_slug_redirects;
+
+ALTER TABLE rooms
+DROP CONSTRAINT rooms_slug_key,
+DROP COLUMN slug;
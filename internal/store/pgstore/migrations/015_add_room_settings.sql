@@ -0,0 +1,34 @@
+ALTER TABLE rooms
+ADD COLUMN "max_question_length" INTEGER NOT NULL DEFAULT 255 CHECK (max_question_length BETWEEN 1 AND 2000),
+ADD COLUMN "slow_mode_seconds" INTEGER NOT NULL DEFAULT 0 CHECK (slow_mode_seconds BETWEEN 0 AND 3600),
+ADD COLUMN "reactions_allowed" BOOLEAN NOT NULL DEFAULT true,
+ADD COLUMN "public_read" BOOLEAN NOT NULL DEFAULT false,
+ADD COLUMN "anonymous_questions" BOOLEAN NOT NULL DEFAULT true;
+
+ALTER TABLE messages
+ALTER COLUMN "message" TYPE VARCHAR(2000);
+
+CREATE TABLE IF NOT EXISTS room_question_cooldowns (
+  "room_id" BIGINT NOT NULL,
+  "user_id" uuid NOT NULL,
+  "asked_at" TIMESTAMP NOT NULL DEFAULT now(),
+
+  FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE ON UPDATE CASCADE,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE ON UPDATE CASCADE,
+
+  PRIMARY KEY (room_id, user_id)
+);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS room_question_cooldowns;
+
+ALTER TABLE messages
+ALTER COLUMN "message" TYPE VARCHAR(255) USING LEFT(message, 255);
+
+ALTER TABLE rooms
+DROP COLUMN max_question_length,
+DROP COLUMN slow_mode_seconds,
+DROP COLUMN reactions_allowed,
+DROP COLUMN public_read,
+DROP COLUMN anonymous_questions;
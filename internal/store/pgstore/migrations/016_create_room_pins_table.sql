@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS room_pins (
+  "room_id" BIGINT PRIMARY KEY NOT NULL,
+  "message_id" uuid NOT NULL,
+  "pinned_by" uuid,
+  "pinned_at" TIMESTAMP NOT NULL DEFAULT now(),
+
+  FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE ON UPDATE CASCADE,
+  FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE ON UPDATE CASCADE,
+  FOREIGN KEY (pinned_by) REFERENCES users(id) ON DELETE SET NULL ON UPDATE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_room_pins_message_id ON room_pins (message_id);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS room_pins;
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS room_transfers (
+  "room_id" BIGINT PRIMARY KEY NOT NULL,
+  "from_user_id" uuid NOT NULL,
+  "to_user_id" uuid NOT NULL,
+  "created_at" TIMESTAMP NOT NULL DEFAULT now(),
+
+  FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE ON UPDATE CASCADE,
+  FOREIGN KEY (from_user_id) REFERENCES users(id) ON DELETE CASCADE ON UPDATE CASCADE,
+  FOREIGN KEY (to_user_id) REFERENCES users(id) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_room_transfers_to_user_id ON room_transfers (to_user_id);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS room_transfers;
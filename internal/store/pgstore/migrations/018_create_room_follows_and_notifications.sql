@@ -0,0 +1,34 @@
+CREATE TABLE IF NOT EXISTS room_follows (
+  "room_id" BIGINT NOT NULL,
+  "user_id" uuid NOT NULL,
+  "created_at" TIMESTAMP NOT NULL DEFAULT now(),
+
+  FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE ON UPDATE CASCADE,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE ON UPDATE CASCADE,
+
+  PRIMARY KEY (room_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_room_follows_user_id ON room_follows (user_id);
+
+CREATE TABLE IF NOT EXISTS notifications (
+  "id" uuid PRIMARY KEY NOT NULL DEFAULT gen_random_uuid(),
+  "user_id" uuid NOT NULL,
+  "room_id" BIGINT NOT NULL,
+  "message_id" uuid,
+  "kind" VARCHAR(32) NOT NULL CHECK (kind IN ('room_opened', 'message_answered')),
+  "created_at" TIMESTAMP NOT NULL DEFAULT now(),
+  "read_at" TIMESTAMP,
+
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE ON UPDATE CASCADE,
+  FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE ON UPDATE CASCADE,
+  FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user_id ON notifications (user_id, created_at) WHERE read_at IS NULL;
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS notifications;
+
+DROP TABLE IF EXISTS room_follows;
@@ -0,0 +1,21 @@
+ALTER TABLE messages
+ADD COLUMN "user_id" uuid,
+ADD COLUMN "anonymous" BOOLEAN NOT NULL DEFAULT false;
+
+ALTER TABLE messages
+ADD CONSTRAINT fk_messages_user_id
+FOREIGN KEY (user_id) REFERENCES users(id)
+ON DELETE SET NULL ON UPDATE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_messages_user_id ON messages (user_id);
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS idx_messages_user_id;
+
+ALTER TABLE messages
+DROP CONSTRAINT fk_messages_user_id;
+
+ALTER TABLE messages
+DROP COLUMN anonymous,
+DROP COLUMN user_id;
@@ -0,0 +1,11 @@
+ALTER TABLE messages
+ADD COLUMN "answered_at" TIMESTAMP;
+
+UPDATE messages
+SET answered_at = updated_at
+WHERE answered;
+
+---- create above / drop below ----
+
+ALTER TABLE messages
+DROP COLUMN answered_at;
@@ -10,13 +10,17 @@ import (
 )
 
 type Message struct {
-	ID        uuid.UUID        `db:"id" json:"id"`
-	RoomID    int64            `db:"room_id" json:"room_id"`
-	Message   string           `db:"message" json:"message"`
-	Answered  bool             `db:"answered" json:"answered"`
-	CreatedAt pgtype.Timestamp `db:"created_at" json:"created_at"`
-	UpdatedAt pgtype.Timestamp `db:"updated_at" json:"updated_at"`
-	Answer    string           `db:"answer" json:"answer"`
+	ID         uuid.UUID        `db:"id" json:"id"`
+	RoomID     int64            `db:"room_id" json:"room_id"`
+	Message    string           `db:"message" json:"message"`
+	Answered   bool             `db:"answered" json:"answered"`
+	CreatedAt  pgtype.Timestamp `db:"created_at" json:"created_at"`
+	UpdatedAt  pgtype.Timestamp `db:"updated_at" json:"updated_at"`
+	Answer     string           `db:"answer" json:"answer"`
+	Hidden     bool             `db:"hidden" json:"hidden"`
+	UserID     pgtype.UUID      `db:"user_id" json:"user_id"`
+	Anonymous  bool             `db:"anonymous" json:"anonymous"`
+	AnsweredAt pgtype.Timestamp `db:"answered_at" json:"answered_at"`
 }
 
 type MessagesReaction struct {
@@ -24,13 +28,89 @@ type MessagesReaction struct {
 	UserID    uuid.UUID `db:"user_id" json:"user_id"`
 }
 
+type Notification struct {
+	ID        uuid.UUID        `db:"id" json:"id"`
+	UserID    uuid.UUID        `db:"user_id" json:"user_id"`
+	RoomID    int64            `db:"room_id" json:"room_id"`
+	MessageID pgtype.UUID      `db:"message_id" json:"message_id"`
+	Kind      string           `db:"kind" json:"kind"`
+	CreatedAt pgtype.Timestamp `db:"created_at" json:"created_at"`
+	ReadAt    pgtype.Timestamp `db:"read_at" json:"read_at"`
+}
+
 type Room struct {
-	ID          int64            `db:"id" json:"id"`
-	Name        string           `db:"name" json:"name"`
-	CreatedAt   pgtype.Timestamp `db:"created_at" json:"created_at"`
-	UpdatedAt   pgtype.Timestamp `db:"updated_at" json:"updated_at"`
-	UserID      uuid.UUID        `db:"user_id" json:"user_id"`
-	Description string           `db:"description" json:"description"`
+	ID                 int64            `db:"id" json:"id"`
+	Name               string           `db:"name" json:"name"`
+	CreatedAt          pgtype.Timestamp `db:"created_at" json:"created_at"`
+	UpdatedAt          pgtype.Timestamp `db:"updated_at" json:"updated_at"`
+	UserID             uuid.UUID        `db:"user_id" json:"user_id"`
+	Description        string           `db:"description" json:"description"`
+	Visibility         string           `db:"visibility" json:"visibility"`
+	Status             string           `db:"status" json:"status"`
+	OpensAt            pgtype.Timestamp `db:"opens_at" json:"opens_at"`
+	ClosesAt           pgtype.Timestamp `db:"closes_at" json:"closes_at"`
+	Slug               string           `db:"slug" json:"slug"`
+	MaxQuestionLength  int32            `db:"max_question_length" json:"max_question_length"`
+	SlowModeSeconds    int32            `db:"slow_mode_seconds" json:"slow_mode_seconds"`
+	ReactionsAllowed   bool             `db:"reactions_allowed" json:"reactions_allowed"`
+	PublicRead         bool             `db:"public_read" json:"public_read"`
+	AnonymousQuestions bool             `db:"anonymous_questions" json:"anonymous_questions"`
+}
+
+type RoomFollow struct {
+	RoomID    int64            `db:"room_id" json:"room_id"`
+	UserID    uuid.UUID        `db:"user_id" json:"user_id"`
+	CreatedAt pgtype.Timestamp `db:"created_at" json:"created_at"`
+}
+
+type RoomGuest struct {
+	RoomID    int64            `db:"room_id" json:"room_id"`
+	UserID    uuid.UUID        `db:"user_id" json:"user_id"`
+	CreatedAt pgtype.Timestamp `db:"created_at" json:"created_at"`
+}
+
+type RoomInvite struct {
+	Token        string           `db:"token" json:"token"`
+	RoomID       int64            `db:"room_id" json:"room_id"`
+	CreatedBy    uuid.UUID        `db:"created_by" json:"created_by"`
+	ExpiresAt    pgtype.Timestamp `db:"expires_at" json:"expires_at"`
+	MaxUses      pgtype.Int4      `db:"max_uses" json:"max_uses"`
+	Uses         int32            `db:"uses" json:"uses"`
+	EmailDomains []string         `db:"email_domains" json:"email_domains"`
+	CreatedAt    pgtype.Timestamp `db:"created_at" json:"created_at"`
+}
+
+type RoomMember struct {
+	RoomID    int64            `db:"room_id" json:"room_id"`
+	UserID    uuid.UUID        `db:"user_id" json:"user_id"`
+	Role      string           `db:"role" json:"role"`
+	CreatedAt pgtype.Timestamp `db:"created_at" json:"created_at"`
+}
+
+type RoomPin struct {
+	RoomID    int64            `db:"room_id" json:"room_id"`
+	MessageID uuid.UUID        `db:"message_id" json:"message_id"`
+	PinnedBy  pgtype.UUID      `db:"pinned_by" json:"pinned_by"`
+	PinnedAt  pgtype.Timestamp `db:"pinned_at" json:"pinned_at"`
+}
+
+type RoomQuestionCooldown struct {
+	RoomID  int64            `db:"room_id" json:"room_id"`
+	UserID  uuid.UUID        `db:"user_id" json:"user_id"`
+	AskedAt pgtype.Timestamp `db:"asked_at" json:"asked_at"`
+}
+
+type RoomSlugRedirect struct {
+	Slug      string           `db:"slug" json:"slug"`
+	RoomID    int64            `db:"room_id" json:"room_id"`
+	CreatedAt pgtype.Timestamp `db:"created_at" json:"created_at"`
+}
+
+type RoomTransfer struct {
+	RoomID     int64            `db:"room_id" json:"room_id"`
+	FromUserID uuid.UUID        `db:"from_user_id" json:"from_user_id"`
+	ToUserID   uuid.UUID        `db:"to_user_id" json:"to_user_id"`
+	CreatedAt  pgtype.Timestamp `db:"created_at" json:"created_at"`
 }
 
 type User struct {
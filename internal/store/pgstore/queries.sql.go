@@ -12,11 +12,61 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const acceptRoomTransfer = `-- name: AcceptRoomTransfer :one
+WITH transfer AS (
+  DELETE FROM room_transfers t
+  WHERE t.room_id = $1 AND t.to_user_id = $2
+  RETURNING t.room_id, t.from_user_id, t.to_user_id
+), moved AS (
+  UPDATE rooms r
+  SET user_id = t.to_user_id, updated_at = now()
+  FROM transfer t
+  WHERE r.id = t.room_id AND r.user_id = t.from_user_id
+  RETURNING r.id, r.name, r.created_at, r.updated_at, r.user_id, r.description, r.visibility, r.status, r.opens_at, r.closes_at, r.slug, r.max_question_length, r.slow_mode_seconds, r.reactions_allowed, r.public_read, r.anonymous_questions
+), promoted AS (
+  -- The new owner leaves the hosts only when the room did change hands
+  DELETE FROM room_members rm
+  USING moved m
+  WHERE rm.room_id = m.id AND rm.user_id = m.user_id
+)
+SELECT id, name, created_at, updated_at, user_id, description, visibility, status, opens_at, closes_at, slug, max_question_length, slow_mode_seconds, reactions_allowed, public_read, anonymous_questions FROM moved
+`
+
+type AcceptRoomTransferParams struct {
+	RoomID int64     `db:"room_id" json:"room_id"`
+	UserID uuid.UUID `db:"user_id" json:"user_id"`
+}
+
+func (q *Queries) AcceptRoomTransfer(ctx context.Context, arg AcceptRoomTransferParams) (Room, error) {
+	row := q.db.QueryRow(ctx, acceptRoomTransfer, arg.RoomID, arg.UserID)
+	var i Room
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Description,
+		&i.Visibility,
+		&i.Status,
+		&i.OpensAt,
+		&i.ClosesAt,
+		&i.Slug,
+		&i.MaxQuestionLength,
+		&i.SlowModeSeconds,
+		&i.ReactionsAllowed,
+		&i.PublicRead,
+		&i.AnonymousQuestions,
+	)
+	return i, err
+}
+
 const answerMessage = `-- name: AnswerMessage :one
 UPDATE messages
 SET
   answered = true,
   answer = $1,
+  answered_at = now(),
   updated_at = now()
 WHERE
   id = $2 AND answered = false
@@ -35,6 +85,50 @@ func (q *Queries) AnswerMessage(ctx context.Context, arg AnswerMessageParams) (b
 	return answered, err
 }
 
+const closeDueRooms = `-- name: CloseDueRooms :many
+UPDATE rooms
+SET status = 'closed', updated_at = now()
+WHERE status = 'open' AND closes_at <= now()
+RETURNING id, name, created_at, updated_at, user_id, description, visibility, status, opens_at, closes_at, slug, max_question_length, slow_mode_seconds, reactions_allowed, public_read, anonymous_questions
+`
+
+func (q *Queries) CloseDueRooms(ctx context.Context) ([]Room, error) {
+	rows, err := q.db.Query(ctx, closeDueRooms)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Room
+	for rows.Next() {
+		var i Room
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.Description,
+			&i.Visibility,
+			&i.Status,
+			&i.OpensAt,
+			&i.ClosesAt,
+			&i.Slug,
+			&i.MaxQuestionLength,
+			&i.SlowModeSeconds,
+			&i.ReactionsAllowed,
+			&i.PublicRead,
+			&i.AnonymousQuestions,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const createUser = `-- name: CreateUser :one
 INSERT INTO users
   ("email", "name", "provider", "provider_user_id", "photo") VALUES
@@ -69,6 +163,97 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (CreateU
 	return i, err
 }
 
+const deleteOwnMessage = `-- name: DeleteOwnMessage :one
+DELETE FROM messages m
+WHERE
+  m.id = $1 AND m.room_id = $2 AND m.user_id = $3 AND NOT m.answered AND NOT m.hidden
+  AND m.created_at > now() - $4::int * INTERVAL '1 second'
+RETURNING EXISTS(SELECT 1 FROM room_pins p WHERE p.message_id = m.id) AS "pinned"
+`
+
+type DeleteOwnMessageParams struct {
+	ID                uuid.UUID   `db:"id" json:"id"`
+	RoomID            int64       `db:"room_id" json:"room_id"`
+	UserID            pgtype.UUID `db:"user_id" json:"user_id"`
+	EditWindowSeconds int32       `db:"edit_window_seconds" json:"edit_window_seconds"`
+}
+
+func (q *Queries) DeleteOwnMessage(ctx context.Context, arg DeleteOwnMessageParams) (bool, error) {
+	row := q.db.QueryRow(ctx, deleteOwnMessage,
+		arg.ID,
+		arg.RoomID,
+		arg.UserID,
+		arg.EditWindowSeconds,
+	)
+	var pinned bool
+	err := row.Scan(&pinned)
+	return pinned, err
+}
+
+const deleteRoom = `-- name: DeleteRoom :one
+DELETE FROM rooms
+WHERE id = $1 RETURNING visibility
+`
+
+func (q *Queries) DeleteRoom(ctx context.Context, id int64) (string, error) {
+	row := q.db.QueryRow(ctx, deleteRoom, id)
+	var visibility string
+	err := row.Scan(&visibility)
+	return visibility, err
+}
+
+const deleteRoomInvite = `-- name: DeleteRoomInvite :one
+DELETE FROM room_invites
+WHERE token = $1 AND room_id = $2 RETURNING token
+`
+
+type DeleteRoomInviteParams struct {
+	Token  string `db:"token" json:"token"`
+	RoomID int64  `db:"room_id" json:"room_id"`
+}
+
+func (q *Queries) DeleteRoomInvite(ctx context.Context, arg DeleteRoomInviteParams) (string, error) {
+	row := q.db.QueryRow(ctx, deleteRoomInvite, arg.Token, arg.RoomID)
+	var token string
+	err := row.Scan(&token)
+	return token, err
+}
+
+const deleteRoomMember = `-- name: DeleteRoomMember :one
+DELETE FROM room_members
+WHERE room_id = $1 AND user_id = $2 RETURNING user_id
+`
+
+type DeleteRoomMemberParams struct {
+	RoomID int64     `db:"room_id" json:"room_id"`
+	UserID uuid.UUID `db:"user_id" json:"user_id"`
+}
+
+func (q *Queries) DeleteRoomMember(ctx context.Context, arg DeleteRoomMemberParams) (uuid.UUID, error) {
+	row := q.db.QueryRow(ctx, deleteRoomMember, arg.RoomID, arg.UserID)
+	var user_id uuid.UUID
+	err := row.Scan(&user_id)
+	return user_id, err
+}
+
+const deleteRoomTransfer = `-- name: DeleteRoomTransfer :one
+DELETE FROM room_transfers
+WHERE room_id = $1 AND (from_user_id = $2 OR to_user_id = $2)
+RETURNING room_id
+`
+
+type DeleteRoomTransferParams struct {
+	RoomID     int64     `db:"room_id" json:"room_id"`
+	FromUserID uuid.UUID `db:"from_user_id" json:"from_user_id"`
+}
+
+func (q *Queries) DeleteRoomTransfer(ctx context.Context, arg DeleteRoomTransferParams) (int64, error) {
+	row := q.db.QueryRow(ctx, deleteRoomTransfer, arg.RoomID, arg.FromUserID)
+	var room_id int64
+	err := row.Scan(&room_id)
+	return room_id, err
+}
+
 const deleteUser = `-- name: DeleteUser :one
 DELETE FROM users
 WHERE id = $1 RETURNING id
@@ -80,77 +265,80 @@ func (q *Queries) DeleteUser(ctx context.Context, id uuid.UUID) (uuid.UUID, erro
 	return id, err
 }
 
-const getMessage = `-- name: GetMessage :one
-SELECT id, room_id, message, answered, created_at, updated_at, answer FROM messages WHERE id = $1
+const deleteUserWithSuccessor = `-- name: DeleteUserWithSuccessor :one
+WITH handed_over AS (
+  UPDATE rooms SET user_id = $1, updated_at = now()
+  WHERE user_id = $2
+  RETURNING id
+), promoted AS (
+  DELETE FROM room_members rm
+  USING handed_over h
+  WHERE rm.room_id = h.id AND rm.user_id = $1
+)
+DELETE FROM users
+WHERE users.id = $2
+RETURNING users.id, ARRAY(SELECT id FROM handed_over ORDER BY id)::bigint[] AS "room_ids"
 `
 
-func (q *Queries) GetMessage(ctx context.Context, id uuid.UUID) (Message, error) {
-	row := q.db.QueryRow(ctx, getMessage, id)
-	var i Message
-	err := row.Scan(
-		&i.ID,
-		&i.RoomID,
-		&i.Message,
-		&i.Answered,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-		&i.Answer,
-	)
-	return i, err
+type DeleteUserWithSuccessorParams struct {
+	SuccessorID uuid.UUID `db:"successor_id" json:"successor_id"`
+	ID          uuid.UUID `db:"id" json:"id"`
 }
 
-const getRoom = `-- name: GetRoom :one
-SELECT id, name, created_at, updated_at, user_id, description FROM rooms WHERE id = $1
-`
+type DeleteUserWithSuccessorRow struct {
+	ID      uuid.UUID `db:"id" json:"id"`
+	RoomIds []int64   `db:"room_ids" json:"room_ids"`
+}
 
-func (q *Queries) GetRoom(ctx context.Context, id int64) (Room, error) {
-	row := q.db.QueryRow(ctx, getRoom, id)
-	var i Room
-	err := row.Scan(
-		&i.ID,
-		&i.Name,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-		&i.UserID,
-		&i.Description,
-	)
+func (q *Queries) DeleteUserWithSuccessor(ctx context.Context, arg DeleteUserWithSuccessorParams) (DeleteUserWithSuccessorRow, error) {
+	row := q.db.QueryRow(ctx, deleteUserWithSuccessor, arg.SuccessorID, arg.ID)
+	var i DeleteUserWithSuccessorRow
+	err := row.Scan(&i.ID, &i.RoomIds)
 	return i, err
 }
 
-const getRoomMessages = `-- name: GetRoomMessages :many
-SELECT m.id, m.room_id, m.message, m.answered, m.created_at, m.updated_at, m.answer, COUNT(mr.message_id) AS reaction_count FROM messages m
+const exportRoomMessages = `-- name: ExportRoomMessages :many
+SELECT m."id", m."message", m."answer", m."answered", m."created_at", m."updated_at", COUNT(mr.message_id) AS "reaction_count"
+FROM messages m
 LEFT JOIN messages_reactions mr ON mr.message_id = m.id
-WHERE room_id = $1 GROUP BY m.id ORDER BY created_at DESC
+WHERE m.room_id = $1 AND NOT m.hidden
+GROUP BY m.id
+ORDER BY
+  CASE WHEN $2::boolean THEN COUNT(mr.message_id) END DESC,
+  m.created_at, m.id
 `
 
-type GetRoomMessagesRow struct {
+type ExportRoomMessagesParams struct {
+	RoomID      int64 `db:"room_id" json:"room_id"`
+	ByReactions bool  `db:"by_reactions" json:"by_reactions"`
+}
+
+type ExportRoomMessagesRow struct {
 	ID            uuid.UUID        `db:"id" json:"id"`
-	RoomID        int64            `db:"room_id" json:"room_id"`
 	Message       string           `db:"message" json:"message"`
+	Answer        string           `db:"answer" json:"answer"`
 	Answered      bool             `db:"answered" json:"answered"`
 	CreatedAt     pgtype.Timestamp `db:"created_at" json:"created_at"`
 	UpdatedAt     pgtype.Timestamp `db:"updated_at" json:"updated_at"`
-	Answer        string           `db:"answer" json:"answer"`
 	ReactionCount int64            `db:"reaction_count" json:"reaction_count"`
 }
 
-func (q *Queries) GetRoomMessages(ctx context.Context, roomID int64) ([]GetRoomMessagesRow, error) {
-	rows, err := q.db.Query(ctx, getRoomMessages, roomID)
+func (q *Queries) ExportRoomMessages(ctx context.Context, arg ExportRoomMessagesParams) ([]ExportRoomMessagesRow, error) {
+	rows, err := q.db.Query(ctx, exportRoomMessages, arg.RoomID, arg.ByReactions)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []GetRoomMessagesRow
+	var items []ExportRoomMessagesRow
 	for rows.Next() {
-		var i GetRoomMessagesRow
+		var i ExportRoomMessagesRow
 		if err := rows.Scan(
 			&i.ID,
-			&i.RoomID,
 			&i.Message,
+			&i.Answer,
 			&i.Answered,
 			&i.CreatedAt,
 			&i.UpdatedAt,
-			&i.Answer,
 			&i.ReactionCount,
 		); err != nil {
 			return nil, err
@@ -163,30 +351,66 @@ func (q *Queries) GetRoomMessages(ctx context.Context, roomID int64) ([]GetRoomM
 	return items, nil
 }
 
-const getRoomMessagesReactions = `-- name: GetRoomMessagesReactions :many
-SELECT mr.message_id FROM messages_reactions mr 
-LEFT JOIN messages m ON m.id = mr.message_id 
-WHERE m.room_id = $1 AND mr.user_id = $2
+const followRoom = `-- name: FollowRoom :exec
+INSERT INTO room_follows ("room_id", "user_id") VALUES ($1, $2)
+ON CONFLICT DO NOTHING
 `
 
-type GetRoomMessagesReactionsParams struct {
+type FollowRoomParams struct {
 	RoomID int64     `db:"room_id" json:"room_id"`
 	UserID uuid.UUID `db:"user_id" json:"user_id"`
 }
 
-func (q *Queries) GetRoomMessagesReactions(ctx context.Context, arg GetRoomMessagesReactionsParams) ([]uuid.UUID, error) {
-	rows, err := q.db.Query(ctx, getRoomMessagesReactions, arg.RoomID, arg.UserID)
+func (q *Queries) FollowRoom(ctx context.Context, arg FollowRoomParams) error {
+	_, err := q.db.Exec(ctx, followRoom, arg.RoomID, arg.UserID)
+	return err
+}
+
+const getFollowedRooms = `-- name: GetFollowedRooms :many
+SELECT r."id", r."name", r."slug", r."status", r."opens_at", r."closes_at", f."created_at" AS "followed_at"
+FROM room_follows f
+JOIN rooms r ON r.id = f.room_id
+WHERE f.user_id = $1
+  AND (
+    r.visibility = 'public'
+    OR r.user_id = f.user_id
+    OR EXISTS (SELECT 1 FROM room_guests g WHERE g.room_id = r.id AND g.user_id = f.user_id)
+    OR EXISTS (SELECT 1 FROM room_members rm WHERE rm.room_id = r.id AND rm.user_id = f.user_id)
+  )
+ORDER BY f.created_at DESC, r.id
+`
+
+type GetFollowedRoomsRow struct {
+	ID         int64            `db:"id" json:"id"`
+	Name       string           `db:"name" json:"name"`
+	Slug       string           `db:"slug" json:"slug"`
+	Status     string           `db:"status" json:"status"`
+	OpensAt    pgtype.Timestamp `db:"opens_at" json:"opens_at"`
+	ClosesAt   pgtype.Timestamp `db:"closes_at" json:"closes_at"`
+	FollowedAt pgtype.Timestamp `db:"followed_at" json:"followed_at"`
+}
+
+func (q *Queries) GetFollowedRooms(ctx context.Context, userID uuid.UUID) ([]GetFollowedRoomsRow, error) {
+	rows, err := q.db.Query(ctx, getFollowedRooms, userID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []uuid.UUID
+	var items []GetFollowedRoomsRow
 	for rows.Next() {
-		var message_id uuid.UUID
-		if err := rows.Scan(&message_id); err != nil {
+		var i GetFollowedRoomsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Slug,
+			&i.Status,
+			&i.OpensAt,
+			&i.ClosesAt,
+			&i.FollowedAt,
+		); err != nil {
 			return nil, err
 		}
-		items = append(items, message_id)
+		items = append(items, i)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, err
@@ -194,78 +418,81 @@ func (q *Queries) GetRoomMessagesReactions(ctx context.Context, arg GetRoomMessa
 	return items, nil
 }
 
-const getRoomWithUser = `-- name: GetRoomWithUser :one
-SELECT
-  r."id", r."name", r."description", r."created_at", r."updated_at", u."email", u."name" as "creator_name", u."id" as "user_id", u."photo", u."enable_picture"
-FROM rooms r
-LEFT JOIN users u ON r.user_id = u.id
-WHERE r.id = $1
+const getImportTargetRoom = `-- name: GetImportTargetRoom :one
+SELECT r.id, r.name, r.created_at, r.updated_at, r.user_id, r.description, r.visibility, r.status, r.opens_at, r.closes_at, r.slug, r.max_question_length, r.slow_mode_seconds, r.reactions_allowed, r.public_read, r.anonymous_questions FROM rooms r
+WHERE r.user_id = $1 AND (
+  r.slug = $2
+  OR EXISTS (SELECT 1 FROM room_slug_redirects rr WHERE rr.room_id = r.id AND rr.slug = $2)
+  OR (r.name = $3 AND r.slug ~ ('^' || $2 || '-[0-9]+$'))
+)
+ORDER BY r.slug = $2 DESC, r.id
+LIMIT 1
 `
 
-type GetRoomWithUserRow struct {
-	ID            int64            `db:"id" json:"id"`
-	Name          string           `db:"name" json:"name"`
-	Description   string           `db:"description" json:"description"`
-	CreatedAt     pgtype.Timestamp `db:"created_at" json:"created_at"`
-	UpdatedAt     pgtype.Timestamp `db:"updated_at" json:"updated_at"`
-	Email         pgtype.Text      `db:"email" json:"email"`
-	CreatorName   pgtype.Text      `db:"creator_name" json:"creator_name"`
-	UserID        pgtype.UUID      `db:"user_id" json:"user_id"`
-	Photo         pgtype.Text      `db:"photo" json:"photo"`
-	EnablePicture pgtype.Bool      `db:"enable_picture" json:"enable_picture"`
+type GetImportTargetRoomParams struct {
+	UserID uuid.UUID `db:"user_id" json:"user_id"`
+	Slug   string    `db:"slug" json:"slug"`
+	Name   string    `db:"name" json:"name"`
 }
 
-func (q *Queries) GetRoomWithUser(ctx context.Context, id int64) (GetRoomWithUserRow, error) {
-	row := q.db.QueryRow(ctx, getRoomWithUser, id)
-	var i GetRoomWithUserRow
+func (q *Queries) GetImportTargetRoom(ctx context.Context, arg GetImportTargetRoomParams) (Room, error) {
+	row := q.db.QueryRow(ctx, getImportTargetRoom, arg.UserID, arg.Slug, arg.Name)
+	var i Room
 	err := row.Scan(
 		&i.ID,
 		&i.Name,
-		&i.Description,
 		&i.CreatedAt,
 		&i.UpdatedAt,
-		&i.Email,
-		&i.CreatorName,
 		&i.UserID,
-		&i.Photo,
-		&i.EnablePicture,
+		&i.Description,
+		&i.Visibility,
+		&i.Status,
+		&i.OpensAt,
+		&i.ClosesAt,
+		&i.Slug,
+		&i.MaxQuestionLength,
+		&i.SlowModeSeconds,
+		&i.ReactionsAllowed,
+		&i.PublicRead,
+		&i.AnonymousQuestions,
 	)
 	return i, err
 }
 
-const getRooms = `-- name: GetRooms :many
-SELECT r.id, r.name, r.created_at, r.updated_at, r.user_id, r.description, u."name" AS "creator_name" FROM rooms r
-LEFT JOIN users u ON r.user_id = u.id
-ORDER BY r.created_at ASC
+const getIncomingRoomTransfers = `-- name: GetIncomingRoomTransfers :many
+SELECT t."room_id", r."name" AS "room_name", r."slug" AS "room_slug", t."from_user_id", u."name" AS "from_user_name", t."created_at"
+FROM room_transfers t
+JOIN rooms r ON r.id = t.room_id AND r.user_id = t.from_user_id
+JOIN users u ON u.id = t.from_user_id
+WHERE t.to_user_id = $1
+ORDER BY t.created_at DESC, t.room_id
 `
 
-type GetRoomsRow struct {
-	ID          int64            `db:"id" json:"id"`
-	Name        string           `db:"name" json:"name"`
-	CreatedAt   pgtype.Timestamp `db:"created_at" json:"created_at"`
-	UpdatedAt   pgtype.Timestamp `db:"updated_at" json:"updated_at"`
-	UserID      uuid.UUID        `db:"user_id" json:"user_id"`
-	Description string           `db:"description" json:"description"`
-	CreatorName pgtype.Text      `db:"creator_name" json:"creator_name"`
+type GetIncomingRoomTransfersRow struct {
+	RoomID       int64            `db:"room_id" json:"room_id"`
+	RoomName     string           `db:"room_name" json:"room_name"`
+	RoomSlug     string           `db:"room_slug" json:"room_slug"`
+	FromUserID   uuid.UUID        `db:"from_user_id" json:"from_user_id"`
+	FromUserName string           `db:"from_user_name" json:"from_user_name"`
+	CreatedAt    pgtype.Timestamp `db:"created_at" json:"created_at"`
 }
 
-func (q *Queries) GetRooms(ctx context.Context) ([]GetRoomsRow, error) {
-	rows, err := q.db.Query(ctx, getRooms)
+func (q *Queries) GetIncomingRoomTransfers(ctx context.Context, toUserID uuid.UUID) ([]GetIncomingRoomTransfersRow, error) {
+	rows, err := q.db.Query(ctx, getIncomingRoomTransfers, toUserID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []GetRoomsRow
+	var items []GetIncomingRoomTransfersRow
 	for rows.Next() {
-		var i GetRoomsRow
+		var i GetIncomingRoomTransfersRow
 		if err := rows.Scan(
-			&i.ID,
-			&i.Name,
+			&i.RoomID,
+			&i.RoomName,
+			&i.RoomSlug,
+			&i.FromUserID,
+			&i.FromUserName,
 			&i.CreatedAt,
-			&i.UpdatedAt,
-			&i.UserID,
-			&i.Description,
-			&i.CreatorName,
 		); err != nil {
 			return nil, err
 		}
@@ -277,91 +504,902 @@ func (q *Queries) GetRooms(ctx context.Context) ([]GetRoomsRow, error) {
 	return items, nil
 }
 
-const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, email, name, created_at, updated_at, photo, enable_picture, provider, provider_user_id, new_user FROM users WHERE email = $1 LIMIT 1
+const getMessage = `-- name: GetMessage :one
+SELECT id, room_id, message, answered, created_at, updated_at, answer, hidden, user_id, anonymous FROM messages WHERE id = $1
 `
 
-func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
-	row := q.db.QueryRow(ctx, getUserByEmail, email)
-	var i User
+func (q *Queries) GetMessage(ctx context.Context, id uuid.UUID) (Message, error) {
+	row := q.db.QueryRow(ctx, getMessage, id)
+	var i Message
 	err := row.Scan(
 		&i.ID,
-		&i.Email,
-		&i.Name,
+		&i.RoomID,
+		&i.Message,
+		&i.Answered,
 		&i.CreatedAt,
 		&i.UpdatedAt,
-		&i.Photo,
-		&i.EnablePicture,
-		&i.Provider,
-		&i.ProviderUserID,
-		&i.NewUser,
+		&i.Answer,
+		&i.Hidden,
+		&i.UserID,
+		&i.Anonymous,
 	)
 	return i, err
 }
 
-const getUserById = `-- name: GetUserById :one
-SELECT id, email, name, created_at, updated_at, photo, enable_picture, provider, provider_user_id, new_user FROM users WHERE id = $1 LIMIT 1
+const getQuestionCooldown = `-- name: GetQuestionCooldown :one
+SELECT CEIL(EXTRACT(EPOCH FROM asked_at + $3::int * INTERVAL '1 second' - now()))::int AS "retry_after"
+FROM room_question_cooldowns
+WHERE room_id = $1 AND user_id = $2
 `
 
-func (q *Queries) GetUserById(ctx context.Context, id uuid.UUID) (User, error) {
-	row := q.db.QueryRow(ctx, getUserById, id)
-	var i User
+type GetQuestionCooldownParams struct {
+	RoomID          int64     `db:"room_id" json:"room_id"`
+	UserID          uuid.UUID `db:"user_id" json:"user_id"`
+	SlowModeSeconds int32     `db:"slow_mode_seconds" json:"slow_mode_seconds"`
+}
+
+func (q *Queries) GetQuestionCooldown(ctx context.Context, arg GetQuestionCooldownParams) (int32, error) {
+	row := q.db.QueryRow(ctx, getQuestionCooldown, arg.RoomID, arg.UserID, arg.SlowModeSeconds)
+	var retry_after int32
+	err := row.Scan(&retry_after)
+	return retry_after, err
+}
+
+const getRoom = `-- name: GetRoom :one
+SELECT id, name, created_at, updated_at, user_id, description, visibility, status, opens_at, closes_at, slug, max_question_length, slow_mode_seconds, reactions_allowed, public_read, anonymous_questions FROM rooms WHERE id = $1
+`
+
+func (q *Queries) GetRoom(ctx context.Context, id int64) (Room, error) {
+	row := q.db.QueryRow(ctx, getRoom, id)
+	var i Room
 	err := row.Scan(
 		&i.ID,
-		&i.Email,
 		&i.Name,
 		&i.CreatedAt,
 		&i.UpdatedAt,
-		&i.Photo,
-		&i.EnablePicture,
-		&i.Provider,
-		&i.ProviderUserID,
-		&i.NewUser,
+		&i.UserID,
+		&i.Description,
+		&i.Visibility,
+		&i.Status,
+		&i.OpensAt,
+		&i.ClosesAt,
+		&i.Slug,
+		&i.MaxQuestionLength,
+		&i.SlowModeSeconds,
+		&i.ReactionsAllowed,
+		&i.PublicRead,
+		&i.AnonymousQuestions,
 	)
 	return i, err
 }
 
-const insertMessage = `-- name: InsertMessage :one
-INSERT INTO messages
-  ("room_id", "message") VALUES
-  ($1, $2)
-RETURNING "id", "created_at"
+const getRoomAccess = `-- name: GetRoomAccess :one
+SELECT
+  r."visibility", r."user_id", r."status", r."opens_at", r."closes_at",
+  r."max_question_length", r."slow_mode_seconds", r."reactions_allowed", r."public_read", r."anonymous_questions",
+  EXISTS (SELECT 1 FROM room_guests g WHERE g.room_id = r.id AND g.user_id = $2) AS "is_guest",
+  COALESCE(m."role", '')::text AS "role"
+FROM rooms r
+LEFT JOIN room_members m ON m.room_id = r.id AND m.user_id = $2
+WHERE r.id = $1
 `
 
-type InsertMessageParams struct {
-	RoomID  int64  `db:"room_id" json:"room_id"`
-	Message string `db:"message" json:"message"`
+type GetRoomAccessParams struct {
+	ID     int64     `db:"id" json:"id"`
+	UserID uuid.UUID `db:"user_id" json:"user_id"`
 }
 
-type InsertMessageRow struct {
-	ID        uuid.UUID        `db:"id" json:"id"`
-	CreatedAt pgtype.Timestamp `db:"created_at" json:"created_at"`
+type GetRoomAccessRow struct {
+	Visibility         string           `db:"visibility" json:"visibility"`
+	UserID             uuid.UUID        `db:"user_id" json:"user_id"`
+	Status             string           `db:"status" json:"status"`
+	OpensAt            pgtype.Timestamp `db:"opens_at" json:"opens_at"`
+	ClosesAt           pgtype.Timestamp `db:"closes_at" json:"closes_at"`
+	MaxQuestionLength  int32            `db:"max_question_length" json:"max_question_length"`
+	SlowModeSeconds    int32            `db:"slow_mode_seconds" json:"slow_mode_seconds"`
+	ReactionsAllowed   bool             `db:"reactions_allowed" json:"reactions_allowed"`
+	PublicRead         bool             `db:"public_read" json:"public_read"`
+	AnonymousQuestions bool             `db:"anonymous_questions" json:"anonymous_questions"`
+	IsGuest            bool             `db:"is_guest" json:"is_guest"`
+	Role               string           `db:"role" json:"role"`
 }
 
-func (q *Queries) InsertMessage(ctx context.Context, arg InsertMessageParams) (InsertMessageRow, error) {
-	row := q.db.QueryRow(ctx, insertMessage, arg.RoomID, arg.Message)
-	var i InsertMessageRow
-	err := row.Scan(&i.ID, &i.CreatedAt)
+func (q *Queries) GetRoomAccess(ctx context.Context, arg GetRoomAccessParams) (GetRoomAccessRow, error) {
+	row := q.db.QueryRow(ctx, getRoomAccess, arg.ID, arg.UserID)
+	var i GetRoomAccessRow
+	err := row.Scan(
+		&i.Visibility,
+		&i.UserID,
+		&i.Status,
+		&i.OpensAt,
+		&i.ClosesAt,
+		&i.MaxQuestionLength,
+		&i.SlowModeSeconds,
+		&i.ReactionsAllowed,
+		&i.PublicRead,
+		&i.AnonymousQuestions,
+		&i.IsGuest,
+		&i.Role,
+	)
 	return i, err
 }
 
-const insertMessageReaction = `-- name: InsertMessageReaction :one
-WITH mr_t AS (
-  SELECT COUNT(*) AS total_count
-  FROM messages_reactions mr
-  WHERE mr."message_id" = $1
-), inserted AS (
-  INSERT INTO messages_reactions ("message_id", "user_id")
-  VALUES ($1, $2)
-  RETURNING "message_id"  
-)
-SELECT (SELECT total_count FROM mr_t) + COUNT(inserted."message_id") AS total_reactions
-FROM inserted
+const getRoomInvite = `-- name: GetRoomInvite :one
+SELECT token, room_id, created_by, expires_at, max_uses, uses, email_domains, created_at FROM room_invites WHERE token = $1
 `
 
-type InsertMessageReactionParams struct {
-	MessageID uuid.UUID `db:"message_id" json:"message_id"`
-	UserID    uuid.UUID `db:"user_id" json:"user_id"`
+func (q *Queries) GetRoomInvite(ctx context.Context, token string) (RoomInvite, error) {
+	row := q.db.QueryRow(ctx, getRoomInvite, token)
+	var i RoomInvite
+	err := row.Scan(
+		&i.Token,
+		&i.RoomID,
+		&i.CreatedBy,
+		&i.ExpiresAt,
+		&i.MaxUses,
+		&i.Uses,
+		&i.EmailDomains,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getRoomInvites = `-- name: GetRoomInvites :many
+SELECT token, room_id, created_by, expires_at, max_uses, uses, email_domains, created_at FROM room_invites
+WHERE room_id = $1
+ORDER BY created_at DESC
+`
+
+func (q *Queries) GetRoomInvites(ctx context.Context, roomID int64) ([]RoomInvite, error) {
+	rows, err := q.db.Query(ctx, getRoomInvites, roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []RoomInvite
+	for rows.Next() {
+		var i RoomInvite
+		if err := rows.Scan(
+			&i.Token,
+			&i.RoomID,
+			&i.CreatedBy,
+			&i.ExpiresAt,
+			&i.MaxUses,
+			&i.Uses,
+			&i.EmailDomains,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRoomMembers = `-- name: GetRoomMembers :many
+SELECT m."user_id", u."name", CASE WHEN u.enable_picture THEN u.photo END AS "photo", m."role", m."created_at"
+FROM room_members m
+JOIN users u ON u.id = m.user_id
+WHERE m.room_id = $1
+ORDER BY m.created_at, u."name"
+`
+
+type GetRoomMembersRow struct {
+	UserID    uuid.UUID        `db:"user_id" json:"user_id"`
+	Name      string           `db:"name" json:"name"`
+	Photo     pgtype.Text      `db:"photo" json:"photo"`
+	Role      string           `db:"role" json:"role"`
+	CreatedAt pgtype.Timestamp `db:"created_at" json:"created_at"`
+}
+
+func (q *Queries) GetRoomMembers(ctx context.Context, roomID int64) ([]GetRoomMembersRow, error) {
+	rows, err := q.db.Query(ctx, getRoomMembers, roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetRoomMembersRow
+	for rows.Next() {
+		var i GetRoomMembersRow
+		if err := rows.Scan(
+			&i.UserID,
+			&i.Name,
+			&i.Photo,
+			&i.Role,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRoomMessages = `-- name: GetRoomMessages :many
+SELECT
+  m."id", m."room_id", m."message", m."answered", m."created_at", m."updated_at", m."answer", m."hidden", m."anonymous",
+  COUNT(mr.message_id) AS "reaction_count",
+  u."id" AS "author_id", u."name" AS "author_name", CASE WHEN u.enable_picture THEN u.photo END AS "author_photo"
+FROM messages m
+LEFT JOIN messages_reactions mr ON mr.message_id = m.id
+LEFT JOIN users u ON u.id = m.user_id AND (NOT m.anonymous OR m.user_id = $2 OR $3::boolean)
+WHERE m.room_id = $1 AND (NOT m.hidden OR $4::boolean)
+GROUP BY m.id, u.id ORDER BY m.created_at DESC
+`
+
+type GetRoomMessagesParams struct {
+	RoomID          int64       `db:"room_id" json:"room_id"`
+	ViewerID        pgtype.UUID `db:"viewer_id" json:"viewer_id"`
+	RevealAnonymous bool        `db:"reveal_anonymous" json:"reveal_anonymous"`
+	IncludeHidden   bool        `db:"include_hidden" json:"include_hidden"`
+}
+
+type GetRoomMessagesRow struct {
+	ID            uuid.UUID        `db:"id" json:"id"`
+	RoomID        int64            `db:"room_id" json:"room_id"`
+	Message       string           `db:"message" json:"message"`
+	Answered      bool             `db:"answered" json:"answered"`
+	CreatedAt     pgtype.Timestamp `db:"created_at" json:"created_at"`
+	UpdatedAt     pgtype.Timestamp `db:"updated_at" json:"updated_at"`
+	Answer        string           `db:"answer" json:"answer"`
+	Hidden        bool             `db:"hidden" json:"hidden"`
+	Anonymous     bool             `db:"anonymous" json:"anonymous"`
+	ReactionCount int64            `db:"reaction_count" json:"reaction_count"`
+	AuthorID      pgtype.UUID      `db:"author_id" json:"author_id"`
+	AuthorName    pgtype.Text      `db:"author_name" json:"author_name"`
+	AuthorPhoto   pgtype.Text      `db:"author_photo" json:"author_photo"`
+}
+
+func (q *Queries) GetRoomMessages(ctx context.Context, arg GetRoomMessagesParams) ([]GetRoomMessagesRow, error) {
+	rows, err := q.db.Query(ctx, getRoomMessages,
+		arg.RoomID,
+		arg.ViewerID,
+		arg.RevealAnonymous,
+		arg.IncludeHidden,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetRoomMessagesRow
+	for rows.Next() {
+		var i GetRoomMessagesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.RoomID,
+			&i.Message,
+			&i.Answered,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Answer,
+			&i.Hidden,
+			&i.Anonymous,
+			&i.ReactionCount,
+			&i.AuthorID,
+			&i.AuthorName,
+			&i.AuthorPhoto,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRoomMessagesReactions = `-- name: GetRoomMessagesReactions :many
+SELECT mr.message_id FROM messages_reactions mr 
+LEFT JOIN messages m ON m.id = mr.message_id 
+WHERE m.room_id = $1 AND mr.user_id = $2
+`
+
+type GetRoomMessagesReactionsParams struct {
+	RoomID int64     `db:"room_id" json:"room_id"`
+	UserID uuid.UUID `db:"user_id" json:"user_id"`
+}
+
+func (q *Queries) GetRoomMessagesReactions(ctx context.Context, arg GetRoomMessagesReactionsParams) ([]uuid.UUID, error) {
+	rows, err := q.db.Query(ctx, getRoomMessagesReactions, arg.RoomID, arg.UserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var message_id uuid.UUID
+		if err := rows.Scan(&message_id); err != nil {
+			return nil, err
+		}
+		items = append(items, message_id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRoomQuestionsPerMinute = `-- name: GetRoomQuestionsPerMinute :many
+SELECT DATE_TRUNC('minute', m.created_at)::timestamp AS "minute", COUNT(*) AS "questions"
+FROM messages m
+WHERE m.room_id = $1
+GROUP BY 1
+ORDER BY 1
+`
+
+type GetRoomQuestionsPerMinuteRow struct {
+	Minute    pgtype.Timestamp `db:"minute" json:"minute"`
+	Questions int64            `db:"questions" json:"questions"`
+}
+
+func (q *Queries) GetRoomQuestionsPerMinute(ctx context.Context, roomID int64) ([]GetRoomQuestionsPerMinuteRow, error) {
+	rows, err := q.db.Query(ctx, getRoomQuestionsPerMinute, roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetRoomQuestionsPerMinuteRow
+	for rows.Next() {
+		var i GetRoomQuestionsPerMinuteRow
+		if err := rows.Scan(&i.Minute, &i.Questions); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRoomStats = `-- name: GetRoomStats :one
+SELECT
+  COUNT(*) AS "total_questions",
+  COUNT(*) FILTER (WHERE m.answered) AS "answered_questions",
+  COUNT(*) FILTER (WHERE NOT m.answered) AS "unanswered_questions",
+  COALESCE(
+    PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM m.answered_at - m.created_at)) FILTER (WHERE m.answered_at IS NOT NULL),
+    0
+  )::float8 AS "median_seconds_to_answer",
+  COUNT(DISTINCT m.user_id) AS "unique_askers",
+  (
+    SELECT COUNT(*) FROM messages_reactions mr
+    JOIN messages rm ON rm.id = mr.message_id
+    WHERE rm.room_id = $1
+  ) AS "total_reactions",
+  (
+    SELECT COUNT(DISTINCT mr.user_id) FROM messages_reactions mr
+    JOIN messages rm ON rm.id = mr.message_id
+    WHERE rm.room_id = $1
+  ) AS "unique_reactors"
+FROM messages m
+WHERE m.room_id = $1
+`
+
+type GetRoomStatsRow struct {
+	TotalQuestions        int64   `db:"total_questions" json:"total_questions"`
+	AnsweredQuestions     int64   `db:"answered_questions" json:"answered_questions"`
+	UnansweredQuestions   int64   `db:"unanswered_questions" json:"unanswered_questions"`
+	MedianSecondsToAnswer float64 `db:"median_seconds_to_answer" json:"median_seconds_to_answer"`
+	UniqueAskers          int64   `db:"unique_askers" json:"unique_askers"`
+	TotalReactions        int64   `db:"total_reactions" json:"total_reactions"`
+	UniqueReactors        int64   `db:"unique_reactors" json:"unique_reactors"`
+}
+
+func (q *Queries) GetRoomStats(ctx context.Context, roomID int64) (GetRoomStatsRow, error) {
+	row := q.db.QueryRow(ctx, getRoomStats, roomID)
+	var i GetRoomStatsRow
+	err := row.Scan(
+		&i.TotalQuestions,
+		&i.AnsweredQuestions,
+		&i.UnansweredQuestions,
+		&i.MedianSecondsToAnswer,
+		&i.UniqueAskers,
+		&i.TotalReactions,
+		&i.UniqueReactors,
+	)
+	return i, err
+}
+
+const getRoomTopReactedMessages = `-- name: GetRoomTopReactedMessages :many
+SELECT m."id", m."message", COUNT(mr.message_id) AS "reaction_count"
+FROM messages m
+JOIN messages_reactions mr ON mr.message_id = m.id
+WHERE m.room_id = $1
+GROUP BY m.id
+ORDER BY "reaction_count" DESC, m.created_at
+LIMIT $2
+`
+
+type GetRoomTopReactedMessagesParams struct {
+	RoomID int64 `db:"room_id" json:"room_id"`
+	Limit  int32 `db:"limit" json:"limit"`
+}
+
+type GetRoomTopReactedMessagesRow struct {
+	ID            uuid.UUID `db:"id" json:"id"`
+	Message       string    `db:"message" json:"message"`
+	ReactionCount int64     `db:"reaction_count" json:"reaction_count"`
+}
+
+func (q *Queries) GetRoomTopReactedMessages(ctx context.Context, arg GetRoomTopReactedMessagesParams) ([]GetRoomTopReactedMessagesRow, error) {
+	rows, err := q.db.Query(ctx, getRoomTopReactedMessages, arg.RoomID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetRoomTopReactedMessagesRow
+	for rows.Next() {
+		var i GetRoomTopReactedMessagesRow
+		if err := rows.Scan(&i.ID, &i.Message, &i.ReactionCount); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRoomWithUser = `-- name: GetRoomWithUser :one
+SELECT
+  r."id", r."name", r."description", r."created_at", r."updated_at", u."name" as "creator_name", u."id" as "user_id", CASE WHEN u.enable_picture THEN u.photo END AS "photo", u."enable_picture", r."visibility", r."status", r."opens_at", r."closes_at", r."slug",
+  r."max_question_length", r."slow_mode_seconds", r."reactions_allowed", r."public_read", r."anonymous_questions",
+  p."message_id" AS "pinned_message_id"
+FROM rooms r
+LEFT JOIN users u ON r.user_id = u.id
+LEFT JOIN room_pins p ON p.room_id = r.id
+WHERE r.id = $1
+`
+
+type GetRoomWithUserRow struct {
+	ID                 int64            `db:"id" json:"id"`
+	Name               string           `db:"name" json:"name"`
+	Description        string           `db:"description" json:"description"`
+	CreatedAt          pgtype.Timestamp `db:"created_at" json:"created_at"`
+	UpdatedAt          pgtype.Timestamp `db:"updated_at" json:"updated_at"`
+	CreatorName        pgtype.Text      `db:"creator_name" json:"creator_name"`
+	UserID             pgtype.UUID      `db:"user_id" json:"user_id"`
+	Photo              pgtype.Text      `db:"photo" json:"photo"`
+	EnablePicture      pgtype.Bool      `db:"enable_picture" json:"enable_picture"`
+	Visibility         string           `db:"visibility" json:"visibility"`
+	Status             string           `db:"status" json:"status"`
+	OpensAt            pgtype.Timestamp `db:"opens_at" json:"opens_at"`
+	ClosesAt           pgtype.Timestamp `db:"closes_at" json:"closes_at"`
+	Slug               string           `db:"slug" json:"slug"`
+	MaxQuestionLength  int32            `db:"max_question_length" json:"max_question_length"`
+	SlowModeSeconds    int32            `db:"slow_mode_seconds" json:"slow_mode_seconds"`
+	ReactionsAllowed   bool             `db:"reactions_allowed" json:"reactions_allowed"`
+	PublicRead         bool             `db:"public_read" json:"public_read"`
+	AnonymousQuestions bool             `db:"anonymous_questions" json:"anonymous_questions"`
+	PinnedMessageID    pgtype.UUID      `db:"pinned_message_id" json:"pinned_message_id"`
+}
+
+func (q *Queries) GetRoomWithUser(ctx context.Context, id int64) (GetRoomWithUserRow, error) {
+	row := q.db.QueryRow(ctx, getRoomWithUser, id)
+	var i GetRoomWithUserRow
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Description,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.CreatorName,
+		&i.UserID,
+		&i.Photo,
+		&i.EnablePicture,
+		&i.Visibility,
+		&i.Status,
+		&i.OpensAt,
+		&i.ClosesAt,
+		&i.Slug,
+		&i.MaxQuestionLength,
+		&i.SlowModeSeconds,
+		&i.ReactionsAllowed,
+		&i.PublicRead,
+		&i.AnonymousQuestions,
+		&i.PinnedMessageID,
+	)
+	return i, err
+}
+
+const getRooms = `-- name: GetRooms :many
+WITH page AS (
+  -- Rooms sorted by creation are paged here, before their messages are
+  -- counted; the other sorts need the counts of every room to be paged
+  SELECT r.id, r.name, r.created_at, r.updated_at, r.user_id, r.description, r.visibility, r.status, r.opens_at, r.closes_at, r.slug, r.max_question_length, r.slow_mode_seconds, r.reactions_allowed, r.public_read, r.anonymous_questions
+  FROM rooms r
+  WHERE
+    ($1::text IS NULL OR r.name ILIKE '%' || $1 || '%' OR r.description ILIKE '%' || $1 || '%')
+    AND ($2::uuid IS NULL OR r.user_id = $2)
+    AND ($3::timestamp IS NULL OR r.created_at > $3)
+    AND ($4::timestamp IS NULL OR r.created_at < $4)
+    AND (
+      r.user_id = $5
+      OR EXISTS (SELECT 1 FROM room_members rm WHERE rm.room_id = r.id AND rm.user_id = $5)
+      -- Drafts are only listed to their hosts
+      OR (r.status <> 'draft' AND (
+        r.visibility = 'public'
+        OR EXISTS (SELECT 1 FROM room_guests g WHERE g.room_id = r.id AND g.user_id = $5)
+      ))
+    )
+    AND (
+      $6::bigint IS NULL
+      OR CASE $7::text
+        WHEN 'newest' THEN (r.created_at, r.id) < ($8::timestamp, $6)
+        WHEN 'oldest' THEN (r.created_at, r.id) > ($8, $6)
+        ELSE true
+      END
+    )
+  ORDER BY
+    CASE WHEN $7 = 'newest' THEN r.created_at END DESC,
+    CASE WHEN $7 = 'oldest' THEN r.created_at END ASC,
+    CASE WHEN $7 = 'oldest' THEN r.id END ASC,
+    r.id DESC
+  LIMIT CASE WHEN $7 IN ('newest', 'oldest') THEN $9::int END
+)
+SELECT
+  page.id, page.name, page.created_at, page.updated_at, page.user_id, page.description, page.visibility, page.status, page.opens_at, page.closes_at, page.slug, page.max_question_length, page.slow_mode_seconds, page.reactions_allowed, page.public_read, page.anonymous_questions,
+  u."name" AS "creator_name",
+  activity.message_count,
+  activity.last_activity_at
+FROM page
+LEFT JOIN users u ON page.user_id = u.id
+CROSS JOIN LATERAL (
+  SELECT
+    COUNT(m.id) AS "message_count",
+    GREATEST(page.updated_at, MAX(m.updated_at))::timestamp AS "last_activity_at"
+  FROM messages m
+  WHERE m.room_id = page.id
+) activity
+WHERE
+  $6 IS NULL
+  OR CASE $7
+    WHEN 'most_questions' THEN (activity.message_count, page.id) < ($10::bigint, $6)
+    WHEN 'recent_activity' THEN (activity.last_activity_at, page.id) < ($8, $6)
+    ELSE true
+  END
+ORDER BY
+  CASE WHEN $7 = 'newest' THEN page.created_at END DESC,
+  CASE WHEN $7 = 'oldest' THEN page.created_at END ASC,
+  CASE WHEN $7 = 'most_questions' THEN activity.message_count END DESC,
+  CASE WHEN $7 = 'recent_activity' THEN activity.last_activity_at END DESC,
+  CASE WHEN $7 = 'oldest' THEN page.id END ASC,
+  page.id DESC
+LIMIT $9
+`
+
+type GetRoomsParams struct {
+	Search        pgtype.Text      `db:"search" json:"search"`
+	CreatorID     pgtype.UUID      `db:"creator_id" json:"creator_id"`
+	CreatedAfter  pgtype.Timestamp `db:"created_after" json:"created_after"`
+	CreatedBefore pgtype.Timestamp `db:"created_before" json:"created_before"`
+	ViewerID      uuid.UUID        `db:"viewer_id" json:"viewer_id"`
+	CursorID      pgtype.Int8      `db:"cursor_id" json:"cursor_id"`
+	Sort          string           `db:"sort" json:"sort"`
+	CursorTime    pgtype.Timestamp `db:"cursor_time" json:"cursor_time"`
+	PageSize      int32            `db:"page_size" json:"page_size"`
+	CursorCount   pgtype.Int8      `db:"cursor_count" json:"cursor_count"`
+}
+
+type GetRoomsRow struct {
+	ID                 int64            `db:"id" json:"id"`
+	Name               string           `db:"name" json:"name"`
+	CreatedAt          pgtype.Timestamp `db:"created_at" json:"created_at"`
+	UpdatedAt          pgtype.Timestamp `db:"updated_at" json:"updated_at"`
+	UserID             uuid.UUID        `db:"user_id" json:"user_id"`
+	Description        string           `db:"description" json:"description"`
+	Visibility         string           `db:"visibility" json:"visibility"`
+	Status             string           `db:"status" json:"status"`
+	OpensAt            pgtype.Timestamp `db:"opens_at" json:"opens_at"`
+	ClosesAt           pgtype.Timestamp `db:"closes_at" json:"closes_at"`
+	Slug               string           `db:"slug" json:"slug"`
+	MaxQuestionLength  int32            `db:"max_question_length" json:"max_question_length"`
+	SlowModeSeconds    int32            `db:"slow_mode_seconds" json:"slow_mode_seconds"`
+	ReactionsAllowed   bool             `db:"reactions_allowed" json:"reactions_allowed"`
+	PublicRead         bool             `db:"public_read" json:"public_read"`
+	AnonymousQuestions bool             `db:"anonymous_questions" json:"anonymous_questions"`
+	CreatorName        pgtype.Text      `db:"creator_name" json:"creator_name"`
+	MessageCount       int64            `db:"message_count" json:"message_count"`
+	LastActivityAt     pgtype.Timestamp `db:"last_activity_at" json:"last_activity_at"`
+}
+
+func (q *Queries) GetRooms(ctx context.Context, arg GetRoomsParams) ([]GetRoomsRow, error) {
+	rows, err := q.db.Query(ctx, getRooms,
+		arg.Search,
+		arg.CreatorID,
+		arg.CreatedAfter,
+		arg.CreatedBefore,
+		arg.ViewerID,
+		arg.CursorID,
+		arg.Sort,
+		arg.CursorTime,
+		arg.PageSize,
+		arg.CursorCount,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetRoomsRow
+	for rows.Next() {
+		var i GetRoomsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.Description,
+			&i.Visibility,
+			&i.Status,
+			&i.OpensAt,
+			&i.ClosesAt,
+			&i.Slug,
+			&i.MaxQuestionLength,
+			&i.SlowModeSeconds,
+			&i.ReactionsAllowed,
+			&i.PublicRead,
+			&i.AnonymousQuestions,
+			&i.CreatorName,
+			&i.MessageCount,
+			&i.LastActivityAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getTakenRoomSlugs = `-- name: GetTakenRoomSlugs :many
+SELECT slug FROM rooms
+WHERE (slug = $1 OR slug LIKE $1 || '-%') AND id <> $2
+UNION
+SELECT slug FROM room_slug_redirects
+WHERE (slug = $1 OR slug LIKE $1 || '-%') AND room_id <> $2
+`
+
+type GetTakenRoomSlugsParams struct {
+	Slug   string `db:"slug" json:"slug"`
+	RoomID int64  `db:"room_id" json:"room_id"`
+}
+
+func (q *Queries) GetTakenRoomSlugs(ctx context.Context, arg GetTakenRoomSlugsParams) ([]string, error) {
+	rows, err := q.db.Query(ctx, getTakenRoomSlugs, arg.Slug, arg.RoomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var slug string
+		if err := rows.Scan(&slug); err != nil {
+			return nil, err
+		}
+		items = append(items, slug)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUnreadNotifications = `-- name: GetUnreadNotifications :many
+SELECT n."id", n."kind", n."room_id", r."name" AS "room_name", r."slug" AS "room_slug", n."message_id", m."message", n."created_at"
+FROM notifications n
+JOIN rooms r ON r.id = n.room_id
+LEFT JOIN messages m ON m.id = n.message_id
+WHERE n.user_id = $1 AND n.read_at IS NULL
+ORDER BY n.created_at DESC, n.id
+LIMIT 100
+`
+
+type GetUnreadNotificationsRow struct {
+	ID        uuid.UUID        `db:"id" json:"id"`
+	Kind      string           `db:"kind" json:"kind"`
+	RoomID    int64            `db:"room_id" json:"room_id"`
+	RoomName  string           `db:"room_name" json:"room_name"`
+	RoomSlug  string           `db:"room_slug" json:"room_slug"`
+	MessageID pgtype.UUID      `db:"message_id" json:"message_id"`
+	Message   pgtype.Text      `db:"message" json:"message"`
+	CreatedAt pgtype.Timestamp `db:"created_at" json:"created_at"`
+}
+
+func (q *Queries) GetUnreadNotifications(ctx context.Context, userID uuid.UUID) ([]GetUnreadNotificationsRow, error) {
+	rows, err := q.db.Query(ctx, getUnreadNotifications, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUnreadNotificationsRow
+	for rows.Next() {
+		var i GetUnreadNotificationsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Kind,
+			&i.RoomID,
+			&i.RoomName,
+			&i.RoomSlug,
+			&i.MessageID,
+			&i.Message,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUserByEmail = `-- name: GetUserByEmail :one
+SELECT id, email, name, created_at, updated_at, photo, enable_picture, provider, provider_user_id, new_user FROM users WHERE email = $1 LIMIT 1
+`
+
+func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
+	row := q.db.QueryRow(ctx, getUserByEmail, email)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.Name,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Photo,
+		&i.EnablePicture,
+		&i.Provider,
+		&i.ProviderUserID,
+		&i.NewUser,
+	)
+	return i, err
+}
+
+const getUserById = `-- name: GetUserById :one
+SELECT id, email, name, created_at, updated_at, photo, enable_picture, provider, provider_user_id, new_user FROM users WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetUserById(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRow(ctx, getUserById, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.Name,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Photo,
+		&i.EnablePicture,
+		&i.Provider,
+		&i.ProviderUserID,
+		&i.NewUser,
+	)
+	return i, err
+}
+
+const importMessage = `-- name: ImportMessage :execrows
+INSERT INTO messages
+  ("room_id", "message", "answer", "answered", "created_at", "updated_at", "answered_at")
+SELECT $1::bigint, $2::text, $3::text, $4::boolean, $5::timestamp, $6::timestamp,
+  CASE WHEN $4 THEN $6 END
+WHERE NOT EXISTS (
+  SELECT 1 FROM messages m WHERE m.room_id = $1 AND m.message = $2
+)
+`
+
+type ImportMessageParams struct {
+	RoomID    int64            `db:"room_id" json:"room_id"`
+	Message   string           `db:"message" json:"message"`
+	Answer    string           `db:"answer" json:"answer"`
+	Answered  bool             `db:"answered" json:"answered"`
+	CreatedAt pgtype.Timestamp `db:"created_at" json:"created_at"`
+	UpdatedAt pgtype.Timestamp `db:"updated_at" json:"updated_at"`
+}
+
+func (q *Queries) ImportMessage(ctx context.Context, arg ImportMessageParams) (int64, error) {
+	result, err := q.db.Exec(ctx, importMessage,
+		arg.RoomID,
+		arg.Message,
+		arg.Answer,
+		arg.Answered,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const insertMessage = `-- name: InsertMessage :one
+INSERT INTO messages
+  ("room_id", "message", "user_id", "anonymous") VALUES
+  ($1, $2, $3, $4)
+RETURNING "id", "created_at"
+`
+
+type InsertMessageParams struct {
+	RoomID    int64       `db:"room_id" json:"room_id"`
+	Message   string      `db:"message" json:"message"`
+	UserID    pgtype.UUID `db:"user_id" json:"user_id"`
+	Anonymous bool        `db:"anonymous" json:"anonymous"`
+}
+
+type InsertMessageRow struct {
+	ID        uuid.UUID        `db:"id" json:"id"`
+	CreatedAt pgtype.Timestamp `db:"created_at" json:"created_at"`
+}
+
+func (q *Queries) InsertMessage(ctx context.Context, arg InsertMessageParams) (InsertMessageRow, error) {
+	row := q.db.QueryRow(ctx, insertMessage,
+		arg.RoomID,
+		arg.Message,
+		arg.UserID,
+		arg.Anonymous,
+	)
+	var i InsertMessageRow
+	err := row.Scan(&i.ID, &i.CreatedAt)
+	return i, err
+}
+
+const insertMessageAnsweredNotifications = `-- name: InsertMessageAnsweredNotifications :execrows
+INSERT INTO notifications ("user_id", "room_id", "message_id", "kind")
+SELECT f.user_id, m.room_id, m.id, 'message_answered'
+FROM messages m
+JOIN rooms r ON r.id = m.room_id
+JOIN room_follows f ON f.room_id = m.room_id
+WHERE m.id = $1
+  AND f.user_id <> r.user_id
+  AND (
+    m.user_id = f.user_id
+    OR EXISTS (SELECT 1 FROM messages_reactions mr WHERE mr.message_id = m.id AND mr.user_id = f.user_id)
+  )
+  AND (
+    r.visibility = 'public'
+    OR r.user_id = f.user_id
+    OR EXISTS (SELECT 1 FROM room_guests g WHERE g.room_id = r.id AND g.user_id = f.user_id)
+    OR EXISTS (SELECT 1 FROM room_members rm WHERE rm.room_id = r.id AND rm.user_id = f.user_id)
+  )
+`
+
+func (q *Queries) InsertMessageAnsweredNotifications(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, insertMessageAnsweredNotifications, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const insertMessageReaction = `-- name: InsertMessageReaction :one
+WITH mr_t AS (
+  SELECT COUNT(*) AS total_count
+  FROM messages_reactions mr
+  WHERE mr."message_id" = $1
+), inserted AS (
+  INSERT INTO messages_reactions ("message_id", "user_id")
+  VALUES ($1, $2)
+  RETURNING "message_id"  
+)
+SELECT (SELECT total_count FROM mr_t) + COUNT(inserted."message_id") AS total_reactions
+FROM inserted
+`
+
+type InsertMessageReactionParams struct {
+	MessageID uuid.UUID `db:"message_id" json:"message_id"`
+	UserID    uuid.UUID `db:"user_id" json:"user_id"`
 }
 
 func (q *Queries) InsertMessageReaction(ctx context.Context, arg InsertMessageReactionParams) (int32, error) {
@@ -373,15 +1411,20 @@ func (q *Queries) InsertMessageReaction(ctx context.Context, arg InsertMessageRe
 
 const insertRoom = `-- name: InsertRoom :one
 INSERT INTO rooms
-  ("name", "user_id", "description") VALUES
-  ($1, $2, $3)
+  ("name", "user_id", "description", "visibility", "status", "opens_at", "closes_at", "slug") VALUES
+  ($1, $2, $3, $4, $5, $6, $7, $8)
 RETURNING "id", "created_at"
 `
 
 type InsertRoomParams struct {
-	Name        string    `db:"name" json:"name"`
-	UserID      uuid.UUID `db:"user_id" json:"user_id"`
-	Description string    `db:"description" json:"description"`
+	Name        string           `db:"name" json:"name"`
+	UserID      uuid.UUID        `db:"user_id" json:"user_id"`
+	Description string           `db:"description" json:"description"`
+	Visibility  string           `db:"visibility" json:"visibility"`
+	Status      string           `db:"status" json:"status"`
+	OpensAt     pgtype.Timestamp `db:"opens_at" json:"opens_at"`
+	ClosesAt    pgtype.Timestamp `db:"closes_at" json:"closes_at"`
+	Slug        string           `db:"slug" json:"slug"`
 }
 
 type InsertRoomRow struct {
@@ -390,12 +1433,200 @@ type InsertRoomRow struct {
 }
 
 func (q *Queries) InsertRoom(ctx context.Context, arg InsertRoomParams) (InsertRoomRow, error) {
-	row := q.db.QueryRow(ctx, insertRoom, arg.Name, arg.UserID, arg.Description)
+	row := q.db.QueryRow(ctx, insertRoom,
+		arg.Name,
+		arg.UserID,
+		arg.Description,
+		arg.Visibility,
+		arg.Status,
+		arg.OpensAt,
+		arg.ClosesAt,
+		arg.Slug,
+	)
 	var i InsertRoomRow
 	err := row.Scan(&i.ID, &i.CreatedAt)
 	return i, err
 }
 
+const insertRoomInvite = `-- name: InsertRoomInvite :one
+INSERT INTO room_invites
+  ("token", "room_id", "created_by", "expires_at", "max_uses", "email_domains") VALUES
+  ($1, $2, $3, $4, $5, $6)
+RETURNING token, room_id, created_by, expires_at, max_uses, uses, email_domains, created_at
+`
+
+type InsertRoomInviteParams struct {
+	Token        string           `db:"token" json:"token"`
+	RoomID       int64            `db:"room_id" json:"room_id"`
+	CreatedBy    uuid.UUID        `db:"created_by" json:"created_by"`
+	ExpiresAt    pgtype.Timestamp `db:"expires_at" json:"expires_at"`
+	MaxUses      pgtype.Int4      `db:"max_uses" json:"max_uses"`
+	EmailDomains []string         `db:"email_domains" json:"email_domains"`
+}
+
+func (q *Queries) InsertRoomInvite(ctx context.Context, arg InsertRoomInviteParams) (RoomInvite, error) {
+	row := q.db.QueryRow(ctx, insertRoomInvite,
+		arg.Token,
+		arg.RoomID,
+		arg.CreatedBy,
+		arg.ExpiresAt,
+		arg.MaxUses,
+		arg.EmailDomains,
+	)
+	var i RoomInvite
+	err := row.Scan(
+		&i.Token,
+		&i.RoomID,
+		&i.CreatedBy,
+		&i.ExpiresAt,
+		&i.MaxUses,
+		&i.Uses,
+		&i.EmailDomains,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const insertRoomOpenedNotifications = `-- name: InsertRoomOpenedNotifications :execrows
+INSERT INTO notifications ("user_id", "room_id", "kind")
+SELECT f.user_id, f.room_id, 'room_opened'
+FROM room_follows f
+JOIN rooms r ON r.id = f.room_id
+WHERE f.room_id = $1
+  AND f.user_id <> r.user_id
+  AND (
+    r.visibility = 'public'
+    OR r.user_id = f.user_id
+    OR EXISTS (SELECT 1 FROM room_guests g WHERE g.room_id = r.id AND g.user_id = f.user_id)
+    OR EXISTS (SELECT 1 FROM room_members rm WHERE rm.room_id = r.id AND rm.user_id = f.user_id)
+  )
+`
+
+func (q *Queries) InsertRoomOpenedNotifications(ctx context.Context, roomID int64) (int64, error) {
+	result, err := q.db.Exec(ctx, insertRoomOpenedNotifications, roomID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const markNotificationsRead = `-- name: MarkNotificationsRead :execrows
+UPDATE notifications
+SET read_at = now()
+WHERE user_id = $1 AND read_at IS NULL
+`
+
+func (q *Queries) MarkNotificationsRead(ctx context.Context, userID uuid.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, markNotificationsRead, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const openDueRooms = `-- name: OpenDueRooms :many
+UPDATE rooms
+SET status = 'open', updated_at = now()
+WHERE status = 'scheduled' AND opens_at <= now()
+RETURNING id, name, created_at, updated_at, user_id, description, visibility, status, opens_at, closes_at, slug, max_question_length, slow_mode_seconds, reactions_allowed, public_read, anonymous_questions
+`
+
+func (q *Queries) OpenDueRooms(ctx context.Context) ([]Room, error) {
+	rows, err := q.db.Query(ctx, openDueRooms)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Room
+	for rows.Next() {
+		var i Room
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.Description,
+			&i.Visibility,
+			&i.Status,
+			&i.OpensAt,
+			&i.ClosesAt,
+			&i.Slug,
+			&i.MaxQuestionLength,
+			&i.SlowModeSeconds,
+			&i.ReactionsAllowed,
+			&i.PublicRead,
+			&i.AnonymousQuestions,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const pinMessage = `-- name: PinMessage :one
+INSERT INTO room_pins ("room_id", "message_id", "pinned_by")
+SELECT m."room_id", m."id", $1::uuid FROM messages m
+WHERE m.id = $2 AND NOT m.hidden
+ON CONFLICT ("room_id") DO UPDATE
+SET message_id = EXCLUDED.message_id, pinned_by = EXCLUDED.pinned_by, pinned_at = now()
+RETURNING room_id, message_id, pinned_by, pinned_at
+`
+
+type PinMessageParams struct {
+	PinnedBy  uuid.UUID `db:"pinned_by" json:"pinned_by"`
+	MessageID uuid.UUID `db:"message_id" json:"message_id"`
+}
+
+func (q *Queries) PinMessage(ctx context.Context, arg PinMessageParams) (RoomPin, error) {
+	row := q.db.QueryRow(ctx, pinMessage, arg.PinnedBy, arg.MessageID)
+	var i RoomPin
+	err := row.Scan(
+		&i.RoomID,
+		&i.MessageID,
+		&i.PinnedBy,
+		&i.PinnedAt,
+	)
+	return i, err
+}
+
+const redeemRoomInvite = `-- name: RedeemRoomInvite :one
+WITH invite AS (
+  SELECT room_id FROM room_invites
+  WHERE
+    token = $1
+    AND (expires_at IS NULL OR expires_at > now())
+    AND (max_uses IS NULL OR uses < max_uses)
+  FOR UPDATE
+), joined AS (
+  INSERT INTO room_guests ("room_id", "user_id")
+  SELECT room_id, $2 FROM invite
+  ON CONFLICT DO NOTHING
+  RETURNING room_id
+)
+UPDATE room_invites
+SET uses = uses + 1
+FROM joined
+WHERE room_invites.token = $1
+RETURNING joined.room_id
+`
+
+type RedeemRoomInviteParams struct {
+	Token  string    `db:"token" json:"token"`
+	UserID uuid.UUID `db:"user_id" json:"user_id"`
+}
+
+func (q *Queries) RedeemRoomInvite(ctx context.Context, arg RedeemRoomInviteParams) (int64, error) {
+	row := q.db.QueryRow(ctx, redeemRoomInvite, arg.Token, arg.UserID)
+	var room_id int64
+	err := row.Scan(&room_id)
+	return room_id, err
+}
+
 const removeMessageReaction = `-- name: RemoveMessageReaction :one
 WITH mr_t AS (
   SELECT COUNT(*) AS total_count
@@ -422,6 +1653,294 @@ func (q *Queries) RemoveMessageReaction(ctx context.Context, arg RemoveMessageRe
 	return total_reactions, err
 }
 
+const resolveRoomSlug = `-- name: ResolveRoomSlug :one
+SELECT r."id", r."slug" FROM rooms r
+WHERE r.slug = $1 OR r.id = (SELECT rr.room_id FROM room_slug_redirects rr WHERE rr.slug = $1)
+ORDER BY r.slug = $1 DESC
+LIMIT 1
+`
+
+type ResolveRoomSlugRow struct {
+	ID   int64  `db:"id" json:"id"`
+	Slug string `db:"slug" json:"slug"`
+}
+
+func (q *Queries) ResolveRoomSlug(ctx context.Context, slug string) (ResolveRoomSlugRow, error) {
+	row := q.db.QueryRow(ctx, resolveRoomSlug, slug)
+	var i ResolveRoomSlugRow
+	err := row.Scan(&i.ID, &i.Slug)
+	return i, err
+}
+
+const setMessageHidden = `-- name: SetMessageHidden :one
+UPDATE messages
+SET
+  hidden = $1,
+  updated_at = now()
+WHERE
+  id = $2
+RETURNING hidden
+`
+
+type SetMessageHiddenParams struct {
+	Hidden bool      `db:"hidden" json:"hidden"`
+	ID     uuid.UUID `db:"id" json:"id"`
+}
+
+func (q *Queries) SetMessageHidden(ctx context.Context, arg SetMessageHiddenParams) (bool, error) {
+	row := q.db.QueryRow(ctx, setMessageHidden, arg.Hidden, arg.ID)
+	var hidden bool
+	err := row.Scan(&hidden)
+	return hidden, err
+}
+
+const startQuestionCooldown = `-- name: StartQuestionCooldown :exec
+INSERT INTO room_question_cooldowns ("room_id", "user_id") VALUES ($1, $2)
+ON CONFLICT ("room_id", "user_id") DO UPDATE SET asked_at = now()
+`
+
+type StartQuestionCooldownParams struct {
+	RoomID int64     `db:"room_id" json:"room_id"`
+	UserID uuid.UUID `db:"user_id" json:"user_id"`
+}
+
+func (q *Queries) StartQuestionCooldown(ctx context.Context, arg StartQuestionCooldownParams) error {
+	_, err := q.db.Exec(ctx, startQuestionCooldown, arg.RoomID, arg.UserID)
+	return err
+}
+
+const unfollowRoom = `-- name: UnfollowRoom :exec
+DELETE FROM room_follows WHERE room_id = $1 AND user_id = $2
+`
+
+type UnfollowRoomParams struct {
+	RoomID int64     `db:"room_id" json:"room_id"`
+	UserID uuid.UUID `db:"user_id" json:"user_id"`
+}
+
+func (q *Queries) UnfollowRoom(ctx context.Context, arg UnfollowRoomParams) error {
+	_, err := q.db.Exec(ctx, unfollowRoom, arg.RoomID, arg.UserID)
+	return err
+}
+
+const unpinMessage = `-- name: UnpinMessage :execrows
+DELETE FROM room_pins WHERE message_id = $1
+`
+
+func (q *Queries) UnpinMessage(ctx context.Context, messageID uuid.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, unpinMessage, messageID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const updateOwnMessage = `-- name: UpdateOwnMessage :one
+UPDATE messages
+SET
+  message = $1,
+  updated_at = now()
+WHERE
+  id = $2 AND room_id = $3 AND user_id = $4 AND NOT answered AND NOT hidden
+  AND created_at > now() - $5::int * INTERVAL '1 second'
+RETURNING id, room_id, message, answered, created_at, updated_at, answer, hidden, user_id, anonymous
+`
+
+type UpdateOwnMessageParams struct {
+	Message           string      `db:"message" json:"message"`
+	ID                uuid.UUID   `db:"id" json:"id"`
+	RoomID            int64       `db:"room_id" json:"room_id"`
+	UserID            pgtype.UUID `db:"user_id" json:"user_id"`
+	EditWindowSeconds int32       `db:"edit_window_seconds" json:"edit_window_seconds"`
+}
+
+func (q *Queries) UpdateOwnMessage(ctx context.Context, arg UpdateOwnMessageParams) (Message, error) {
+	row := q.db.QueryRow(ctx, updateOwnMessage,
+		arg.Message,
+		arg.ID,
+		arg.RoomID,
+		arg.UserID,
+		arg.EditWindowSeconds,
+	)
+	var i Message
+	err := row.Scan(
+		&i.ID,
+		&i.RoomID,
+		&i.Message,
+		&i.Answered,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Answer,
+		&i.Hidden,
+		&i.UserID,
+		&i.Anonymous,
+	)
+	return i, err
+}
+
+const updateRoom = `-- name: UpdateRoom :one
+WITH previous AS (
+  SELECT slug FROM rooms WHERE id = $1
+), redirected AS (
+  INSERT INTO room_slug_redirects ("slug", "room_id")
+  SELECT previous.slug, $1 FROM previous
+  WHERE previous.slug <> COALESCE($2, previous.slug)
+  ON CONFLICT ("slug") DO UPDATE SET room_id = EXCLUDED.room_id
+), reclaimed AS (
+  DELETE FROM room_slug_redirects WHERE slug = $2 AND room_id = $1
+)
+UPDATE rooms
+SET
+  name = COALESCE($3, name),
+  description = COALESCE($4, description),
+  visibility = COALESCE($5, visibility),
+  slug = COALESCE($2, slug),
+  updated_at = now()
+WHERE
+  id = $1
+RETURNING id, name, created_at, updated_at, user_id, description, visibility, status, opens_at, closes_at, slug, max_question_length, slow_mode_seconds, reactions_allowed, public_read, anonymous_questions
+`
+
+type UpdateRoomParams struct {
+	ID          int64       `db:"id" json:"id"`
+	Slug        pgtype.Text `db:"slug" json:"slug"`
+	Name        pgtype.Text `db:"name" json:"name"`
+	Description pgtype.Text `db:"description" json:"description"`
+	Visibility  pgtype.Text `db:"visibility" json:"visibility"`
+}
+
+func (q *Queries) UpdateRoom(ctx context.Context, arg UpdateRoomParams) (Room, error) {
+	row := q.db.QueryRow(ctx, updateRoom,
+		arg.ID,
+		arg.Slug,
+		arg.Name,
+		arg.Description,
+		arg.Visibility,
+	)
+	var i Room
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Description,
+		&i.Visibility,
+		&i.Status,
+		&i.OpensAt,
+		&i.ClosesAt,
+		&i.Slug,
+		&i.MaxQuestionLength,
+		&i.SlowModeSeconds,
+		&i.ReactionsAllowed,
+		&i.PublicRead,
+		&i.AnonymousQuestions,
+	)
+	return i, err
+}
+
+const updateRoomSettings = `-- name: UpdateRoomSettings :one
+UPDATE rooms
+SET
+  anonymous_questions = COALESCE($1, anonymous_questions),
+  max_question_length = COALESCE($2, max_question_length),
+  slow_mode_seconds = COALESCE($3, slow_mode_seconds),
+  reactions_allowed = COALESCE($4, reactions_allowed),
+  public_read = COALESCE($5, public_read),
+  updated_at = now()
+WHERE
+  id = $6
+RETURNING id, name, created_at, updated_at, user_id, description, visibility, status, opens_at, closes_at, slug, max_question_length, slow_mode_seconds, reactions_allowed, public_read, anonymous_questions
+`
+
+type UpdateRoomSettingsParams struct {
+	AnonymousQuestions pgtype.Bool `db:"anonymous_questions" json:"anonymous_questions"`
+	MaxQuestionLength  pgtype.Int4 `db:"max_question_length" json:"max_question_length"`
+	SlowModeSeconds    pgtype.Int4 `db:"slow_mode_seconds" json:"slow_mode_seconds"`
+	ReactionsAllowed   pgtype.Bool `db:"reactions_allowed" json:"reactions_allowed"`
+	PublicRead         pgtype.Bool `db:"public_read" json:"public_read"`
+	ID                 int64       `db:"id" json:"id"`
+}
+
+func (q *Queries) UpdateRoomSettings(ctx context.Context, arg UpdateRoomSettingsParams) (Room, error) {
+	row := q.db.QueryRow(ctx, updateRoomSettings,
+		arg.AnonymousQuestions,
+		arg.MaxQuestionLength,
+		arg.SlowModeSeconds,
+		arg.ReactionsAllowed,
+		arg.PublicRead,
+		arg.ID,
+	)
+	var i Room
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Description,
+		&i.Visibility,
+		&i.Status,
+		&i.OpensAt,
+		&i.ClosesAt,
+		&i.Slug,
+		&i.MaxQuestionLength,
+		&i.SlowModeSeconds,
+		&i.ReactionsAllowed,
+		&i.PublicRead,
+		&i.AnonymousQuestions,
+	)
+	return i, err
+}
+
+const updateRoomStatus = `-- name: UpdateRoomStatus :one
+UPDATE rooms
+SET
+  status = $1,
+  opens_at = $2,
+  closes_at = $3,
+  updated_at = now()
+WHERE
+  id = $4
+RETURNING id, name, created_at, updated_at, user_id, description, visibility, status, opens_at, closes_at, slug, max_question_length, slow_mode_seconds, reactions_allowed, public_read, anonymous_questions
+`
+
+type UpdateRoomStatusParams struct {
+	Status   string           `db:"status" json:"status"`
+	OpensAt  pgtype.Timestamp `db:"opens_at" json:"opens_at"`
+	ClosesAt pgtype.Timestamp `db:"closes_at" json:"closes_at"`
+	ID       int64            `db:"id" json:"id"`
+}
+
+func (q *Queries) UpdateRoomStatus(ctx context.Context, arg UpdateRoomStatusParams) (Room, error) {
+	row := q.db.QueryRow(ctx, updateRoomStatus,
+		arg.Status,
+		arg.OpensAt,
+		arg.ClosesAt,
+		arg.ID,
+	)
+	var i Room
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Description,
+		&i.Visibility,
+		&i.Status,
+		&i.OpensAt,
+		&i.ClosesAt,
+		&i.Slug,
+		&i.MaxQuestionLength,
+		&i.SlowModeSeconds,
+		&i.ReactionsAllowed,
+		&i.PublicRead,
+		&i.AnonymousQuestions,
+	)
+	return i, err
+}
+
 const updateUser = `-- name: UpdateUser :one
 UPDATE users
 SET
@@ -452,6 +1971,59 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (UpdateU
 	return i, err
 }
 
+const upsertRoomMember = `-- name: UpsertRoomMember :one
+INSERT INTO room_members
+  ("room_id", "user_id", "role") VALUES
+  ($1, $2, $3)
+ON CONFLICT ("room_id", "user_id") DO UPDATE SET role = EXCLUDED.role
+RETURNING room_id, user_id, role, created_at
+`
+
+type UpsertRoomMemberParams struct {
+	RoomID int64     `db:"room_id" json:"room_id"`
+	UserID uuid.UUID `db:"user_id" json:"user_id"`
+	Role   string    `db:"role" json:"role"`
+}
+
+func (q *Queries) UpsertRoomMember(ctx context.Context, arg UpsertRoomMemberParams) (RoomMember, error) {
+	row := q.db.QueryRow(ctx, upsertRoomMember, arg.RoomID, arg.UserID, arg.Role)
+	var i RoomMember
+	err := row.Scan(
+		&i.RoomID,
+		&i.UserID,
+		&i.Role,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const upsertRoomTransfer = `-- name: UpsertRoomTransfer :one
+INSERT INTO room_transfers
+  ("room_id", "from_user_id", "to_user_id") VALUES
+  ($1, $2, $3)
+ON CONFLICT ("room_id") DO UPDATE
+SET from_user_id = EXCLUDED.from_user_id, to_user_id = EXCLUDED.to_user_id, created_at = now()
+RETURNING room_id, from_user_id, to_user_id, created_at
+`
+
+type UpsertRoomTransferParams struct {
+	RoomID     int64     `db:"room_id" json:"room_id"`
+	FromUserID uuid.UUID `db:"from_user_id" json:"from_user_id"`
+	ToUserID   uuid.UUID `db:"to_user_id" json:"to_user_id"`
+}
+
+func (q *Queries) UpsertRoomTransfer(ctx context.Context, arg UpsertRoomTransferParams) (RoomTransfer, error) {
+	row := q.db.QueryRow(ctx, upsertRoomTransfer, arg.RoomID, arg.FromUserID, arg.ToUserID)
+	var i RoomTransfer
+	err := row.Scan(
+		&i.RoomID,
+		&i.FromUserID,
+		&i.ToUserID,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
 const userHasReacted = `-- name: UserHasReacted :one
 SELECT message_id, user_id FROM messages_reactions
 WHERE message_id = $1 AND user_id = $2
@@ -3,36 +3,391 @@ SELECT * FROM rooms WHERE id = $1;
 
 -- name: GetRoomWithUser :one
 SELECT
-  r."id", r."name", r."description", r."created_at", r."updated_at", u."email", u."name" as "creator_name", u."id" as "user_id", u."photo", u."enable_picture"
+  r."id", r."name", r."description", r."created_at", r."updated_at", u."name" as "creator_name", u."id" as "user_id", CASE WHEN u.enable_picture THEN u.photo END AS "photo", u."enable_picture", r."visibility", r."status", r."opens_at", r."closes_at", r."slug",
+  r."max_question_length", r."slow_mode_seconds", r."reactions_allowed", r."public_read", r."anonymous_questions",
+  p."message_id" AS "pinned_message_id"
 FROM rooms r
 LEFT JOIN users u ON r.user_id = u.id
+LEFT JOIN room_pins p ON p.room_id = r.id
 WHERE r.id = $1;
 
 -- name: GetRooms :many
-SELECT r.*, u."name" AS "creator_name" FROM rooms r
-LEFT JOIN users u ON r.user_id = u.id
-ORDER BY r.created_at ASC;
+WITH page AS (
+  -- Rooms sorted by creation are paged here, before their messages are
+  -- counted; the other sorts need the counts of every room to be paged
+  SELECT r.*
+  FROM rooms r
+  WHERE
+    (sqlc.narg('search')::text IS NULL OR r.name ILIKE '%' || sqlc.narg('search') || '%' OR r.description ILIKE '%' || sqlc.narg('search') || '%')
+    AND (sqlc.narg('creator_id')::uuid IS NULL OR r.user_id = sqlc.narg('creator_id'))
+    AND (sqlc.narg('created_after')::timestamp IS NULL OR r.created_at > sqlc.narg('created_after'))
+    AND (sqlc.narg('created_before')::timestamp IS NULL OR r.created_at < sqlc.narg('created_before'))
+    AND (
+      r.user_id = sqlc.arg('viewer_id')
+      OR EXISTS (SELECT 1 FROM room_members rm WHERE rm.room_id = r.id AND rm.user_id = sqlc.arg('viewer_id'))
+      -- Drafts are only listed to their hosts
+      OR (r.status <> 'draft' AND (
+        r.visibility = 'public'
+        OR EXISTS (SELECT 1 FROM room_guests g WHERE g.room_id = r.id AND g.user_id = sqlc.arg('viewer_id'))
+      ))
+    )
+    AND (
+      sqlc.narg('cursor_id')::bigint IS NULL
+      OR CASE sqlc.arg('sort')::text
+        WHEN 'newest' THEN (r.created_at, r.id) < (sqlc.narg('cursor_time')::timestamp, sqlc.narg('cursor_id'))
+        WHEN 'oldest' THEN (r.created_at, r.id) > (sqlc.narg('cursor_time'), sqlc.narg('cursor_id'))
+        ELSE true
+      END
+    )
+  ORDER BY
+    CASE WHEN sqlc.arg('sort') = 'newest' THEN r.created_at END DESC,
+    CASE WHEN sqlc.arg('sort') = 'oldest' THEN r.created_at END ASC,
+    CASE WHEN sqlc.arg('sort') = 'oldest' THEN r.id END ASC,
+    r.id DESC
+  LIMIT CASE WHEN sqlc.arg('sort') IN ('newest', 'oldest') THEN sqlc.arg('page_size')::int END
+)
+SELECT
+  page.*,
+  u."name" AS "creator_name",
+  activity.message_count,
+  activity.last_activity_at
+FROM page
+LEFT JOIN users u ON page.user_id = u.id
+CROSS JOIN LATERAL (
+  SELECT
+    COUNT(m.id) AS "message_count",
+    GREATEST(page.updated_at, MAX(m.updated_at))::timestamp AS "last_activity_at"
+  FROM messages m
+  WHERE m.room_id = page.id
+) activity
+WHERE
+  sqlc.narg('cursor_id') IS NULL
+  OR CASE sqlc.arg('sort')
+    WHEN 'most_questions' THEN (activity.message_count, page.id) < (sqlc.narg('cursor_count')::bigint, sqlc.narg('cursor_id'))
+    WHEN 'recent_activity' THEN (activity.last_activity_at, page.id) < (sqlc.narg('cursor_time'), sqlc.narg('cursor_id'))
+    ELSE true
+  END
+ORDER BY
+  CASE WHEN sqlc.arg('sort') = 'newest' THEN page.created_at END DESC,
+  CASE WHEN sqlc.arg('sort') = 'oldest' THEN page.created_at END ASC,
+  CASE WHEN sqlc.arg('sort') = 'most_questions' THEN activity.message_count END DESC,
+  CASE WHEN sqlc.arg('sort') = 'recent_activity' THEN activity.last_activity_at END DESC,
+  CASE WHEN sqlc.arg('sort') = 'oldest' THEN page.id END ASC,
+  page.id DESC
+LIMIT sqlc.arg('page_size');
 
 -- name: InsertRoom :one
 INSERT INTO rooms
-  ("name", "user_id", "description") VALUES
-  ($1, $2, $3)
+  ("name", "user_id", "description", "visibility", "status", "opens_at", "closes_at", "slug") VALUES
+  ($1, $2, $3, $4, $5, $6, $7, $8)
 RETURNING "id", "created_at";
 
+-- name: UpdateRoom :one
+WITH previous AS (
+  SELECT slug FROM rooms WHERE id = sqlc.arg('id')
+), redirected AS (
+  INSERT INTO room_slug_redirects ("slug", "room_id")
+  SELECT previous.slug, sqlc.arg('id') FROM previous
+  WHERE previous.slug <> COALESCE(sqlc.narg('slug'), previous.slug)
+  ON CONFLICT ("slug") DO UPDATE SET room_id = EXCLUDED.room_id
+), reclaimed AS (
+  DELETE FROM room_slug_redirects WHERE slug = sqlc.narg('slug') AND room_id = sqlc.arg('id')
+)
+UPDATE rooms
+SET
+  name = COALESCE(sqlc.narg('name'), name),
+  description = COALESCE(sqlc.narg('description'), description),
+  visibility = COALESCE(sqlc.narg('visibility'), visibility),
+  slug = COALESCE(sqlc.narg('slug'), slug),
+  updated_at = now()
+WHERE
+  id = sqlc.arg('id')
+RETURNING *;
+
+-- name: ResolveRoomSlug :one
+SELECT r."id", r."slug" FROM rooms r
+WHERE r.slug = $1 OR r.id = (SELECT rr.room_id FROM room_slug_redirects rr WHERE rr.slug = $1)
+ORDER BY r.slug = $1 DESC
+LIMIT 1;
+
+-- name: GetImportTargetRoom :one
+SELECT r.* FROM rooms r
+WHERE r.user_id = sqlc.arg('user_id') AND (
+  r.slug = sqlc.arg('slug')
+  OR EXISTS (SELECT 1 FROM room_slug_redirects rr WHERE rr.room_id = r.id AND rr.slug = sqlc.arg('slug'))
+  OR (r.name = sqlc.arg('name') AND r.slug ~ ('^' || sqlc.arg('slug') || '-[0-9]+$'))
+)
+ORDER BY r.slug = sqlc.arg('slug') DESC, r.id
+LIMIT 1;
+
+-- name: GetTakenRoomSlugs :many
+SELECT slug FROM rooms
+WHERE (slug = sqlc.arg('slug') OR slug LIKE sqlc.arg('slug') || '-%') AND id <> sqlc.arg('room_id')
+UNION
+SELECT slug FROM room_slug_redirects
+WHERE (slug = sqlc.arg('slug') OR slug LIKE sqlc.arg('slug') || '-%') AND room_id <> sqlc.arg('room_id');
+
+-- name: UpdateRoomStatus :one
+UPDATE rooms
+SET
+  status = $1,
+  opens_at = $2,
+  closes_at = $3,
+  updated_at = now()
+WHERE
+  id = $4
+RETURNING *;
+
+-- name: OpenDueRooms :many
+UPDATE rooms
+SET status = 'open', updated_at = now()
+WHERE status = 'scheduled' AND opens_at <= now()
+RETURNING *;
+
+-- name: CloseDueRooms :many
+UPDATE rooms
+SET status = 'closed', updated_at = now()
+WHERE status = 'open' AND closes_at <= now()
+RETURNING *;
+
+-- name: UpdateRoomSettings :one
+UPDATE rooms
+SET
+  anonymous_questions = COALESCE(sqlc.narg('anonymous_questions'), anonymous_questions),
+  max_question_length = COALESCE(sqlc.narg('max_question_length'), max_question_length),
+  slow_mode_seconds = COALESCE(sqlc.narg('slow_mode_seconds'), slow_mode_seconds),
+  reactions_allowed = COALESCE(sqlc.narg('reactions_allowed'), reactions_allowed),
+  public_read = COALESCE(sqlc.narg('public_read'), public_read),
+  updated_at = now()
+WHERE
+  id = sqlc.arg('id')
+RETURNING *;
+
+-- name: StartQuestionCooldown :exec
+INSERT INTO room_question_cooldowns ("room_id", "user_id") VALUES ($1, $2)
+ON CONFLICT ("room_id", "user_id") DO UPDATE SET asked_at = now();
+
+-- name: GetQuestionCooldown :one
+SELECT CEIL(EXTRACT(EPOCH FROM asked_at + sqlc.arg('slow_mode_seconds')::int * INTERVAL '1 second' - now()))::int AS "retry_after"
+FROM room_question_cooldowns
+WHERE room_id = $1 AND user_id = $2;
+
+-- name: DeleteRoom :one
+DELETE FROM rooms
+WHERE id = $1 RETURNING visibility;
+
+-- name: GetRoomAccess :one
+SELECT
+  r."visibility", r."user_id", r."status", r."opens_at", r."closes_at",
+  r."max_question_length", r."slow_mode_seconds", r."reactions_allowed", r."public_read", r."anonymous_questions",
+  EXISTS (SELECT 1 FROM room_guests g WHERE g.room_id = r.id AND g.user_id = $2) AS "is_guest",
+  COALESCE(m."role", '')::text AS "role"
+FROM rooms r
+LEFT JOIN room_members m ON m.room_id = r.id AND m.user_id = $2
+WHERE r.id = $1;
+
+-- name: InsertRoomInvite :one
+INSERT INTO room_invites
+  ("token", "room_id", "created_by", "expires_at", "max_uses", "email_domains") VALUES
+  ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: GetRoomInvites :many
+SELECT * FROM room_invites
+WHERE room_id = $1
+ORDER BY created_at DESC;
+
+-- name: GetRoomInvite :one
+SELECT * FROM room_invites WHERE token = $1;
+
+-- name: DeleteRoomInvite :one
+DELETE FROM room_invites
+WHERE token = $1 AND room_id = $2 RETURNING token;
+
+-- name: RedeemRoomInvite :one
+WITH invite AS (
+  SELECT room_id FROM room_invites
+  WHERE
+    token = $1
+    AND (expires_at IS NULL OR expires_at > now())
+    AND (max_uses IS NULL OR uses < max_uses)
+  FOR UPDATE
+), joined AS (
+  INSERT INTO room_guests ("room_id", "user_id")
+  SELECT room_id, $2 FROM invite
+  ON CONFLICT DO NOTHING
+  RETURNING room_id
+)
+UPDATE room_invites
+SET uses = uses + 1
+FROM joined
+WHERE room_invites.token = $1
+RETURNING joined.room_id;
+
+-- name: UpsertRoomMember :one
+INSERT INTO room_members
+  ("room_id", "user_id", "role") VALUES
+  ($1, $2, $3)
+ON CONFLICT ("room_id", "user_id") DO UPDATE SET role = EXCLUDED.role
+RETURNING *;
+
+-- name: UpsertRoomTransfer :one
+INSERT INTO room_transfers
+  ("room_id", "from_user_id", "to_user_id") VALUES
+  ($1, $2, $3)
+ON CONFLICT ("room_id") DO UPDATE
+SET from_user_id = EXCLUDED.from_user_id, to_user_id = EXCLUDED.to_user_id, created_at = now()
+RETURNING *;
+
+-- name: DeleteRoomTransfer :one
+DELETE FROM room_transfers
+WHERE room_id = $1 AND (from_user_id = $2 OR to_user_id = $2)
+RETURNING room_id;
+
+-- name: GetIncomingRoomTransfers :many
+SELECT t."room_id", r."name" AS "room_name", r."slug" AS "room_slug", t."from_user_id", u."name" AS "from_user_name", t."created_at"
+FROM room_transfers t
+JOIN rooms r ON r.id = t.room_id AND r.user_id = t.from_user_id
+JOIN users u ON u.id = t.from_user_id
+WHERE t.to_user_id = $1
+ORDER BY t.created_at DESC, t.room_id;
+
+-- name: AcceptRoomTransfer :one
+WITH transfer AS (
+  DELETE FROM room_transfers t
+  WHERE t.room_id = sqlc.arg('room_id') AND t.to_user_id = sqlc.arg('user_id')
+  RETURNING t.room_id, t.from_user_id, t.to_user_id
+), moved AS (
+  UPDATE rooms r
+  SET user_id = t.to_user_id, updated_at = now()
+  FROM transfer t
+  WHERE r.id = t.room_id AND r.user_id = t.from_user_id
+  RETURNING r.*
+), promoted AS (
+  -- The new owner leaves the hosts only when the room did change hands
+  DELETE FROM room_members rm
+  USING moved m
+  WHERE rm.room_id = m.id AND rm.user_id = m.user_id
+)
+SELECT *; FROM moved
+
+-- name: FollowRoom :exec
+INSERT INTO room_follows ("room_id", "user_id") VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: UnfollowRoom :exec
+DELETE FROM room_follows WHERE room_id = $1 AND user_id = $2;
+
+-- name: GetFollowedRooms :many
+SELECT r."id", r."name", r."slug", r."status", r."opens_at", r."closes_at", f."created_at" AS "followed_at"
+FROM room_follows f
+JOIN rooms r ON r.id = f.room_id
+WHERE f.user_id = $1
+  AND (
+    r.visibility = 'public'
+    OR r.user_id = f.user_id
+    OR EXISTS (SELECT 1 FROM room_guests g WHERE g.room_id = r.id AND g.user_id = f.user_id)
+    OR EXISTS (SELECT 1 FROM room_members rm WHERE rm.room_id = r.id AND rm.user_id = f.user_id)
+  )
+ORDER BY f.created_at DESC, r.id;
+
+-- name: InsertRoomOpenedNotifications :execrows
+INSERT INTO notifications ("user_id", "room_id", "kind")
+SELECT f.user_id, f.room_id, 'room_opened'
+FROM room_follows f
+JOIN rooms r ON r.id = f.room_id
+WHERE f.room_id = $1
+  AND f.user_id <> r.user_id
+  AND (
+    r.visibility = 'public'
+    OR r.user_id = f.user_id
+    OR EXISTS (SELECT 1 FROM room_guests g WHERE g.room_id = r.id AND g.user_id = f.user_id)
+    OR EXISTS (SELECT 1 FROM room_members rm WHERE rm.room_id = r.id AND rm.user_id = f.user_id)
+  );
+
+-- name: InsertMessageAnsweredNotifications :execrows
+INSERT INTO notifications ("user_id", "room_id", "message_id", "kind")
+SELECT f.user_id, m.room_id, m.id, 'message_answered'
+FROM messages m
+JOIN rooms r ON r.id = m.room_id
+JOIN room_follows f ON f.room_id = m.room_id
+WHERE m.id = $1
+  AND f.user_id <> r.user_id
+  AND (
+    m.user_id = f.user_id
+    OR EXISTS (SELECT 1 FROM messages_reactions mr WHERE mr.message_id = m.id AND mr.user_id = f.user_id)
+  )
+  AND (
+    r.visibility = 'public'
+    OR r.user_id = f.user_id
+    OR EXISTS (SELECT 1 FROM room_guests g WHERE g.room_id = r.id AND g.user_id = f.user_id)
+    OR EXISTS (SELECT 1 FROM room_members rm WHERE rm.room_id = r.id AND rm.user_id = f.user_id)
+  );
+
+-- name: GetUnreadNotifications :many
+SELECT n."id", n."kind", n."room_id", r."name" AS "room_name", r."slug" AS "room_slug", n."message_id", m."message", n."created_at"
+FROM notifications n
+JOIN rooms r ON r.id = n.room_id
+LEFT JOIN messages m ON m.id = n.message_id
+WHERE n.user_id = $1 AND n.read_at IS NULL
+ORDER BY n.created_at DESC, n.id
+LIMIT 100;
+
+-- name: MarkNotificationsRead :execrows
+UPDATE notifications
+SET read_at = now()
+WHERE user_id = $1 AND read_at IS NULL;
+
+-- name: GetRoomMembers :many
+SELECT m."user_id", u."name", CASE WHEN u.enable_picture THEN u.photo END AS "photo", m."role", m."created_at"
+FROM room_members m
+JOIN users u ON u.id = m.user_id
+WHERE m.room_id = $1
+ORDER BY m.created_at, u."name";
+
+-- name: DeleteRoomMember :one
+DELETE FROM room_members
+WHERE room_id = $1 AND user_id = $2 RETURNING user_id;
+
 -- name: GetMessage :one
 SELECT * FROM messages WHERE id = $1;
 
 -- name: GetRoomMessages :many
-SELECT m.*, COUNT(mr.message_id) AS reaction_count FROM messages m
+SELECT
+  m."id", m."room_id", m."message", m."answered", m."created_at", m."updated_at", m."answer", m."hidden", m."anonymous",
+  COUNT(mr.message_id) AS "reaction_count",
+  u."id" AS "author_id", u."name" AS "author_name", CASE WHEN u.enable_picture THEN u.photo END AS "author_photo"
+FROM messages m
 LEFT JOIN messages_reactions mr ON mr.message_id = m.id
-WHERE room_id = $1 GROUP BY m.id ORDER BY created_at DESC;
+LEFT JOIN users u ON u.id = m.user_id AND (NOT m.anonymous OR m.user_id = sqlc.arg('viewer_id') OR sqlc.arg('reveal_anonymous')::boolean)
+WHERE m.room_id = $1 AND (NOT m.hidden OR sqlc.arg('include_hidden')::boolean)
+GROUP BY m.id, u.id ORDER BY m.created_at DESC;
+
+-- name: ExportRoomMessages :many
+SELECT m."id", m."message", m."answer", m."answered", m."created_at", m."updated_at", COUNT(mr.message_id) AS "reaction_count"
+FROM messages m
+LEFT JOIN messages_reactions mr ON mr.message_id = m.id
+WHERE m.room_id = $1 AND NOT m.hidden
+GROUP BY m.id
+ORDER BY
+  CASE WHEN sqlc.arg('by_reactions')::boolean THEN COUNT(mr.message_id) END DESC,
+  m.created_at, m.id;
 
 -- name: InsertMessage :one
 INSERT INTO messages
-  ("room_id", "message") VALUES
-  ($1, $2)
+  ("room_id", "message", "user_id", "anonymous") VALUES
+  ($1, $2, $3, $4)
 RETURNING "id", "created_at";
 
+-- name: ImportMessage :execrows
+INSERT INTO messages
+  ("room_id", "message", "answer", "answered", "created_at", "updated_at", "answered_at")
+SELECT sqlc.arg('room_id')::bigint, sqlc.arg('message')::text, sqlc.arg('answer')::text, sqlc.arg('answered')::boolean, sqlc.arg('created_at')::timestamp, sqlc.arg('updated_at')::timestamp,
+  CASE WHEN sqlc.arg('answered') THEN sqlc.arg('updated_at') END
+WHERE NOT EXISTS (
+  SELECT 1 FROM messages m WHERE m.room_id = sqlc.arg('room_id') AND m.message = sqlc.arg('message')
+);
+
 -- name: InsertMessageReaction :one
 WITH mr_t AS (
   SELECT COUNT(*) AS total_count
@@ -68,6 +423,7 @@ UPDATE messages
 SET
   answered = true,
   answer = $1,
+  answered_at = now(),
   updated_at = now()
 WHERE
   id = $2 AND answered = false
@@ -100,7 +456,97 @@ RETURNING new_user, updated_at;
 DELETE FROM users
 WHERE id = $1 RETURNING id;
 
+-- name: DeleteUserWithSuccessor :one
+WITH handed_over AS (
+  UPDATE rooms SET user_id = sqlc.arg('successor_id'), updated_at = now()
+  WHERE user_id = sqlc.arg('id')
+  RETURNING id
+), promoted AS (
+  DELETE FROM room_members rm
+  USING handed_over h
+  WHERE rm.room_id = h.id AND rm.user_id = sqlc.arg('successor_id')
+)
+DELETE FROM users
+WHERE users.id = sqlc.arg('id')
+RETURNING users.id, ARRAY(SELECT id FROM handed_over ORDER BY id)::bigint[] AS "room_ids";
+
 -- name: GetRoomMessagesReactions :many
 SELECT mr.message_id FROM messages_reactions mr 
 LEFT JOIN messages m ON m.id = mr.message_id 
-WHERE m.room_id = $1 AND mr.user_id = $2;
\ No newline at end of file
+WHERE m.room_id = $1 AND mr.user_id = $2;
+
+-- name: SetMessageHidden :one
+UPDATE messages
+SET
+  hidden = $1,
+  updated_at = now()
+WHERE
+  id = $2
+RETURNING hidden;
+
+-- name: UpdateOwnMessage :one
+UPDATE messages
+SET
+  message = $1,
+  updated_at = now()
+WHERE
+  id = $2 AND room_id = $3 AND user_id = $4 AND NOT answered AND NOT hidden
+  AND created_at > now() - sqlc.arg('edit_window_seconds')::int * INTERVAL '1 second'
+RETURNING *;
+
+-- name: DeleteOwnMessage :one
+DELETE FROM messages m
+WHERE
+  m.id = $1 AND m.room_id = $2 AND m.user_id = $3 AND NOT m.answered AND NOT m.hidden
+  AND m.created_at > now() - sqlc.arg('edit_window_seconds')::int * INTERVAL '1 second'
+RETURNING EXISTS(SELECT 1 FROM room_pins p WHERE p.message_id = m.id) AS "pinned";
+
+-- name: PinMessage :one
+INSERT INTO room_pins ("room_id", "message_id", "pinned_by")
+SELECT m."room_id", m."id", sqlc.arg('pinned_by')::uuid FROM messages m
+WHERE m.id = sqlc.arg('message_id') AND NOT m.hidden
+ON CONFLICT ("room_id") DO UPDATE
+SET message_id = EXCLUDED.message_id, pinned_by = EXCLUDED.pinned_by, pinned_at = now()
+RETURNING *;
+
+-- name: UnpinMessage :execrows
+DELETE FROM room_pins WHERE message_id = $1;
+
+-- name: GetRoomStats :one
+SELECT
+  COUNT(*) AS "total_questions",
+  COUNT(*) FILTER (WHERE m.answered) AS "answered_questions",
+  COUNT(*) FILTER (WHERE NOT m.answered) AS "unanswered_questions",
+  COALESCE(
+    PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM m.answered_at - m.created_at)) FILTER (WHERE m.answered_at IS NOT NULL),
+    0
+  )::float8 AS "median_seconds_to_answer",
+  COUNT(DISTINCT m.user_id) AS "unique_askers",
+  (
+    SELECT COUNT(*) FROM messages_reactions mr
+    JOIN messages rm ON rm.id = mr.message_id
+    WHERE rm.room_id = $1
+  ) AS "total_reactions",
+  (
+    SELECT COUNT(DISTINCT mr.user_id) FROM messages_reactions mr
+    JOIN messages rm ON rm.id = mr.message_id
+    WHERE rm.room_id = $1
+  ) AS "unique_reactors"
+FROM messages m
+WHERE m.room_id = $1;
+
+-- name: GetRoomTopReactedMessages :many
+SELECT m."id", m."message", COUNT(mr.message_id) AS "reaction_count"
+FROM messages m
+JOIN messages_reactions mr ON mr.message_id = m.id
+WHERE m.room_id = $1
+GROUP BY m.id
+ORDER BY "reaction_count" DESC, m.created_at
+LIMIT $2;
+
+-- name: GetRoomQuestionsPerMinute :many
+SELECT DATE_TRUNC('minute', m.created_at)::timestamp AS "minute", COUNT(*) AS "questions"
+FROM messages m
+WHERE m.room_id = $1
+GROUP BY 1
+ORDER BY 1;
@@ -0,0 +1,35 @@
+package pgstore
+
+import "context"
+
+// StreamExportRoomMessages runs ExportRoomMessages and calls fn with each row
+// as it is read, so large rooms are never held in memory. It stops at the
+// first error returned by fn.
+func (q *Queries) StreamExportRoomMessages(ctx context.Context, arg ExportRoomMessagesParams, fn func(ExportRoomMessagesRow) error) error {
+	rows, err := q.db.Query(ctx, exportRoomMessages, arg.RoomID, arg.ByReactions)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var i ExportRoomMessagesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Message,
+			&i.Answer,
+			&i.Answered,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ReactionCount,
+		); err != nil {
+			return err
+		}
+
+		if err := fn(i); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
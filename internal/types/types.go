@@ -1,17 +1,85 @@
 package types
 
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+)
+
 const (
 	MessageKindMessageCreated         = "message_created"
 	MessageKindMessageReactionAdd     = "message_reaction_added"
 	MessageKindMessageReactionRemoved = "message_reaction_removed"
 	MessageKindMessageAnswered        = "message_answered"
+	MessageKindMessageHidden          = "message_hidden"
+	MessageKindMessagePinned          = "message_pinned"
+	MessageKindMessageUpdated         = "message_updated"
+	MessageKindMessageDeleted         = "message_deleted"
 	MessageKindRoomCreated            = "room_created"
+	MessageKindRoomUpdated            = "room_updated"
+	MessageKindRoomDeleted            = "room_deleted"
+	MessageKindResyncRequired         = "resync_required"
+	MessageKindCommandAck             = "command_ack"
+	MessageKindCommandError           = "command_error"
+	MessageKindPresenceChanged        = "presence_changed"
+	MessageKindRoomSnapshot           = "room_snapshot"
+	MessageKindRoomHidden             = "room_hidden"
+	MessageKindRoomStatusChanged      = "room_status_changed"
+	MessageKindRoomSettingsUpdated    = "room_settings_updated"
+	MessageKindRoomOwnerChanged       = "room_owner_changed"
+	MessageKindNotifications          = "notifications"
+)
+
+const (
+	CommandKindAsk     = "ask"
+	CommandKindReact   = "react"
+	CommandKindUnreact = "unreact"
+	CommandKindAnswer  = "answer"
+
+	CommandKindSubscribe   = "subscribe"
+	CommandKindUnsubscribe = "unsubscribe"
+)
+
+const (
+	TopicRooms      = "rooms"
+	roomTopicPrefix = "room:"
 )
 
+func RoomTopic(roomID int64) string {
+	return roomTopicPrefix + strconv.FormatInt(roomID, 10)
+}
+
+// ParseTopic validates a topic and returns the room it refers to, or zero for
+// the rooms list topic.
+func ParseTopic(topic string) (roomID int64, err error) {
+	if topic == TopicRooms {
+		return 0, nil
+	}
+
+	rawRoomID, ok := strings.CutPrefix(topic, roomTopicPrefix)
+	if !ok {
+		return 0, errors.New("invalid topic")
+	}
+
+	roomID, err = strconv.ParseInt(rawRoomID, 10, 64)
+	if err != nil || roomID <= 0 {
+		return 0, errors.New("invalid topic")
+	}
+
+	return roomID, nil
+}
+
+// MessageCreated carries a new question. The author fields are nil when the
+// question was asked anonymously.
 type MessageCreated struct {
-	ID        string `json:"id"`
-	CreatedAt string `json:"created_at"`
-	Message   string `json:"message"`
+	ID          string  `json:"id"`
+	CreatedAt   string  `json:"created_at"`
+	Message     string  `json:"message"`
+	Anonymous   bool    `json:"anonymous"`
+	AuthorID    *string `json:"author_id"`
+	AuthorName  *string `json:"author_name"`
+	AuthorPhoto *string `json:"author_photo"`
 }
 
 type MessageReactionAdded struct {
@@ -29,17 +97,159 @@ type MessageAnswered struct {
 	Answer string `json:"answer"`
 }
 
+// MessageHidden tells the room subscribers that a host hid or showed again a
+// message. Clients without a role in the room drop hidden messages and fetch
+// them again when they are shown.
+type MessageHidden struct {
+	ID     string `json:"id"`
+	Hidden bool   `json:"hidden"`
+}
+
+// MessagePinned tells the room subscribers which message the hosts are
+// answering now. ID is nil when the pin is cleared.
+type MessagePinned struct {
+	ID *string `json:"id"`
+}
+
+// MessageUpdated tells the room subscribers that the author changed the text
+// of a question.
+type MessageUpdated struct {
+	ID        string `json:"id"`
+	Message   string `json:"message"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+// MessageDeleted tells the room subscribers that the author withdrew a
+// question.
+type MessageDeleted struct {
+	ID string `json:"id"`
+}
+
 type Message struct {
 	Kind   string `json:"kind"`
 	Value  any    `json:"value"`
 	RoomID int64  `json:"-"`
+	Topic  string `json:"topic,omitempty"`
+	Seq    int64  `json:"seq,omitempty"`
+}
+
+// Command is a frame sent by a client over the room socket. Every command is
+// answered with a command_ack or command_error message carrying its RequestID.
+type Command struct {
+	Kind      string          `json:"kind"`
+	RequestID string          `json:"request_id"`
+	Value     json.RawMessage `json:"value"`
+}
+
+type CommandAck struct {
+	RequestID string `json:"request_id"`
+	Result    any    `json:"result"`
+}
+
+type CommandError struct {
+	RequestID string `json:"request_id"`
+	Status    int    `json:"status"`
+	Error     string `json:"error"`
+}
+
+type TopicSubscription struct {
+	Topic string `json:"topic"`
+	Since *int64 `json:"since,omitempty"`
+}
+
+type Viewer struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Photo string `json:"photo,omitempty"`
+}
+
+type PresenceChanged struct {
+	Count   int      `json:"count"`
+	Viewers []Viewer `json:"viewers"`
+}
+
+type ResyncRequired struct {
+	Since int64 `json:"since"`
 }
 
 type RoomCreated struct {
+	ID          int64   `json:"id"`
+	CreatedAt   string  `json:"created_at"`
+	Name        string  `json:"name"`
+	UserID      string  `json:"user_id"`
+	CreatorName string  `json:"creator_name"`
+	Description string  `json:"description"`
+	Status      string  `json:"status"`
+	OpensAt     *string `json:"opens_at"`
+	ClosesAt    *string `json:"closes_at"`
+	Slug        string  `json:"slug"`
+}
+
+type RoomUpdated struct {
 	ID          int64  `json:"id"`
-	CreatedAt   string `json:"created_at"`
+	UpdatedAt   string `json:"updated_at"`
 	Name        string `json:"name"`
+	Description string `json:"description"`
+	Visibility  string `json:"visibility"`
+	Slug        string `json:"slug"`
+}
+
+type RoomDeleted struct {
+	ID int64 `json:"id"`
+}
+
+// RoomHidden tells the rooms list subscribers that a room is no longer public
+// and must be dropped from the list.
+type RoomHidden struct {
+	ID int64 `json:"id"`
+}
+
+// RoomStatusChanged carries the new status of a room and the times it opens
+// and closes at, null when it does not open or close on its own.
+type RoomStatusChanged struct {
+	ID        int64   `json:"id"`
+	Status    string  `json:"status"`
+	OpensAt   *string `json:"opens_at"`
+	ClosesAt  *string `json:"closes_at"`
+	UpdatedAt string  `json:"updated_at"`
+}
+
+// RoomOwnerChanged carries the new owner of a room, after a transfer was
+// accepted or the previous owner handed the room over on leaving.
+type RoomOwnerChanged struct {
+	ID          int64  `json:"id"`
 	UserID      string `json:"user_id"`
 	CreatorName string `json:"creator_name"`
-	Description string `json:"description"`
+	UpdatedAt   string `json:"updated_at"`
+}
+
+// Notification is something that happened in a room the user follows while
+// they were away. MessageID and Message are only set for answered messages.
+type Notification struct {
+	ID        string  `json:"id"`
+	Kind      string  `json:"kind"`
+	RoomID    int64   `json:"room_id"`
+	RoomName  string  `json:"room_name"`
+	RoomSlug  string  `json:"room_slug"`
+	MessageID *string `json:"message_id"`
+	Message   *string `json:"message"`
+	CreatedAt string  `json:"created_at"`
+}
+
+// Notifications carries the unread notifications of the user, sent on the
+// rooms list socket when it connects.
+type Notifications struct {
+	Notifications []Notification `json:"notifications"`
+}
+
+// RoomSettingsUpdated carries all the settings of a room after its owner
+// changed any of them.
+type RoomSettingsUpdated struct {
+	ID                 int64  `json:"id"`
+	AnonymousQuestions bool   `json:"anonymous_questions"`
+	MaxQuestionLength  int32  `json:"max_question_length"`
+	SlowModeSeconds    int32  `json:"slow_mode_seconds"`
+	ReactionsAllowed   bool   `json:"reactions_allowed"`
+	PublicRead         bool   `json:"public_read"`
+	UpdatedAt          string `json:"updated_at"`
 }
@@ -0,0 +1,229 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator"
+	"github.com/google/uuid"
+	"github.com/vhrboliveira/ama-go/internal/service"
+	"github.com/vhrboliveira/ama-go/internal/store/pgstore"
+	"github.com/vhrboliveira/ama-go/internal/types"
+)
+
+// roomCommands runs the commands sent over a room socket on behalf of the
+// session user. Each command goes through the same checks as its REST
+// counterpart and its broadcast reaches every subscriber, the sender included.
+func (h *Handlers) roomCommands(user pgstore.User, roomID int64) service.CommandHandler {
+	return func(ctx context.Context, _ *service.Subscriber, cmd types.Command) types.Message {
+		if cmd.RequestID == "" {
+			return service.CommandErrorMessage(cmd, http.StatusBadRequest, errors.New("validation failed, missing required field(s): RequestID"))
+		}
+
+		result, status, err := h.runRoomCommand(ctx, user, roomID, cmd)
+		if err != nil {
+			slog.Error("room command failed", "kind", cmd.Kind, "room_id", roomID, "error", err)
+			return service.CommandErrorMessage(cmd, status, err)
+		}
+
+		return types.Message{
+			Kind:   types.MessageKindCommandAck,
+			RoomID: roomID,
+			Topic:  types.RoomTopic(roomID),
+			Value: types.CommandAck{
+				RequestID: cmd.RequestID,
+				Result:    result,
+			},
+		}
+	}
+}
+
+func (h *Handlers) runRoomCommand(ctx context.Context, user pgstore.User, roomID int64, cmd types.Command) (any, int, error) {
+	switch cmd.Kind {
+	case types.CommandKindAsk:
+		return h.askCommand(ctx, user, roomID, cmd.Value)
+	case types.CommandKindReact:
+		return h.reactCommand(ctx, user, roomID, cmd.Value, h.reactToMessage)
+	case types.CommandKindUnreact:
+		return h.reactCommand(ctx, user, roomID, cmd.Value, h.removeReactionFromMessage)
+	case types.CommandKindAnswer:
+		return h.answerCommand(ctx, user, roomID, cmd.Value)
+	default:
+		return nil, http.StatusBadRequest, errors.New("unknown command: " + cmd.Kind)
+	}
+}
+
+// topicCommands runs the commands sent over a multiplexed socket, which
+// manage the topics the connection is subscribed to.
+func (h *Handlers) topicCommands(user pgstore.User) service.CommandHandler {
+	return func(ctx context.Context, sub *service.Subscriber, cmd types.Command) types.Message {
+		if cmd.RequestID == "" {
+			return service.CommandErrorMessage(cmd, http.StatusBadRequest, errors.New("validation failed, missing required field(s): RequestID"))
+		}
+
+		var body types.TopicSubscription
+		if err := decodeCommandValue(cmd.Value, &body); err != nil {
+			return service.CommandErrorMessage(cmd, http.StatusBadRequest, errors.New("invalid body"))
+		}
+
+		var status int
+		var err error
+		switch cmd.Kind {
+		case types.CommandKindSubscribe:
+			status, err = h.subscribeCommand(ctx, user, sub, body)
+		case types.CommandKindUnsubscribe:
+			status, err = http.StatusBadRequest, h.WebsocketService.Unsubscribe(sub, body.Topic)
+		default:
+			status, err = http.StatusBadRequest, errors.New("unknown command: "+cmd.Kind)
+		}
+
+		if err != nil {
+			slog.Error("topic command failed", "kind", cmd.Kind, "topic", body.Topic, "user_id", user.ID, "error", err)
+			return service.CommandErrorMessage(cmd, status, err)
+		}
+
+		return types.Message{
+			Kind:  types.MessageKindCommandAck,
+			Topic: body.Topic,
+			Value: types.CommandAck{
+				RequestID: cmd.RequestID,
+				Result:    body,
+			},
+		}
+	}
+}
+
+func (h *Handlers) subscribeCommand(ctx context.Context, user pgstore.User, sub *service.Subscriber, body types.TopicSubscription) (int, error) {
+	roomID, err := types.ParseTopic(body.Topic)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	if body.Since != nil && *body.Since < 0 {
+		return http.StatusBadRequest, errors.New("invalid since cursor")
+	}
+
+	var snapshot service.SnapshotFunc
+	if roomID != 0 {
+		status, err := h.RoomService.CheckRoomAccess(ctx, roomID, user.ID)
+		if err != nil {
+			return status, err
+		}
+
+		snapshot = h.roomSnapshot(user, roomID)
+	}
+
+	if err := h.WebsocketService.Subscribe(sub, body.Topic, body.Since, snapshot); err != nil {
+		if errors.Is(err, service.ErrAlreadySubscribed) {
+			return http.StatusBadRequest, err
+		}
+
+		return http.StatusInternalServerError, err
+	}
+
+	return http.StatusOK, nil
+}
+
+func (h *Handlers) askCommand(ctx context.Context, user pgstore.User, roomID int64, value json.RawMessage) (any, int, error) {
+	type commandValue struct {
+		Message   string `json:"message" validate:"required"`
+		Anonymous bool   `json:"anonymous"`
+	}
+
+	type result struct {
+		ID        string `json:"id"`
+		CreatedAt string `json:"created_at"`
+	}
+
+	var body commandValue
+	if err := decodeCommandValue(value, &body); err != nil {
+		return nil, http.StatusBadRequest, errors.New("validation failed: missing required field(s): message")
+	}
+
+	message, status, err := h.createMessage(ctx, roomID, user, body.Message, body.Anonymous)
+	if err != nil {
+		return nil, status, err
+	}
+
+	return result{ID: message.ID.String(), CreatedAt: message.CreatedAt.Time.Format(time.RFC3339)}, status, nil
+}
+
+func (h *Handlers) reactCommand(
+	ctx context.Context,
+	user pgstore.User,
+	roomID int64,
+	value json.RawMessage,
+	react func(ctx context.Context, roomID int64, messageID, userID uuid.UUID) (int32, error),
+) (any, int, error) {
+	type commandValue struct {
+		MessageID string `json:"message_id" validate:"required"`
+	}
+
+	type result struct {
+		Count int32 `json:"count"`
+	}
+
+	var body commandValue
+	if err := decodeCommandValue(value, &body); err != nil {
+		return nil, http.StatusBadRequest, errors.New("validation failed, missing required field(s): MessageID")
+	}
+
+	messageID, err := uuid.Parse(body.MessageID)
+	if err != nil {
+		return nil, http.StatusBadRequest, errors.New("invalid message id")
+	}
+
+	status, err := h.checkReactableMessage(ctx, roomID, user.ID, messageID)
+	if err != nil {
+		return nil, status, err
+	}
+
+	count, err := react(ctx, roomID, messageID, user.ID)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+
+	return result{Count: count}, http.StatusOK, nil
+}
+
+func (h *Handlers) answerCommand(ctx context.Context, user pgstore.User, roomID int64, value json.RawMessage) (any, int, error) {
+	type commandValue struct {
+		MessageID string `json:"message_id" validate:"required"`
+		Answer    string `json:"answer" validate:"required"`
+	}
+
+	var body commandValue
+	if err := decodeCommandValue(value, &body); err != nil || strings.TrimSpace(body.Answer) == "" {
+		return nil, http.StatusBadRequest, errors.New("validation failed, missing required field(s): MessageID, Answer")
+	}
+	body.Answer = strings.TrimSpace(body.Answer)
+
+	messageID, err := uuid.Parse(body.MessageID)
+	if err != nil {
+		return nil, http.StatusBadRequest, errors.New("invalid message id")
+	}
+
+	status, err := h.checkModeratedMessage(ctx, roomID, user.ID, messageID, "answer questions")
+	if err != nil {
+		return nil, status, err
+	}
+
+	if status, err := h.answerMessage(ctx, roomID, messageID, body.Answer); err != nil {
+		return nil, status, err
+	}
+
+	return types.MessageAnswered{ID: messageID.String(), Answer: body.Answer}, http.StatusOK, nil
+}
+
+func decodeCommandValue(value json.RawMessage, dst any) error {
+	if err := json.Unmarshal(value, dst); err != nil {
+		return err
+	}
+
+	return validator.New().Struct(dst)
+}
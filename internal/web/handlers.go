@@ -6,6 +6,7 @@ import (
 	"errors"
 	"log/slog"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -14,17 +15,25 @@ import (
 	"github.com/go-playground/validator"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/vhrboliveira/ama-go/internal/auth"
 	"github.com/vhrboliveira/ama-go/internal/service"
 	"github.com/vhrboliveira/ama-go/internal/store/pgstore"
 	"github.com/vhrboliveira/ama-go/internal/types"
 )
 
+var (
+	invalidVisibilityMessage = "Visibility must be one of: " + strings.Join(service.RoomVisibilities, ", ")
+	invalidStatusMessage     = "Status must be one of: " + strings.Join(service.RoomStatuses, ", ")
+	invalidSlugMessage       = "Slug must have at most 60 lowercase letters, digits and single dashes, and cannot be only digits"
+)
+
 type Handlers struct {
 	Router           *chi.Mux
 	RoomService      *service.RoomService
 	MessageService   *service.MessageService
 	UserService      *service.UserService
+	ImportService    *service.ImportService
 	WebsocketService *service.WebSocketService
 }
 
@@ -39,6 +48,7 @@ func NewHandler(
 	roomService *service.RoomService,
 	messageService *service.MessageService,
 	userService *service.UserService,
+	importService *service.ImportService,
 	websocketService *service.WebSocketService,
 ) *Handlers {
 	return &Handlers{
@@ -46,6 +56,7 @@ func NewHandler(
 		RoomService:      roomService,
 		MessageService:   messageService,
 		UserService:      userService,
+		ImportService:    importService,
 		WebsocketService: websocketService,
 	}
 }
@@ -56,9 +67,13 @@ func (h *Handlers) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handlers) CreateRoom(w http.ResponseWriter, r *http.Request) {
 	type requestBody struct {
-		Name        string `json:"name" validate:"required"`
-		UserID      string `json:"user_id" validate:"required,uuid"`
-		Description string `json:"description" validate:"max=255"`
+		Name        string     `json:"name" validate:"required"`
+		UserID      string     `json:"user_id" validate:"required,uuid"`
+		Description string     `json:"description" validate:"max=255"`
+		Visibility  string     `json:"visibility" validate:"omitempty,oneof=public unlisted private"`
+		Status      string     `json:"status" validate:"omitempty,oneof=draft scheduled open closed archived"`
+		OpensAt     *time.Time `json:"opens_at"`
+		ClosesAt    *time.Time `json:"closes_at"`
 	}
 
 	var body requestBody
@@ -84,12 +99,39 @@ func (h *Handlers) CreateRoom(w http.ResponseWriter, r *http.Request) {
 				http.Error(w, "validation failed: UserID must be a valid UUID", http.StatusBadRequest)
 				return
 			}
+
+			if err.Tag() == "oneof" && err.Field() == "Visibility" {
+				http.Error(w, "validation failed: "+invalidVisibilityMessage, http.StatusBadRequest)
+				return
+			}
+
+			if err.Tag() == "oneof" && err.Field() == "Status" {
+				http.Error(w, "validation failed: "+invalidStatusMessage, http.StatusBadRequest)
+				return
+			}
 		}
 
 		http.Error(w, "validation failed, missing required field(s): "+strings.Join(missingFields, ", "), http.StatusBadRequest)
 		return
 	}
 
+	if body.Visibility == "" {
+		body.Visibility = service.RoomVisibilityPublic
+	}
+
+	schedule := service.RoomSchedule{Status: body.Status, OpensAt: body.OpensAt, ClosesAt: body.ClosesAt}
+	if schedule.Status == "" {
+		schedule.Status = service.RoomStatusOpen
+		if schedule.OpensAt != nil {
+			schedule.Status = service.RoomStatusScheduled
+		}
+	}
+
+	if err := validateRoomSchedule(schedule, time.Now()); err != nil {
+		http.Error(w, "validation failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	userID, err := uuid.Parse(body.UserID)
 	if err != nil {
 		slog.Error("invalid user ID", "error", err)
@@ -110,7 +152,7 @@ func (h *Handlers) CreateRoom(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	room, err := h.RoomService.CreateRoom(r.Context(), body.Name, userID, body.Description)
+	room, slug, err := h.RoomService.CreateRoom(r.Context(), body.Name, userID, body.Description, body.Visibility, schedule)
 	if err != nil {
 		slog.Error("error creating room", "error", err)
 		http.Error(w, "error creating room", http.StatusInternalServerError)
@@ -118,16 +160,39 @@ func (h *Handlers) CreateRoom(w http.ResponseWriter, r *http.Request) {
 	}
 
 	type response struct {
-		ID          int64  `json:"id"`
-		UserID      string `json:"user_id"`
-		CreatedAt   string `json:"created_at"`
-		Description string `json:"description"`
+		ID          int64   `json:"id"`
+		UserID      string  `json:"user_id"`
+		CreatedAt   string  `json:"created_at"`
+		Description string  `json:"description"`
+		Visibility  string  `json:"visibility"`
+		Status      string  `json:"status"`
+		OpensAt     *string `json:"opens_at"`
+		ClosesAt    *string `json:"closes_at"`
+		Slug        string  `json:"slug"`
 	}
 
 	createdAt := room.CreatedAt.Time.Format(time.RFC3339)
+	opensAt := formatTime(schedule.OpensAt)
+	closesAt := formatTime(schedule.ClosesAt)
 
 	w.WriteHeader(http.StatusCreated)
-	sendJSON(w, response{ID: room.ID, UserID: userID.String(), CreatedAt: createdAt, Description: body.Description})
+	sendJSON(w, response{
+		ID:          room.ID,
+		UserID:      userID.String(),
+		CreatedAt:   createdAt,
+		Description: body.Description,
+		Visibility:  body.Visibility,
+		Status:      schedule.Status,
+		OpensAt:     opensAt,
+		ClosesAt:    closesAt,
+		Slug:        slug,
+	})
+
+	// Only public rooms are announced, the others are found through a link.
+	// Drafts are announced by the status change once they are opened.
+	if body.Visibility != service.RoomVisibilityPublic || schedule.Status == service.RoomStatusDraft {
+		return
+	}
 
 	go h.WebsocketService.NotifyRoomsListClients(types.Message{
 		Kind:   types.MessageKindRoomCreated,
@@ -139,22 +204,75 @@ func (h *Handlers) CreateRoom(w http.ResponseWriter, r *http.Request) {
 			UserID:      userID.String(),
 			CreatorName: user.Name,
 			Description: body.Description,
+			Status:      schedule.Status,
+			OpensAt:     opensAt,
+			ClosesAt:    closesAt,
+			Slug:        slug,
 		},
 	})
 }
 
 func (h *Handlers) GetRooms(w http.ResponseWriter, r *http.Request) {
-	rooms, err := h.RoomService.GetRooms(r.Context())
+	type response struct {
+		pgstore.GetRoomsRow
+		Presence int `json:"presence"`
+	}
+
+	query, err := parseRoomsQuery(r.URL.Query())
 	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user, ok := r.Context().Value(auth.UserKey).(pgstore.User)
+	if !ok {
+		slog.Error("user not found on the session cookie")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	query.ViewerID = user.ID
+
+	rooms, next, err := h.RoomService.GetRooms(r.Context(), query)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCursor) {
+			http.Error(w, "invalid cursor", http.StatusBadRequest)
+			return
+		}
+
 		slog.Error("error getting rooms list", "error", err)
 		http.Error(w, "error getting rooms list", http.StatusInternalServerError)
 		return
 	}
 
-	sendJSON(w, rooms)
+	if next != "" {
+		w.Header().Set("Link", nextPageLink(r.URL, next))
+	}
+
+	roomIDs := make([]int64, 0, len(rooms))
+	for _, room := range rooms {
+		roomIDs = append(roomIDs, room.ID)
+	}
+
+	// The presence is a hint, so the list is still sent without it
+	presence, err := h.WebsocketService.PresenceCounts(r.Context(), roomIDs)
+	if err != nil {
+		slog.Error("error getting rooms presence", "error", err)
+	}
+
+	result := make([]response, 0, len(rooms))
+	for _, room := range rooms {
+		result = append(result, response{GetRoomsRow: room, Presence: presence[room.ID]})
+	}
+
+	sendJSON(w, result)
 }
 
 func (h *Handlers) GetRoom(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		pgstore.GetRoomWithUserRow
+		Presence int `json:"presence"`
+	}
+
 	rawRoomID := chi.URLParam(r, "room_id")
 	roomID, err := strconv.ParseInt(rawRoomID, 10, 64)
 	if err != nil {
@@ -163,7 +281,11 @@ func (h *Handlers) GetRoom(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	room, err := h.RoomService.GetRoom(r.Context(), roomID)
+	// Visitors without a session only get here for the rooms that allow public
+	// reads, and read them as users with no access beyond that
+	user, _ := r.Context().Value(auth.UserKey).(pgstore.User)
+
+	room, err := h.RoomService.GetRoom(r.Context(), roomID, user.ID)
 	if err != nil {
 		slog.Error("error getting room", "error", err)
 		http.Error(w, "error getting room", http.StatusInternalServerError)
@@ -175,12 +297,170 @@ func (h *Handlers) GetRoom(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sendJSON(w, room)
+	presence, err := h.WebsocketService.PresenceCount(r.Context(), roomID)
+	if err != nil {
+		slog.Error("error getting room presence", "error", err)
+	}
+
+	sendJSON(w, response{GetRoomWithUserRow: room, Presence: presence})
+}
+
+func (h *Handlers) UpdateRoom(w http.ResponseWriter, r *http.Request) {
+	type requestBody struct {
+		Name        *string `json:"name"`
+		Description *string `json:"description"`
+		Visibility  *string `json:"visibility"`
+		Slug        *string `json:"slug"`
+	}
+
+	rawRoomID := chi.URLParam(r, "room_id")
+	roomID, err := strconv.ParseInt(rawRoomID, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid room id", http.StatusBadRequest)
+		return
+	}
+
+	var body requestBody
+	err = json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		slog.Error("failed to decode body", "error", err)
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	if body.Name == nil && body.Description == nil && body.Visibility == nil && body.Slug == nil {
+		http.Error(w, "validation failed, missing required field(s): Name, Description, Visibility or Slug", http.StatusBadRequest)
+		return
+	}
+
+	if body.Name != nil {
+		name := strings.TrimSpace(*body.Name)
+		if name == "" {
+			http.Error(w, "validation failed: name cannot be empty", http.StatusBadRequest)
+			return
+		}
+		body.Name = &name
+	}
+
+	if body.Description != nil && len(*body.Description) > 255 {
+		http.Error(w, "validation failed: description must have at most 255 characters", http.StatusBadRequest)
+		return
+	}
+
+	if body.Visibility != nil && !slices.Contains(service.RoomVisibilities, *body.Visibility) {
+		http.Error(w, "validation failed: "+invalidVisibilityMessage, http.StatusBadRequest)
+		return
+	}
+
+	if body.Slug != nil && !service.IsRoomSlug(*body.Slug) {
+		http.Error(w, "validation failed: "+invalidSlugMessage, http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	user, ok := ctx.Value(auth.UserKey).(pgstore.User)
+	if !ok {
+		slog.Error("user not found on the session cookie")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	status, err := h.RoomService.CheckRoomOwner(ctx, roomID, user.ID)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	room, err := h.RoomService.UpdateRoom(ctx, roomID, service.RoomChanges{
+		Name:        body.Name,
+		Description: body.Description,
+		Visibility:  body.Visibility,
+		Slug:        body.Slug,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrRoomSlugTaken) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+
+		slog.Error("error updating room", "error", err)
+		http.Error(w, "error updating room", http.StatusInternalServerError)
+		return
+	}
+
+	roomUpdated := types.RoomUpdated{
+		ID:          room.ID,
+		UpdatedAt:   room.UpdatedAt.Time.Format(time.RFC3339),
+		Name:        room.Name,
+		Description: room.Description,
+		Visibility:  room.Visibility,
+		Slug:        room.Slug,
+	}
+
+	sendJSON(w, roomUpdated)
+
+	msg := types.Message{
+		Kind:   types.MessageKindRoomUpdated,
+		RoomID: room.ID,
+		Value:  roomUpdated,
+	}
+	if room.Visibility != service.RoomVisibilityPublic {
+		msg.Kind = types.MessageKindRoomHidden
+		msg.Value = types.RoomHidden{ID: room.ID}
+	}
+
+	go h.WebsocketService.NotifyRoomsListClients(msg)
+}
+
+func (h *Handlers) DeleteRoom(w http.ResponseWriter, r *http.Request) {
+	rawRoomID := chi.URLParam(r, "room_id")
+	roomID, err := strconv.ParseInt(rawRoomID, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid room id", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	user, ok := ctx.Value(auth.UserKey).(pgstore.User)
+	if !ok {
+		slog.Error("user not found on the session cookie")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	status, err := h.RoomService.CheckRoomOwner(ctx, roomID, user.ID)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	visibility, err := h.RoomService.DeleteRoom(ctx, roomID)
+	if err != nil {
+		slog.Error("error deleting room", "error", err)
+		http.Error(w, "error deleting room", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	msg := types.Message{
+		Kind:   types.MessageKindRoomDeleted,
+		RoomID: roomID,
+		Value:  types.RoomDeleted{ID: roomID},
+	}
+
+	// Only public rooms are on the rooms list
+	if visibility == service.RoomVisibilityPublic {
+		go h.WebsocketService.NotifyRoomsListClients(msg)
+	}
+	// Delivering room_deleted to the room topic also closes its subscribers
+	go h.WebsocketService.NotifyRoomClient(msg)
 }
 
 func (h *Handlers) CreateRoomMessage(w http.ResponseWriter, r *http.Request) {
 	type roomMessageRequestBody struct {
-		Message string `json:"message" validate:"required"`
+		Message   string `json:"message" validate:"required"`
+		Anonymous bool   `json:"anonymous"`
 	}
 
 	type response struct {
@@ -210,28 +490,26 @@ func (h *Handlers) CreateRoomMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx := r.Context()
-	status, err := h.RoomService.CheckRoomExists(ctx, roomID)
-	if err != nil {
-		http.Error(w, err.Error(), status)
+	user, ok := r.Context().Value(auth.UserKey).(pgstore.User)
+	if !ok {
+		slog.Error("user not found on the session cookie")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	message, err := h.MessageService.CreateMessage(ctx, roomID, body.Message)
+	message, status, err := h.createMessage(r.Context(), roomID, user, body.Message, body.Anonymous)
 	if err != nil {
-		slog.Error("error inserting message", "error", err)
-		http.Error(w, "error inserting message", http.StatusInternalServerError)
+		var slowMode service.SlowModeError
+		if errors.As(err, &slowMode) {
+			w.Header().Set("Retry-After", strconv.Itoa(int(slowMode.RetryAfter)))
+		}
+
+		http.Error(w, err.Error(), status)
 		return
 	}
 
 	w.WriteHeader(http.StatusCreated)
 	sendJSON(w, response{ID: message.ID.String(), CreatedAt: message.CreatedAt.Time.Format(time.RFC3339)})
-
-	go h.WebsocketService.NotifyRoomClient(types.Message{
-		Kind:   types.MessageKindMessageCreated,
-		Value:  types.MessageCreated{ID: message.ID.String(), CreatedAt: message.CreatedAt.Time.Format(time.RFC3339), Message: body.Message},
-		RoomID: roomID,
-	})
 }
 
 func (h *Handlers) GetRoomMessages(w http.ResponseWriter, r *http.Request) {
@@ -243,13 +521,18 @@ func (h *Handlers) GetRoomMessages(w http.ResponseWriter, r *http.Request) {
 	}
 
 	ctx := r.Context()
-	status, err := h.RoomService.CheckRoomExists(ctx, roomID)
+	// Visitors without a session only get here for the rooms that allow public
+	// reads
+	user, _ := ctx.Value(auth.UserKey).(pgstore.User)
+
+	status, err := h.RoomService.CheckRoomAccess(ctx, roomID, user.ID)
 	if err != nil {
 		http.Error(w, err.Error(), status)
 		return
 	}
 
-	roomMessages, err := h.MessageService.GetMessages(ctx, roomID)
+	canModerate := h.RoomService.CanModerateRoom(ctx, roomID, user.ID)
+	roomMessages, err := h.MessageService.GetMessages(ctx, roomID, user.ID, canModerate)
 	if err != nil {
 		slog.Error("error getting room messages", "error", err)
 		http.Error(w, "error getting room messages", http.StatusInternalServerError)
@@ -276,7 +559,11 @@ func (h *Handlers) GetRoomMessage(w http.ResponseWriter, r *http.Request) {
 	}
 
 	ctx := r.Context()
-	status, err := h.RoomService.CheckRoomExists(ctx, roomID)
+	// Visitors without a session only get here for the rooms that allow public
+	// reads
+	user, _ := ctx.Value(auth.UserKey).(pgstore.User)
+
+	status, err := h.RoomService.CheckRoomAccess(ctx, roomID, user.ID)
 	if err != nil {
 		http.Error(w, err.Error(), status)
 		return
@@ -289,11 +576,22 @@ func (h *Handlers) GetRoomMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if message == (pgstore.Message{}) {
+	if message == (pgstore.Message{}) || message.RoomID != roomID {
+		http.Error(w, "message not found", http.StatusNotFound)
+		return
+	}
+
+	if message.Hidden && !h.RoomService.CanModerateRoom(ctx, roomID, user.ID) {
+		slog.Error("the message is hidden", "message_id", messageID)
 		http.Error(w, "message not found", http.StatusNotFound)
 		return
 	}
 
+	// Only the author and the room hosts know who asked an anonymous question
+	if message.Anonymous && uuid.UUID(message.UserID.Bytes) != user.ID && !h.RoomService.CanModerateRoom(ctx, roomID, user.ID) {
+		message.UserID = pgtype.UUID{}
+	}
+
 	sendJSON(w, message)
 }
 
@@ -322,13 +620,14 @@ func (h *Handlers) ReactionToMessage(w http.ResponseWriter, r *http.Request) {
 	}
 
 	ctx := r.Context()
-	status, err := h.RoomService.CheckRoomExists(ctx, roomID)
-	if err != nil {
-		http.Error(w, err.Error(), status)
+	user, ok := ctx.Value(auth.UserKey).(pgstore.User)
+	if !ok {
+		slog.Error("user not found on the session cookie")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	status, err = h.MessageService.CheckMessageExists(ctx, messageID)
+	status, err := h.checkReactableMessage(ctx, roomID, user.ID, messageID)
 	if err != nil {
 		http.Error(w, err.Error(), status)
 		return
@@ -367,35 +666,19 @@ func (h *Handlers) ReactionToMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, ok := r.Context().Value(auth.UserKey).(pgstore.User)
-	if !ok {
-		slog.Error("user not found on the session cookie")
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
-		return
-	}
-
 	if user.ID.String() != body.UserID {
 		slog.Error("the provided user ID is different from the session")
 		http.Error(w, "invalid user ID", http.StatusForbidden)
 		return
 	}
 
-	count, err := h.MessageService.ReactToMessage(ctx, messageID, user.ID)
+	count, err := h.reactToMessage(ctx, roomID, messageID, user.ID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	sendJSON(w, response{Count: count})
-
-	go h.WebsocketService.NotifyRoomClient(types.Message{
-		Kind:   types.MessageKindMessageReactionAdd,
-		RoomID: roomID,
-		Value: types.MessageReactionAdded{
-			ID:    rawMessageID,
-			Count: count,
-		},
-	})
 }
 
 func (h *Handlers) RemoveReactionFromMessage(w http.ResponseWriter, r *http.Request) {
@@ -423,13 +706,14 @@ func (h *Handlers) RemoveReactionFromMessage(w http.ResponseWriter, r *http.Requ
 	}
 
 	ctx := r.Context()
-	status, err := h.RoomService.CheckRoomExists(ctx, roomID)
-	if err != nil {
-		http.Error(w, err.Error(), status)
+	user, ok := ctx.Value(auth.UserKey).(pgstore.User)
+	if !ok {
+		slog.Error("user not found on the session cookie")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	status, err = h.MessageService.CheckMessageExists(ctx, messageID)
+	status, err := h.checkReactableMessage(ctx, roomID, user.ID, messageID)
 	if err != nil {
 		http.Error(w, err.Error(), status)
 		return
@@ -468,35 +752,19 @@ func (h *Handlers) RemoveReactionFromMessage(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	user, ok := r.Context().Value(auth.UserKey).(pgstore.User)
-	if !ok {
-		slog.Error("user not found on the session cookie")
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
-		return
-	}
-
 	if user.ID.String() != body.UserID {
 		slog.Error("the provided user ID is different from the session")
 		http.Error(w, "invalid user ID", http.StatusForbidden)
 		return
 	}
 
-	count, err := h.MessageService.RemoveReactionFromMessage(ctx, messageID, user.ID)
+	count, err := h.removeReactionFromMessage(ctx, roomID, messageID, user.ID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	sendJSON(w, response{Count: count})
-
-	go h.WebsocketService.NotifyRoomClient(types.Message{
-		Kind:   types.MessageKindMessageReactionRemoved,
-		RoomID: roomID,
-		Value: types.MessageReactionRemoved{
-			ID:    rawMessageID,
-			Count: count,
-		},
-	})
 }
 
 func (h *Handlers) SetMessageToAnswered(w http.ResponseWriter, r *http.Request) {
@@ -571,42 +839,79 @@ func (h *Handlers) SetMessageToAnswered(w http.ResponseWriter, r *http.Request)
 	}
 
 	ctx := r.Context()
-	status, err := h.RoomService.CheckRoomExists(ctx, roomID)
+	status, err := h.checkModeratedMessage(ctx, roomID, user.ID, messageID, "answer questions")
 	if err != nil {
 		http.Error(w, err.Error(), status)
 		return
 	}
 
-	status, err = h.MessageService.CheckMessageExists(ctx, messageID)
-	if err != nil {
+	if status, err := h.answerMessage(ctx, roomID, messageID, body.Answer); err != nil {
 		http.Error(w, err.Error(), status)
 		return
 	}
 
-	err = h.MessageService.AnswerMessage(ctx, messageID, body.Answer)
+	sendJSON(w, types.MessageAnswered{
+		ID:     rawMessageID,
+		Answer: body.Answer,
+	})
+}
+
+func (h *Handlers) SetMessageHidden(w http.ResponseWriter, r *http.Request) {
+	type requestBody struct {
+		Hidden *bool `json:"hidden" validate:"required"`
+	}
+
+	rawRoomID := chi.URLParam(r, "room_id")
+	roomID, err := strconv.ParseInt(rawRoomID, 10, 64)
 	if err != nil {
-		slog.Error("error setting message to answered", "error", err)
-		if errors.Is(pgx.ErrNoRows, err) {
-			http.Error(w, "the message has already been answered", http.StatusInternalServerError)
-			return
-		}
+		http.Error(w, "invalid room id", http.StatusBadRequest)
+		return
+	}
 
-		http.Error(w, "error setting message to answered", http.StatusInternalServerError)
+	rawMessageID := chi.URLParam(r, "message_id")
+	messageID, err := uuid.Parse(rawMessageID)
+	if err != nil {
+		slog.Error("unable to parse message id", "error", err)
+		http.Error(w, "invalid message id", http.StatusBadRequest)
 		return
 	}
 
-	sendJSON(w, types.MessageAnswered{
-		ID:     rawMessageID,
-		Answer: body.Answer,
-	})
+	var body requestBody
+	err = json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		slog.Error("failed to decode body", "error", err)
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
 
-	go h.WebsocketService.NotifyRoomClient(types.Message{
-		Kind:   types.MessageKindMessageAnswered,
-		RoomID: roomID,
-		Value: types.MessageAnswered{
-			ID:     rawMessageID,
-			Answer: body.Answer,
-		},
+	if err := validator.New().Struct(&body); err != nil {
+		slog.Error("validation failed", "error", err)
+		http.Error(w, "validation failed, missing required field(s): Hidden", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	user, ok := ctx.Value(auth.UserKey).(pgstore.User)
+	if !ok {
+		slog.Error("user not found on the session cookie")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	status, err := h.checkModeratedMessage(ctx, roomID, user.ID, messageID, "hide questions")
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	if err := h.hideMessage(ctx, roomID, messageID, *body.Hidden); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	sendJSON(w, types.MessageHidden{
+		ID:     rawMessageID,
+		Hidden: *body.Hidden,
 	})
 }
 
@@ -623,7 +928,14 @@ func (h *Handlers) GetRoomMessagesReactions(w http.ResponseWriter, r *http.Reque
 	}
 
 	ctx := r.Context()
-	status, err := h.RoomService.CheckRoomExists(ctx, roomID)
+	user, ok := ctx.Value(auth.UserKey).(pgstore.User)
+	if !ok {
+		slog.Error("user not found on the session cookie")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	status, err := h.RoomService.CheckRoomAccess(ctx, roomID, user.ID)
 	if err != nil {
 		http.Error(w, err.Error(), status)
 		return
@@ -636,13 +948,6 @@ func (h *Handlers) GetRoomMessagesReactions(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	user, ok := r.Context().Value(auth.UserKey).(pgstore.User)
-	if !ok {
-		slog.Error("user not found on the session cookie")
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
-		return
-	}
-
 	if user.ID.String() != userID {
 		slog.Error("the provided user ID is different from the session")
 		http.Error(w, "invalid user ID", http.StatusForbidden)
@@ -696,11 +1001,30 @@ func (h *Handlers) DeleteUserInfo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	deletedID, err := h.UserService.DeleteUserInfo(ctx, userID)
-	if err != nil || deletedID != userID {
-		slog.Error("error deleting user info", "error", err, "deletedID", deletedID, "userID", userID)
-		http.Error(w, "error deleting user info", http.StatusInternalServerError)
-		return
+	// The rooms of the user are deleted along with the user unless a successor
+	// takes them over
+	successorEmail := strings.TrimSpace(r.URL.Query().Get("successor_email"))
+	if successorEmail == "" {
+		deletedID, err := h.UserService.DeleteUserInfo(ctx, userID)
+		if err != nil || deletedID != userID {
+			slog.Error("error deleting user info", "error", err, "deletedID", deletedID, "userID", userID)
+			http.Error(w, "error deleting user info", http.StatusInternalServerError)
+			return
+		}
+	} else {
+		deletedID, roomIDs, status, err := h.UserService.DeleteUserWithSuccessor(ctx, userID, successorEmail)
+		if err != nil {
+			http.Error(w, err.Error(), status)
+			return
+		}
+
+		if deletedID != userID {
+			slog.Error("error deleting user info", "deletedID", deletedID, "userID", userID)
+			http.Error(w, "error deleting user info", http.StatusInternalServerError)
+			return
+		}
+
+		h.notifyRoomsHandedOver(roomIDs)
 	}
 
 	http.SetCookie(w, auth.GenerateExpiredCookie())
@@ -714,6 +1038,24 @@ func (h *Handlers) DeleteUserInfo(w http.ResponseWriter, r *http.Request) {
 	return
 }
 
+// notifyRoomsHandedOver tells the subscribers of the rooms a leaving user
+// handed over about their new owner. It runs after the request is done, so
+// it does not use its context.
+func (h *Handlers) notifyRoomsHandedOver(roomIDs []int64) {
+	go func() {
+		ctx := context.Background()
+		for _, roomID := range roomIDs {
+			room, err := h.RoomService.Queries.GetRoomWithUser(ctx, roomID)
+			if err != nil {
+				slog.Error("error getting handed over room", "room_id", roomID, "error", err)
+				continue
+			}
+
+			h.WebsocketService.NotifyRoomOwnerChanged(room)
+		}
+	}()
+}
+
 func (h *Handlers) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 	type requestBody struct {
 		UserID        string `json:"user_id" validate:"required,uuid"`
@@ -820,13 +1162,28 @@ func (h *Handlers) SubscribeToRoom(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	since, err := parseSinceCursor(r.URL.Query().Get("since"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Visitors without a session only get here for the rooms that allow public
+	// reads, and follow them without sending commands
 	ctx := r.Context()
-	status, err := h.RoomService.CheckRoomExists(ctx, roomID)
+	user, ok := ctx.Value(auth.UserKey).(pgstore.User)
+
+	status, err := h.RoomService.CheckRoomAccess(ctx, roomID, user.ID)
 	if err != nil {
 		http.Error(w, err.Error(), status)
 		return
 	}
 
+	var commands service.CommandHandler
+	if ok {
+		commands = h.roomCommands(user, roomID)
+	}
+
 	c, err := h.WebsocketService.Upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		slog.Error("failed to upgrade connection", "error", err)
@@ -837,10 +1194,17 @@ func (h *Handlers) SubscribeToRoom(w http.ResponseWriter, r *http.Request) {
 	defer c.Close()
 
 	ctx, cancel := context.WithCancel(r.Context())
-	h.WebsocketService.SubscribeToRoom(c, ctx, cancel, roomID, r.RemoteAddr)
+	h.WebsocketService.SubscribeToRoom(c, ctx, cancel, roomID, user, r.RemoteAddr, since, h.roomSnapshot(user, roomID), commands)
 }
 
 func (h Handlers) SubscribeToRoomsList(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(auth.UserKey).(pgstore.User)
+	if !ok {
+		slog.Error("user not found on the session cookie")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
 	c, err := h.WebsocketService.Upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		slog.Warn("failed to upgrade connection", "error", err)
@@ -851,5 +1215,262 @@ func (h Handlers) SubscribeToRoomsList(w http.ResponseWriter, r *http.Request) {
 	defer c.Close()
 
 	ctx, cancel := context.WithCancel(r.Context())
-	h.WebsocketService.SubscribeToRoomsList(c, ctx, cancel, r.RemoteAddr)
+	h.WebsocketService.SubscribeToRoomsList(c, ctx, cancel, user, r.RemoteAddr, h.unreadNotifications(user))
+}
+
+func (h *Handlers) SubscribeToTopics(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(auth.UserKey).(pgstore.User)
+	if !ok {
+		slog.Error("user not found on the session cookie")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	c, err := h.WebsocketService.Upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		slog.Error("failed to upgrade connection", "error", err)
+		http.Error(w, "failed to connect to ws connection", http.StatusBadRequest)
+		return
+	}
+
+	defer c.Close()
+
+	ctx, cancel := context.WithCancel(r.Context())
+	h.WebsocketService.SubscribeToTopics(c, ctx, cancel, user, r.RemoteAddr, h.topicCommands(user))
+}
+
+func (h *Handlers) StreamRoom(w http.ResponseWriter, r *http.Request) {
+	rawRoomID := chi.URLParam(r, "room_id")
+	roomID, err := strconv.ParseInt(rawRoomID, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid room id", http.StatusBadRequest)
+		return
+	}
+
+	// EventSource sends the last received event ID when it reconnects
+	rawSince := r.URL.Query().Get("since")
+	if rawSince == "" {
+		rawSince = r.Header.Get("Last-Event-ID")
+	}
+
+	since, err := parseSinceCursor(rawSince)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Visitors without a session only get here for the rooms that allow public
+	// reads
+	ctx := r.Context()
+	user, _ := ctx.Value(auth.UserKey).(pgstore.User)
+
+	status, err := h.RoomService.CheckRoomAccess(ctx, roomID, user.ID)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	if err := h.WebsocketService.StreamRoom(w, ctx, cancel, roomID, user, r.RemoteAddr, since, h.roomSnapshot(user, roomID)); err != nil {
+		slog.Error("failed to start event stream", "error", err)
+	}
+}
+
+func (h *Handlers) StreamRoomsList(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(auth.UserKey).(pgstore.User)
+	if !ok {
+		slog.Error("user not found on the session cookie")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	if err := h.WebsocketService.StreamRoomsList(w, ctx, cancel, user, r.RemoteAddr, h.unreadNotifications(user)); err != nil {
+		slog.Error("failed to start event stream", "error", err)
+	}
+}
+
+func parseSinceCursor(rawSince string) (*int64, error) {
+	if rawSince == "" {
+		return nil, nil
+	}
+
+	seq, err := strconv.ParseInt(rawSince, 10, 64)
+	if err != nil || seq < 0 {
+		slog.Error("invalid since cursor", "since", rawSince, "error", err)
+		return nil, errors.New("invalid since cursor")
+	}
+
+	return &seq, nil
+}
+
+// checkModeratedMessage checks that the message is in the room and the user is
+// one of its hosts, who are the only ones allowed to take the action.
+func (h *Handlers) checkModeratedMessage(ctx context.Context, roomID int64, userID, messageID uuid.UUID, action string) (int, error) {
+	status, err := h.RoomService.CheckRoomModerator(ctx, roomID, userID, action)
+	if err != nil {
+		return status, err
+	}
+
+	return h.MessageService.CheckMessageExists(ctx, roomID, messageID)
+}
+
+// checkReactableMessage checks that the message is in a room the user can
+// react in, which must be open and allow reactions.
+func (h *Handlers) checkReactableMessage(ctx context.Context, roomID int64, userID, messageID uuid.UUID) (int, error) {
+	status, err := h.RoomService.CheckRoomReactions(ctx, roomID, userID)
+	if err != nil {
+		return status, err
+	}
+
+	return h.MessageService.CheckMessageExists(ctx, roomID, messageID)
+}
+
+// createMessage, reactToMessage, removeReactionFromMessage and answerMessage
+// are shared by the REST handlers and the room socket commands, so both run
+// the same checks and notify the room subscribers the same way.
+func (h *Handlers) createMessage(ctx context.Context, roomID int64, user pgstore.User, msg string, anonymous bool) (pgstore.InsertMessageRow, int, error) {
+	slowed, status, err := h.RoomService.CheckQuestion(ctx, roomID, user.ID, msg, anonymous)
+	if err != nil {
+		return pgstore.InsertMessageRow{}, status, err
+	}
+
+	message, err := h.MessageService.CreateMessage(ctx, roomID, user.ID, msg, anonymous)
+	if err != nil {
+		slog.Error("error inserting message", "error", err)
+		return pgstore.InsertMessageRow{}, http.StatusInternalServerError, errors.New("error inserting message")
+	}
+
+	// The question is in even if its cooldown could not be started
+	if slowed {
+		if err := h.RoomService.StartQuestionCooldown(ctx, roomID, user.ID); err != nil {
+			slog.Error("error starting question cooldown", "room_id", roomID, "error", err)
+		}
+	}
+
+	created := types.MessageCreated{
+		ID:        message.ID.String(),
+		CreatedAt: message.CreatedAt.Time.Format(time.RFC3339),
+		Message:   msg,
+		Anonymous: anonymous,
+	}
+
+	if !anonymous {
+		authorID := user.ID.String()
+		created.AuthorID = &authorID
+		created.AuthorName = &user.Name
+		if user.EnablePicture {
+			created.AuthorPhoto = &user.Photo
+		}
+	}
+
+	go h.WebsocketService.NotifyRoomClient(types.Message{
+		Kind:   types.MessageKindMessageCreated,
+		Value:  created,
+		RoomID: roomID,
+	})
+
+	return message, http.StatusCreated, nil
+}
+
+func (h *Handlers) reactToMessage(ctx context.Context, roomID int64, messageID, userID uuid.UUID) (int32, error) {
+	count, err := h.MessageService.ReactToMessage(ctx, messageID, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	go h.WebsocketService.NotifyRoomClient(types.Message{
+		Kind:   types.MessageKindMessageReactionAdd,
+		RoomID: roomID,
+		Value: types.MessageReactionAdded{
+			ID:    messageID.String(),
+			Count: count,
+		},
+	})
+
+	return count, nil
+}
+
+func (h *Handlers) removeReactionFromMessage(ctx context.Context, roomID int64, messageID, userID uuid.UUID) (int32, error) {
+	count, err := h.MessageService.RemoveReactionFromMessage(ctx, messageID, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	go h.WebsocketService.NotifyRoomClient(types.Message{
+		Kind:   types.MessageKindMessageReactionRemoved,
+		RoomID: roomID,
+		Value: types.MessageReactionRemoved{
+			ID:    messageID.String(),
+			Count: count,
+		},
+	})
+
+	return count, nil
+}
+
+func (h *Handlers) answerMessage(ctx context.Context, roomID int64, messageID uuid.UUID, answer string) (int, error) {
+	err := h.MessageService.AnswerMessage(ctx, messageID, answer)
+	if err != nil {
+		slog.Error("error setting message to answered", "error", err)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return http.StatusConflict, errors.New("the message has already been answered")
+		}
+
+		return http.StatusInternalServerError, errors.New("error setting message to answered")
+	}
+
+	go h.WebsocketService.NotifyRoomClient(types.Message{
+		Kind:   types.MessageKindMessageAnswered,
+		RoomID: roomID,
+		Value: types.MessageAnswered{
+			ID:     messageID.String(),
+			Answer: answer,
+		},
+	})
+
+	return http.StatusOK, nil
+}
+
+// hideMessage also clears the pin of a hidden message, which would otherwise
+// point the viewers to a message they cannot see.
+func (h *Handlers) hideMessage(ctx context.Context, roomID int64, messageID uuid.UUID, hidden bool) error {
+	if err := h.MessageService.HideMessage(ctx, messageID, hidden); err != nil {
+		slog.Error("error hiding message", "error", err)
+		return errors.New("error hiding message")
+	}
+
+	unpinned := false
+	if hidden {
+		var err error
+		unpinned, err = h.MessageService.UnpinMessage(ctx, messageID)
+		if err != nil {
+			slog.Error("error unpinning hidden message", "message_id", messageID, "error", err)
+		}
+	}
+
+	go func() {
+		h.WebsocketService.NotifyRoomClient(types.Message{
+			Kind:   types.MessageKindMessageHidden,
+			RoomID: roomID,
+			Value: types.MessageHidden{
+				ID:     messageID.String(),
+				Hidden: hidden,
+			},
+		})
+
+		if unpinned {
+			h.WebsocketService.NotifyRoomClient(types.Message{
+				Kind:   types.MessageKindMessagePinned,
+				RoomID: roomID,
+				Value:  types.MessagePinned{},
+			})
+		}
+	}()
+
+	return nil
 }
@@ -0,0 +1,171 @@
+package web
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/vhrboliveira/ama-go/internal/auth"
+	"github.com/vhrboliveira/ama-go/internal/service"
+	"github.com/vhrboliveira/ama-go/internal/store/pgstore"
+)
+
+func (h *Handlers) CreateRoomInvite(w http.ResponseWriter, r *http.Request) {
+	type requestBody struct {
+		ExpiresAt    *time.Time `json:"expires_at"`
+		MaxUses      *int32     `json:"max_uses"`
+		EmailDomains []string   `json:"email_domains"`
+	}
+
+	rawRoomID := chi.URLParam(r, "room_id")
+	roomID, err := strconv.ParseInt(rawRoomID, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid room id", http.StatusBadRequest)
+		return
+	}
+
+	var body requestBody
+	err = json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		slog.Error("failed to decode body", "error", err)
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	if body.ExpiresAt != nil && !body.ExpiresAt.After(time.Now()) {
+		http.Error(w, "validation failed: expires_at must be in the future", http.StatusBadRequest)
+		return
+	}
+
+	if body.MaxUses != nil && *body.MaxUses < 1 {
+		http.Error(w, "validation failed: max_uses must be at least 1", http.StatusBadRequest)
+		return
+	}
+
+	domains := make([]string, 0, len(body.EmailDomains))
+	for _, rawDomain := range body.EmailDomains {
+		domain := service.NormalizeEmailDomain(rawDomain)
+		if domain == "" || !strings.Contains(domain, ".") || strings.ContainsAny(domain, "@ ") {
+			http.Error(w, "validation failed: invalid email domain: "+rawDomain, http.StatusBadRequest)
+			return
+		}
+
+		if !slices.Contains(domains, domain) {
+			domains = append(domains, domain)
+		}
+	}
+
+	ctx := r.Context()
+	user, ok := ctx.Value(auth.UserKey).(pgstore.User)
+	if !ok {
+		slog.Error("user not found on the session cookie")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	status, err := h.RoomService.CheckRoomOwner(ctx, roomID, user.ID)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	invite, err := h.RoomService.CreateInvite(ctx, roomID, user.ID, body.ExpiresAt, body.MaxUses, domains)
+	if err != nil {
+		slog.Error("error creating invite", "error", err)
+		http.Error(w, "error creating invite", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	sendJSON(w, invite)
+}
+
+func (h *Handlers) GetRoomInvites(w http.ResponseWriter, r *http.Request) {
+	rawRoomID := chi.URLParam(r, "room_id")
+	roomID, err := strconv.ParseInt(rawRoomID, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid room id", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	user, ok := ctx.Value(auth.UserKey).(pgstore.User)
+	if !ok {
+		slog.Error("user not found on the session cookie")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	status, err := h.RoomService.CheckRoomOwner(ctx, roomID, user.ID)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	invites, err := h.RoomService.GetInvites(ctx, roomID)
+	if err != nil {
+		slog.Error("error getting invites", "error", err)
+		http.Error(w, "error getting invites", http.StatusInternalServerError)
+		return
+	}
+
+	sendJSON(w, invites)
+}
+
+func (h *Handlers) DeleteRoomInvite(w http.ResponseWriter, r *http.Request) {
+	rawRoomID := chi.URLParam(r, "room_id")
+	roomID, err := strconv.ParseInt(rawRoomID, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid room id", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	user, ok := ctx.Value(auth.UserKey).(pgstore.User)
+	if !ok {
+		slog.Error("user not found on the session cookie")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	status, err := h.RoomService.CheckRoomOwner(ctx, roomID, user.ID)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	status, err = h.RoomService.DeleteInvite(ctx, roomID, chi.URLParam(r, "token"))
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handlers) AcceptRoomInvite(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		RoomID int64 `json:"room_id"`
+	}
+
+	ctx := r.Context()
+	user, ok := ctx.Value(auth.UserKey).(pgstore.User)
+	if !ok {
+		slog.Error("user not found on the session cookie")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	roomID, status, err := h.RoomService.AcceptInvite(ctx, chi.URLParam(r, "token"), user)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	sendJSON(w, response{RoomID: roomID})
+}
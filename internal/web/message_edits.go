@@ -0,0 +1,140 @@
+package web
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator"
+	"github.com/google/uuid"
+	"github.com/vhrboliveira/ama-go/internal/auth"
+	"github.com/vhrboliveira/ama-go/internal/store/pgstore"
+	"github.com/vhrboliveira/ama-go/internal/types"
+)
+
+// UpdateRoomMessage lets the author fix the text of a question shortly after
+// asking it, as long as it is unanswered.
+func (h *Handlers) UpdateRoomMessage(w http.ResponseWriter, r *http.Request) {
+	type requestBody struct {
+		Message string `json:"message" validate:"required"`
+	}
+
+	roomID, messageID, user, ok := parseOwnMessageRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var body requestBody
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		slog.Error("failed to decode body", "error", err)
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	if err := validator.New().Struct(&body); err != nil {
+		msg := "validation failed: missing required field(s): message"
+		slog.Error(msg, "error", err)
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	status, err := h.RoomService.CheckQuestionEdit(ctx, roomID, user.ID, body.Message)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	message, status, err := h.MessageService.UpdateOwnMessage(ctx, roomID, messageID, user.ID, body.Message)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	updated := types.MessageUpdated{
+		ID:        message.ID.String(),
+		Message:   message.Message,
+		UpdatedAt: message.UpdatedAt.Time.Format(time.RFC3339),
+	}
+
+	sendJSON(w, updated)
+
+	go h.WebsocketService.NotifyRoomClient(types.Message{
+		Kind:   types.MessageKindMessageUpdated,
+		RoomID: roomID,
+		Value:  updated,
+	})
+}
+
+// DeleteRoomMessage lets the author withdraw a question under the same rules
+// as UpdateRoomMessage. Withdrawing the pinned question also clears the pin.
+func (h *Handlers) DeleteRoomMessage(w http.ResponseWriter, r *http.Request) {
+	roomID, messageID, user, ok := parseOwnMessageRequest(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	status, err := h.RoomService.CheckRoomAccess(ctx, roomID, user.ID)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	pinned, status, err := h.MessageService.DeleteOwnMessage(ctx, roomID, messageID, user.ID)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	go func() {
+		h.WebsocketService.NotifyRoomClient(types.Message{
+			Kind:   types.MessageKindMessageDeleted,
+			RoomID: roomID,
+			Value:  types.MessageDeleted{ID: messageID.String()},
+		})
+
+		if pinned {
+			h.WebsocketService.NotifyRoomClient(types.Message{
+				Kind:   types.MessageKindMessagePinned,
+				RoomID: roomID,
+				Value:  types.MessagePinned{},
+			})
+		}
+	}()
+}
+
+// parseOwnMessageRequest reads the room and message of a request changing a
+// question and the session user, who must be its author. It writes the error
+// response when the request cannot go on.
+func parseOwnMessageRequest(w http.ResponseWriter, r *http.Request) (int64, uuid.UUID, pgstore.User, bool) {
+	rawRoomID := chi.URLParam(r, "room_id")
+	roomID, err := strconv.ParseInt(rawRoomID, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid room id", http.StatusBadRequest)
+		return 0, uuid.Nil, pgstore.User{}, false
+	}
+
+	rawMessageID := chi.URLParam(r, "message_id")
+	messageID, err := uuid.Parse(rawMessageID)
+	if err != nil {
+		slog.Error("unable to parse message id", "error", err)
+		http.Error(w, "invalid message id", http.StatusBadRequest)
+		return 0, uuid.Nil, pgstore.User{}, false
+	}
+
+	user, ok := r.Context().Value(auth.UserKey).(pgstore.User)
+	if !ok {
+		slog.Error("user not found on the session cookie")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return 0, uuid.Nil, pgstore.User{}, false
+	}
+
+	return roomID, messageID, user, true
+}
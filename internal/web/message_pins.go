@@ -0,0 +1,102 @@
+package web
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/vhrboliveira/ama-go/internal/auth"
+	"github.com/vhrboliveira/ama-go/internal/store/pgstore"
+	"github.com/vhrboliveira/ama-go/internal/types"
+)
+
+// PinMessage highlights the message as the one the hosts are answering now,
+// replacing the one pinned before.
+func (h *Handlers) PinMessage(w http.ResponseWriter, r *http.Request) {
+	roomID, messageID, user, ok := h.parsePinRequest(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	status, err := h.MessageService.PinMessage(ctx, messageID, user.ID)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	pinnedID := messageID.String()
+	pinned := types.MessagePinned{ID: &pinnedID}
+
+	sendJSON(w, pinned)
+
+	h.notifyMessagePinned(roomID, pinned)
+}
+
+// UnpinMessage clears the pin of the room when the message is the pinned one.
+// Unpinning any other message changes nothing.
+func (h *Handlers) UnpinMessage(w http.ResponseWriter, r *http.Request) {
+	roomID, messageID, _, ok := h.parsePinRequest(w, r)
+	if !ok {
+		return
+	}
+
+	unpinned, err := h.MessageService.UnpinMessage(r.Context(), messageID)
+	if err != nil {
+		slog.Error("error unpinning message", "message_id", messageID, "error", err)
+		http.Error(w, "error unpinning message", http.StatusInternalServerError)
+		return
+	}
+
+	sendJSON(w, types.MessagePinned{})
+
+	if unpinned {
+		h.notifyMessagePinned(roomID, types.MessagePinned{})
+	}
+}
+
+// parsePinRequest reads the room and message of a pin request and checks the
+// session user is one of the room hosts. It writes the error response when
+// the request cannot go on.
+func (h *Handlers) parsePinRequest(w http.ResponseWriter, r *http.Request) (int64, uuid.UUID, pgstore.User, bool) {
+	rawRoomID := chi.URLParam(r, "room_id")
+	roomID, err := strconv.ParseInt(rawRoomID, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid room id", http.StatusBadRequest)
+		return 0, uuid.Nil, pgstore.User{}, false
+	}
+
+	rawMessageID := chi.URLParam(r, "message_id")
+	messageID, err := uuid.Parse(rawMessageID)
+	if err != nil {
+		slog.Error("unable to parse message id", "error", err)
+		http.Error(w, "invalid message id", http.StatusBadRequest)
+		return 0, uuid.Nil, pgstore.User{}, false
+	}
+
+	ctx := r.Context()
+	user, ok := ctx.Value(auth.UserKey).(pgstore.User)
+	if !ok {
+		slog.Error("user not found on the session cookie")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return 0, uuid.Nil, pgstore.User{}, false
+	}
+
+	status, err := h.checkModeratedMessage(ctx, roomID, user.ID, messageID, "pin questions")
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return 0, uuid.Nil, pgstore.User{}, false
+	}
+
+	return roomID, messageID, user, true
+}
+
+func (h *Handlers) notifyMessagePinned(roomID int64, pinned types.MessagePinned) {
+	go h.WebsocketService.NotifyRoomClient(types.Message{
+		Kind:   types.MessageKindMessagePinned,
+		RoomID: roomID,
+		Value:  pinned,
+	})
+}
@@ -0,0 +1,67 @@
+package web
+
+import (
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/vhrboliveira/ama-go/internal/auth"
+	"github.com/vhrboliveira/ama-go/internal/service"
+	"github.com/vhrboliveira/ama-go/internal/store/pgstore"
+)
+
+var invalidExportFormatMessage = "format must be one of: " + strings.Join(service.ExportFormats, ", ")
+
+// ExportRoom streams the room and its questions as JSON, CSV or a Markdown
+// transcript, JSON being the default.
+func (h *Handlers) ExportRoom(w http.ResponseWriter, r *http.Request) {
+	rawRoomID := chi.URLParam(r, "room_id")
+	roomID, err := strconv.ParseInt(rawRoomID, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid room id", http.StatusBadRequest)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = service.ExportFormatJSON
+	}
+
+	if !slices.Contains(service.ExportFormats, format) {
+		http.Error(w, "validation failed: "+invalidExportFormatMessage, http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	user, ok := ctx.Value(auth.UserKey).(pgstore.User)
+	if !ok {
+		slog.Error("user not found on the session cookie")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	status, err := h.RoomService.CheckRoomModerator(ctx, roomID, user.ID, "export the room")
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	room, err := h.RoomService.GetRoom(ctx, roomID, user.ID)
+	if err != nil {
+		slog.Error("error getting room", "error", err)
+		http.Error(w, "error exporting room", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", service.ExportContentType(format))
+	w.Header().Set("Content-Disposition", `attachment; filename="`+room.Slug+"."+format+`"`)
+
+	// The room is written before its messages are read, so failures past this
+	// point can only cut the export short.
+	if err := h.RoomService.ExportRoom(ctx, w, room, format); err != nil {
+		slog.Error("error exporting room", "room_id", roomID, "format", format, "error", err)
+	}
+}
@@ -0,0 +1,136 @@
+package web
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/vhrboliveira/ama-go/internal/auth"
+	"github.com/vhrboliveira/ama-go/internal/service"
+	"github.com/vhrboliveira/ama-go/internal/store/pgstore"
+	"github.com/vhrboliveira/ama-go/internal/types"
+)
+
+func (h *Handlers) FollowRoom(w http.ResponseWriter, r *http.Request) {
+	rawRoomID := chi.URLParam(r, "room_id")
+	roomID, err := strconv.ParseInt(rawRoomID, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid room id", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	user, ok := ctx.Value(auth.UserKey).(pgstore.User)
+	if !ok {
+		slog.Error("user not found on the session cookie")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	status, err := h.RoomService.CheckRoomAccess(ctx, roomID, user.ID)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	if err := h.RoomService.FollowRoom(ctx, roomID, user.ID); err != nil {
+		slog.Error("error following room", "room_id", roomID, "error", err)
+		http.Error(w, "error following room", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UnfollowRoom needs no access to the room, so users who lost it can still
+// stop following it.
+func (h *Handlers) UnfollowRoom(w http.ResponseWriter, r *http.Request) {
+	rawRoomID := chi.URLParam(r, "room_id")
+	roomID, err := strconv.ParseInt(rawRoomID, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid room id", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	user, ok := ctx.Value(auth.UserKey).(pgstore.User)
+	if !ok {
+		slog.Error("user not found on the session cookie")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.RoomService.UnfollowRoom(ctx, roomID, user.ID); err != nil {
+		slog.Error("error unfollowing room", "room_id", roomID, "error", err)
+		http.Error(w, "error unfollowing room", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handlers) GetFollowedRooms(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, ok := ctx.Value(auth.UserKey).(pgstore.User)
+	if !ok {
+		slog.Error("user not found on the session cookie")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	rooms, err := h.RoomService.GetFollowedRooms(ctx, user.ID)
+	if err != nil {
+		slog.Error("error getting followed rooms", "error", err)
+		http.Error(w, "error getting followed rooms", http.StatusInternalServerError)
+		return
+	}
+
+	sendJSON(w, rooms)
+}
+
+func (h *Handlers) GetNotifications(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, ok := ctx.Value(auth.UserKey).(pgstore.User)
+	if !ok {
+		slog.Error("user not found on the session cookie")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	notifications, err := h.RoomService.GetUnreadNotifications(ctx, user.ID)
+	if err != nil {
+		slog.Error("error getting notifications", "error", err)
+		http.Error(w, "error getting notifications", http.StatusInternalServerError)
+		return
+	}
+
+	sendJSON(w, types.Notifications{Notifications: notifications})
+}
+
+// MarkNotificationsRead marks every unread notification of the user as read,
+// so they are no longer sent when the rooms list socket connects.
+func (h *Handlers) MarkNotificationsRead(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, ok := ctx.Value(auth.UserKey).(pgstore.User)
+	if !ok {
+		slog.Error("user not found on the session cookie")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if _, err := h.RoomService.MarkNotificationsRead(ctx, user.ID); err != nil {
+		slog.Error("error marking notifications as read", "error", err)
+		http.Error(w, "error marking notifications as read", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handlers) unreadNotifications(user pgstore.User) service.NotificationsFunc {
+	return func(ctx context.Context) ([]types.Notification, error) {
+		return h.RoomService.GetUnreadNotifications(ctx, user.ID)
+	}
+}
@@ -0,0 +1,89 @@
+package web
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/vhrboliveira/ama-go/internal/auth"
+	"github.com/vhrboliveira/ama-go/internal/service"
+	"github.com/vhrboliveira/ama-go/internal/store/pgstore"
+)
+
+const maxImportSize = 10 << 20
+
+var invalidImportFormatMessage = "format must be one of: " + strings.Join(service.ImportFormats, ", ")
+
+// ImportRoom creates a draft room of the session user from a JSON or CSV
+// export, JSON being the default. Importing it again adds the questions the
+// room is missing. Like any draft, the room is announced once it is opened.
+func (h *Handlers) ImportRoom(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		ID         int64  `json:"id"`
+		Name       string `json:"name"`
+		Slug       string `json:"slug"`
+		Status     string `json:"status"`
+		Visibility string `json:"visibility"`
+		CreatedAt  string `json:"created_at"`
+		Created    bool   `json:"created"`
+		Imported   int64  `json:"imported"`
+		Skipped    int64  `json:"skipped"`
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = service.ExportFormatJSON
+	}
+
+	if !slices.Contains(service.ImportFormats, format) {
+		http.Error(w, "validation failed: "+invalidImportFormatMessage, http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	user, ok := ctx.Value(auth.UserKey).(pgstore.User)
+	if !ok {
+		slog.Error("user not found on the session cookie")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	imported, err := service.ParseRoomImport(http.MaxBytesReader(w, r.Body, maxImportSize), format)
+	if err != nil {
+		slog.Error("invalid room import", "format", format, "error", err)
+		http.Error(w, "validation failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.ImportService.Import(ctx, user.ID, imported)
+	if err != nil {
+		if errors.Is(err, service.ErrRoomSlugTaken) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+
+		slog.Error("error importing room", "error", err)
+		http.Error(w, "error importing room", http.StatusInternalServerError)
+		return
+	}
+
+	room := result.Room
+
+	if result.Created {
+		w.WriteHeader(http.StatusCreated)
+	}
+	sendJSON(w, response{
+		ID:         room.ID,
+		Name:       room.Name,
+		Slug:       room.Slug,
+		Status:     room.Status,
+		Visibility: room.Visibility,
+		CreatedAt:  room.CreatedAt.Time.Format(time.RFC3339),
+		Created:    result.Created,
+		Imported:   result.Imported,
+		Skipped:    result.Skipped,
+	})
+}
@@ -0,0 +1,157 @@
+package web
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator"
+	"github.com/google/uuid"
+	"github.com/vhrboliveira/ama-go/internal/auth"
+	"github.com/vhrboliveira/ama-go/internal/service"
+	"github.com/vhrboliveira/ama-go/internal/store/pgstore"
+)
+
+var invalidRoleMessage = "Role must be one of: " + strings.Join(service.RoomMemberRoles, ", ")
+
+func (h *Handlers) GetRoomMembers(w http.ResponseWriter, r *http.Request) {
+	rawRoomID := chi.URLParam(r, "room_id")
+	roomID, err := strconv.ParseInt(rawRoomID, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid room id", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	user, ok := ctx.Value(auth.UserKey).(pgstore.User)
+	if !ok {
+		slog.Error("user not found on the session cookie")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	status, err := h.RoomService.CheckRoomAccess(ctx, roomID, user.ID)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	members, err := h.RoomService.GetMembers(ctx, roomID)
+	if err != nil {
+		slog.Error("error getting room members", "error", err)
+		http.Error(w, "error getting room members", http.StatusInternalServerError)
+		return
+	}
+
+	sendJSON(w, members)
+}
+
+// AddRoomMember gives a moderator or panelist role to the user with the given
+// email. Adding a user who is already a member changes their role.
+func (h *Handlers) AddRoomMember(w http.ResponseWriter, r *http.Request) {
+	type requestBody struct {
+		Email string `json:"email" validate:"required,email"`
+		Role  string `json:"role" validate:"required,oneof=moderator panelist"`
+	}
+
+	rawRoomID := chi.URLParam(r, "room_id")
+	roomID, err := strconv.ParseInt(rawRoomID, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid room id", http.StatusBadRequest)
+		return
+	}
+
+	var body requestBody
+	err = json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		slog.Error("failed to decode body", "error", err)
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	body.Email = strings.TrimSpace(body.Email)
+
+	if err := validator.New().Struct(&body); err != nil {
+		slog.Error("validation failed", "error", err)
+
+		missingFields := []string{}
+		for _, err := range err.(validator.ValidationErrors) {
+			switch err.Tag() {
+			case "required":
+				missingFields = append(missingFields, err.Field())
+			case "email":
+				http.Error(w, "validation failed: Email must be a valid email address", http.StatusBadRequest)
+				return
+			case "oneof":
+				http.Error(w, "validation failed: "+invalidRoleMessage, http.StatusBadRequest)
+				return
+			}
+		}
+
+		http.Error(w, "validation failed, missing required field(s): "+strings.Join(missingFields, ", "), http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	user, ok := ctx.Value(auth.UserKey).(pgstore.User)
+	if !ok {
+		slog.Error("user not found on the session cookie")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	status, err := h.RoomService.CheckRoomOwner(ctx, roomID, user.ID)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	member, status, err := h.RoomService.AddMember(ctx, roomID, body.Email, body.Role)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	sendJSON(w, member)
+}
+
+func (h *Handlers) RemoveRoomMember(w http.ResponseWriter, r *http.Request) {
+	rawRoomID := chi.URLParam(r, "room_id")
+	roomID, err := strconv.ParseInt(rawRoomID, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid room id", http.StatusBadRequest)
+		return
+	}
+
+	memberID, err := uuid.Parse(chi.URLParam(r, "user_id"))
+	if err != nil {
+		slog.Error("unable to parse member id", "error", err)
+		http.Error(w, "invalid user id", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	user, ok := ctx.Value(auth.UserKey).(pgstore.User)
+	if !ok {
+		slog.Error("user not found on the session cookie")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	status, err := h.RoomService.CheckRoomOwner(ctx, roomID, user.ID)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	status, err = h.RoomService.RemoveMember(ctx, roomID, memberID)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,143 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/vhrboliveira/ama-go/internal/auth"
+	"github.com/vhrboliveira/ama-go/internal/service"
+	"github.com/vhrboliveira/ama-go/internal/store/pgstore"
+	"github.com/vhrboliveira/ama-go/internal/types"
+)
+
+// visitorRoutes are the routes visitors without a session can read, in the
+// rooms that allow it, live through the room subscriptions too.
+var visitorRoutes = []string{
+	"/api/rooms/{room_id}",
+	"/api/rooms/{room_id}/messages",
+	"/api/rooms/{room_id}/messages/{message_id}",
+	"/subscribe/room/{room_id}",
+	"/subscribe/sse/room/{room_id}",
+}
+
+// AllowsVisitor reports whether an unauthenticated request can go on without
+// a user, which is only the case for reading a room that allows public reads.
+func (h *Handlers) AllowsVisitor(r *http.Request) bool {
+	if r.Method != http.MethodGet {
+		return false
+	}
+
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil || rctx.Routes == nil {
+		return false
+	}
+
+	match := chi.NewRouteContext()
+	if !rctx.Routes.Match(match, r.Method, r.URL.Path) {
+		return false
+	}
+
+	if !slices.Contains(visitorRoutes, strings.TrimSuffix(match.RoutePattern(), "/")) {
+		return false
+	}
+
+	return h.RoomService.AllowsVisitors(r.Context(), match.URLParam("room_id"))
+}
+
+func (h *Handlers) UpdateRoomSettings(w http.ResponseWriter, r *http.Request) {
+	type requestBody struct {
+		AnonymousQuestions *bool  `json:"anonymous_questions"`
+		MaxQuestionLength  *int32 `json:"max_question_length"`
+		SlowModeSeconds    *int32 `json:"slow_mode_seconds"`
+		ReactionsAllowed   *bool  `json:"reactions_allowed"`
+		PublicRead         *bool  `json:"public_read"`
+	}
+
+	rawRoomID := chi.URLParam(r, "room_id")
+	roomID, err := strconv.ParseInt(rawRoomID, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid room id", http.StatusBadRequest)
+		return
+	}
+
+	var body requestBody
+	err = json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		slog.Error("failed to decode body", "error", err)
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	if body == (requestBody{}) {
+		http.Error(
+			w,
+			"validation failed, missing required field(s): AnonymousQuestions, MaxQuestionLength, SlowModeSeconds, ReactionsAllowed or PublicRead",
+			http.StatusBadRequest,
+		)
+		return
+	}
+
+	if body.MaxQuestionLength != nil && (*body.MaxQuestionLength < 1 || *body.MaxQuestionLength > service.MaxQuestionLengthLimit) {
+		msg := fmt.Sprintf("validation failed: max_question_length must be between 1 and %d", service.MaxQuestionLengthLimit)
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	if body.SlowModeSeconds != nil && (*body.SlowModeSeconds < 0 || *body.SlowModeSeconds > service.MaxSlowModeSeconds) {
+		msg := fmt.Sprintf("validation failed: slow_mode_seconds must be between 0 and %d", service.MaxSlowModeSeconds)
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	user, ok := ctx.Value(auth.UserKey).(pgstore.User)
+	if !ok {
+		slog.Error("user not found on the session cookie")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	status, err := h.RoomService.CheckRoomOwner(ctx, roomID, user.ID)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	room, err := h.RoomService.UpdateSettings(ctx, roomID, service.RoomSettingsChanges{
+		AnonymousQuestions: body.AnonymousQuestions,
+		MaxQuestionLength:  body.MaxQuestionLength,
+		SlowModeSeconds:    body.SlowModeSeconds,
+		ReactionsAllowed:   body.ReactionsAllowed,
+		PublicRead:         body.PublicRead,
+	})
+	if err != nil {
+		slog.Error("error updating room settings", "error", err)
+		http.Error(w, "error updating room settings", http.StatusInternalServerError)
+		return
+	}
+
+	settings := types.RoomSettingsUpdated{
+		ID:                 room.ID,
+		AnonymousQuestions: room.AnonymousQuestions,
+		MaxQuestionLength:  room.MaxQuestionLength,
+		SlowModeSeconds:    room.SlowModeSeconds,
+		ReactionsAllowed:   room.ReactionsAllowed,
+		PublicRead:         room.PublicRead,
+		UpdatedAt:          room.UpdatedAt.Time.Format(time.RFC3339),
+	}
+
+	sendJSON(w, settings)
+
+	go h.WebsocketService.NotifyRoomClient(types.Message{
+		Kind:   types.MessageKindRoomSettingsUpdated,
+		RoomID: room.ID,
+		Value:  settings,
+	})
+}
@@ -0,0 +1,50 @@
+package web
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/vhrboliveira/ama-go/internal/service"
+)
+
+// ResolveRoomSlug lets the room routes take the room slug in place of the
+// room ID. The room_id URL param of a slug is replaced by the ID of its room,
+// so the handlers keep parsing IDs. When redirect is set, requests with a slug
+// the room used before are permanently redirected to its current slug.
+func (h *Handlers) ResolveRoomSlug(redirect bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rctx := chi.RouteContext(r.Context())
+			slug := chi.URLParam(r, "room_id")
+			if rctx == nil || !service.IsRoomSlug(slug) {
+				// IDs and invalid values are left for the handlers to check
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			room, status, err := h.RoomService.ResolveRoomSlug(r.Context(), slug)
+			if err != nil {
+				http.Error(w, err.Error(), status)
+				return
+			}
+
+			if redirect && room.Slug != slug {
+				target := *r.URL
+				target.Path = strings.Replace(r.URL.Path, "/rooms/"+slug, "/rooms/"+room.Slug, 1)
+				target.RawPath = ""
+				http.Redirect(w, r, target.String(), http.StatusPermanentRedirect)
+				return
+			}
+
+			for i, key := range rctx.URLParams.Keys {
+				if key == "room_id" {
+					rctx.URLParams.Values[i] = strconv.FormatInt(room.ID, 10)
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package web
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/vhrboliveira/ama-go/internal/auth"
+	"github.com/vhrboliveira/ama-go/internal/store/pgstore"
+)
+
+// GetRoomStats returns how the session of the room went. The median time to
+// answer is null until a question is answered, and the questions per minute
+// only list the minutes with questions.
+func (h *Handlers) GetRoomStats(w http.ResponseWriter, r *http.Request) {
+	type reactedMessage struct {
+		ID        string `json:"id"`
+		Message   string `json:"message"`
+		Reactions int64  `json:"reactions"`
+	}
+
+	type minuteQuestions struct {
+		Minute    string `json:"minute"`
+		Questions int64  `json:"questions"`
+	}
+
+	type response struct {
+		TotalQuestions        int64             `json:"total_questions"`
+		AnsweredQuestions     int64             `json:"answered_questions"`
+		UnansweredQuestions   int64             `json:"unanswered_questions"`
+		MedianSecondsToAnswer *float64          `json:"median_seconds_to_answer"`
+		UniqueAskers          int64             `json:"unique_askers"`
+		TotalReactions        int64             `json:"total_reactions"`
+		TopReacted            []reactedMessage  `json:"top_reacted"`
+		QuestionsPerMinute    []minuteQuestions `json:"questions_per_minute"`
+		UniqueReactors        int64             `json:"unique_reactors"`
+	}
+
+	rawRoomID := chi.URLParam(r, "room_id")
+	roomID, err := strconv.ParseInt(rawRoomID, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid room id", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	user, ok := ctx.Value(auth.UserKey).(pgstore.User)
+	if !ok {
+		slog.Error("user not found on the session cookie")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	status, err := h.RoomService.CheckRoomOwner(ctx, roomID, user.ID)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	stats, err := h.RoomService.GetStats(ctx, roomID)
+	if err != nil {
+		slog.Error("error getting room stats", "error", err)
+		http.Error(w, "error getting room stats", http.StatusInternalServerError)
+		return
+	}
+
+	result := response{
+		TotalQuestions:      stats.Totals.TotalQuestions,
+		AnsweredQuestions:   stats.Totals.AnsweredQuestions,
+		UnansweredQuestions: stats.Totals.UnansweredQuestions,
+		UniqueAskers:        stats.Totals.UniqueAskers,
+		TotalReactions:      stats.Totals.TotalReactions,
+		TopReacted:          make([]reactedMessage, 0, len(stats.TopReacted)),
+		QuestionsPerMinute:  make([]minuteQuestions, 0, len(stats.QuestionsPerMinute)),
+		UniqueReactors:      stats.Totals.UniqueReactors,
+	}
+
+	if stats.Totals.AnsweredQuestions > 0 {
+		result.MedianSecondsToAnswer = &stats.Totals.MedianSecondsToAnswer
+	}
+
+	for _, message := range stats.TopReacted {
+		result.TopReacted = append(result.TopReacted, reactedMessage{
+			ID:        message.ID.String(),
+			Message:   message.Message,
+			Reactions: message.ReactionCount,
+		})
+	}
+
+	for _, minute := range stats.QuestionsPerMinute {
+		result.QuestionsPerMinute = append(result.QuestionsPerMinute, minuteQuestions{
+			Minute:    minute.Minute.Time.Format(time.RFC3339),
+			Questions: minute.Questions,
+		})
+	}
+
+	sendJSON(w, result)
+}
@@ -0,0 +1,133 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator"
+	"github.com/vhrboliveira/ama-go/internal/auth"
+	"github.com/vhrboliveira/ama-go/internal/service"
+	"github.com/vhrboliveira/ama-go/internal/store/pgstore"
+)
+
+// validateRoomSchedule checks that the opening and closing times fit the
+// status and are still ahead, so the scheduler has something to do with them.
+func validateRoomSchedule(schedule service.RoomSchedule, now time.Time) error {
+	if schedule.Status == service.RoomStatusScheduled && schedule.OpensAt == nil {
+		return errors.New("opens_at is required when the status is scheduled")
+	}
+
+	if schedule.OpensAt != nil {
+		if schedule.Status != service.RoomStatusScheduled {
+			return errors.New("opens_at can only be set when the status is scheduled")
+		}
+
+		if !schedule.OpensAt.After(now) {
+			return errors.New("opens_at must be in the future")
+		}
+	}
+
+	if schedule.ClosesAt != nil {
+		if schedule.Status != service.RoomStatusScheduled && schedule.Status != service.RoomStatusOpen {
+			return errors.New("closes_at can only be set when the status is scheduled or open")
+		}
+
+		if !schedule.ClosesAt.After(now) {
+			return errors.New("closes_at must be in the future")
+		}
+
+		if schedule.OpensAt != nil && !schedule.ClosesAt.After(*schedule.OpensAt) {
+			return errors.New("closes_at must be after opens_at")
+		}
+	}
+
+	return nil
+}
+
+func formatTime(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+
+	formatted := t.UTC().Format(time.RFC3339)
+	return &formatted
+}
+
+// SetRoomStatus moves the room through its lifecycle. The opening and closing
+// times are replaced by the ones in the body, so leaving them out clears them.
+func (h *Handlers) SetRoomStatus(w http.ResponseWriter, r *http.Request) {
+	type requestBody struct {
+		Status   string     `json:"status" validate:"required,oneof=draft scheduled open closed archived"`
+		OpensAt  *time.Time `json:"opens_at"`
+		ClosesAt *time.Time `json:"closes_at"`
+	}
+
+	rawRoomID := chi.URLParam(r, "room_id")
+	roomID, err := strconv.ParseInt(rawRoomID, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid room id", http.StatusBadRequest)
+		return
+	}
+
+	var body requestBody
+	err = json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		slog.Error("failed to decode body", "error", err)
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	if err := validator.New().Struct(&body); err != nil {
+		slog.Error("validation failed", "error", err)
+
+		missingFields := []string{}
+		for _, err := range err.(validator.ValidationErrors) {
+			if err.Tag() == "oneof" {
+				http.Error(w, "validation failed: "+invalidStatusMessage, http.StatusBadRequest)
+				return
+			}
+
+			missingFields = append(missingFields, err.Field())
+		}
+
+		http.Error(w, "validation failed, missing required field(s): "+strings.Join(missingFields, ", "), http.StatusBadRequest)
+		return
+	}
+
+	schedule := service.RoomSchedule{Status: body.Status, OpensAt: body.OpensAt, ClosesAt: body.ClosesAt}
+	if err := validateRoomSchedule(schedule, time.Now()); err != nil {
+		http.Error(w, "validation failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	user, ok := ctx.Value(auth.UserKey).(pgstore.User)
+	if !ok {
+		slog.Error("user not found on the session cookie")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	status, err := h.RoomService.CheckRoomOwner(ctx, roomID, user.ID)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	room, err := h.RoomService.SetRoomStatus(ctx, roomID, schedule)
+	if err != nil {
+		slog.Error("error updating room status", "error", err)
+		http.Error(w, "error updating room status", http.StatusInternalServerError)
+		return
+	}
+
+	sendJSON(w, service.RoomStatusChangedValue(room))
+
+	go h.WebsocketService.NotifyRoomStatusChanged(room)
+}
@@ -0,0 +1,156 @@
+package web
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator"
+	"github.com/vhrboliveira/ama-go/internal/auth"
+	"github.com/vhrboliveira/ama-go/internal/store/pgstore"
+)
+
+// TransferRoom offers the room to the user with the given email. The room only
+// changes hands when that user accepts the transfer.
+func (h *Handlers) TransferRoom(w http.ResponseWriter, r *http.Request) {
+	type requestBody struct {
+		Email string `json:"email" validate:"required,email"`
+	}
+
+	rawRoomID := chi.URLParam(r, "room_id")
+	roomID, err := strconv.ParseInt(rawRoomID, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid room id", http.StatusBadRequest)
+		return
+	}
+
+	var body requestBody
+	err = json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		slog.Error("failed to decode body", "error", err)
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	body.Email = strings.TrimSpace(body.Email)
+
+	if err := validator.New().Struct(&body); err != nil {
+		slog.Error("validation failed", "error", err)
+
+		if body.Email == "" {
+			http.Error(w, "validation failed, missing required field(s): Email", http.StatusBadRequest)
+			return
+		}
+
+		http.Error(w, "validation failed: Email must be a valid email address", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	user, ok := ctx.Value(auth.UserKey).(pgstore.User)
+	if !ok {
+		slog.Error("user not found on the session cookie")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	status, err := h.RoomService.CheckRoomOwner(ctx, roomID, user.ID)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	transfer, status, err := h.RoomService.CreateTransfer(ctx, roomID, user.ID, body.Email)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	sendJSON(w, transfer)
+}
+
+// CancelRoomTransfer withdraws the pending transfer of the room, for the owner
+// who offered it, or declines it, for the user it was offered to.
+func (h *Handlers) CancelRoomTransfer(w http.ResponseWriter, r *http.Request) {
+	rawRoomID := chi.URLParam(r, "room_id")
+	roomID, err := strconv.ParseInt(rawRoomID, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid room id", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	user, ok := ctx.Value(auth.UserKey).(pgstore.User)
+	if !ok {
+		slog.Error("user not found on the session cookie")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	status, err := h.RoomService.CancelTransfer(ctx, roomID, user.ID)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AcceptRoomTransfer makes the session user the owner of the room offered to
+// them and tells the room and rooms list subscribers.
+func (h *Handlers) AcceptRoomTransfer(w http.ResponseWriter, r *http.Request) {
+	rawRoomID := chi.URLParam(r, "room_id")
+	roomID, err := strconv.ParseInt(rawRoomID, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid room id", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	user, ok := ctx.Value(auth.UserKey).(pgstore.User)
+	if !ok {
+		slog.Error("user not found on the session cookie")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	_, status, err := h.RoomService.AcceptTransfer(ctx, roomID, user.ID)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	room, err := h.RoomService.GetRoom(ctx, roomID, user.ID)
+	if err != nil {
+		slog.Error("error getting room", "error", err)
+		http.Error(w, "error getting room", http.StatusInternalServerError)
+		return
+	}
+
+	sendJSON(w, room)
+
+	go h.WebsocketService.NotifyRoomOwnerChanged(room)
+}
+
+// GetRoomTransfers lists the rooms offered to the session user.
+func (h *Handlers) GetRoomTransfers(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, ok := ctx.Value(auth.UserKey).(pgstore.User)
+	if !ok {
+		slog.Error("user not found on the session cookie")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	transfers, err := h.RoomService.GetIncomingTransfers(ctx, user.ID)
+	if err != nil {
+		slog.Error("error getting room transfers", "error", err)
+		http.Error(w, "error getting room transfers", http.StatusInternalServerError)
+		return
+	}
+
+	sendJSON(w, transfers)
+}
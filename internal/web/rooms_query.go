@@ -0,0 +1,83 @@
+package web
+
+import (
+	"errors"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vhrboliveira/ama-go/internal/service"
+)
+
+const (
+	defaultRoomsPageSize = 20
+	maxRoomsPageSize     = 100
+)
+
+// parseRoomsQuery reads the filters, the sort and the page of the rooms list
+// from the query string. Rooms are listed oldest first unless asked otherwise.
+func parseRoomsQuery(values url.Values) (service.RoomsQuery, error) {
+	query := service.RoomsQuery{
+		Search: strings.TrimSpace(values.Get("search")),
+		Sort:   service.RoomsSortOldest,
+		Cursor: values.Get("cursor"),
+		Limit:  defaultRoomsPageSize,
+	}
+
+	if rawLimit := values.Get("limit"); rawLimit != "" {
+		limit, err := strconv.Atoi(rawLimit)
+		if err != nil || limit < 1 || limit > maxRoomsPageSize {
+			return query, errors.New("invalid limit, it must be between 1 and " + strconv.Itoa(maxRoomsPageSize))
+		}
+		query.Limit = int32(limit)
+	}
+
+	if sort := values.Get("sort"); sort != "" {
+		if !slices.Contains(service.RoomsSorts, sort) {
+			return query, errors.New("invalid sort, it must be one of: " + strings.Join(service.RoomsSorts, ", "))
+		}
+		query.Sort = sort
+	}
+
+	if rawCreatorID := values.Get("creator_id"); rawCreatorID != "" {
+		creatorID, err := uuid.Parse(rawCreatorID)
+		if err != nil {
+			return query, errors.New("invalid creator id")
+		}
+		query.CreatorID = &creatorID
+	}
+
+	for _, param := range []struct {
+		name string
+		dst  **time.Time
+	}{
+		{name: "created_after", dst: &query.CreatedAfter},
+		{name: "created_before", dst: &query.CreatedBefore},
+	} {
+		raw := values.Get(param.name)
+		if raw == "" {
+			continue
+		}
+
+		date, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return query, errors.New("invalid " + param.name + " date, it must be in RFC 3339 format")
+		}
+		*param.dst = &date
+	}
+
+	return query, nil
+}
+
+// nextPageLink builds the Link header pointing at the page after the current
+// one, keeping every other query parameter of the request.
+func nextPageLink(current *url.URL, cursor string) string {
+	values := current.Query()
+	values.Set("cursor", cursor)
+
+	next := url.URL{Path: current.Path, RawQuery: values.Encode()}
+	return "<" + next.String() + `>; rel="next"`
+}
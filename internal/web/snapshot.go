@@ -0,0 +1,48 @@
+package web
+
+import (
+	"context"
+	"errors"
+
+	"github.com/vhrboliveira/ama-go/internal/service"
+	"github.com/vhrboliveira/ama-go/internal/store/pgstore"
+)
+
+// roomSnapshot is the room state sent as the first frame of a room
+// subscription: what GetRoom, GetRoomMessages and GetRoomMessagesReactions
+// return, in a single message.
+type roomSnapshot struct {
+	Room       pgstore.GetRoomWithUserRow   `json:"room"`
+	Messages   []pgstore.GetRoomMessagesRow `json:"messages"`
+	ReactedIDs []string                     `json:"reacted_ids"`
+}
+
+func (h *Handlers) roomSnapshot(user pgstore.User, roomID int64) service.SnapshotFunc {
+	return func(ctx context.Context) (any, error) {
+		room, err := h.RoomService.GetRoom(ctx, roomID, user.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		if room == (pgstore.GetRoomWithUserRow{}) {
+			return nil, errors.New("room not found")
+		}
+
+		canModerate := h.RoomService.CanModerateRoom(ctx, roomID, user.ID)
+		messages, err := h.MessageService.GetMessages(ctx, roomID, user.ID, canModerate)
+		if err != nil {
+			return nil, err
+		}
+
+		reactedIDs, err := h.MessageService.GetRoomMessagesReactions(ctx, roomID, user.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		if reactedIDs == nil {
+			reactedIDs = []string{}
+		}
+
+		return roomSnapshot{Room: room, Messages: messages, ReactedIDs: reactedIDs}, nil
+	}
+}
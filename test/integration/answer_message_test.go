@@ -63,7 +63,7 @@ func TestAnswerMessage(t *testing.T) {
 		body := parseResponseBody(t, response)
 		want := "the message has already been answered\n"
 
-		assert.Equal(t, http.StatusInternalServerError, response.StatusCode)
+		assert.Equal(t, http.StatusConflict, response.StatusCode)
 		assert.Equal(t, want, body)
 	})
 
@@ -80,6 +80,7 @@ func TestAnswerMessage(t *testing.T) {
 		ws, err := connectAuthenticatedWS(t, wsURL)
 		require.NoError(t, err)
 		defer ws.Close()
+		readWSMessageOfKind(t, ws, types.MessageKindRoomSnapshot)
 
 		answer := "This is the answer to this message"
 		payload := strings.NewReader(`{"user_id": "` + room.UserID.String() + `", "answer": "` + answer + `"}`)
@@ -258,6 +259,18 @@ func TestAnswerMessage(t *testing.T) {
 				setMessagesConstraintFailure(t)
 			},
 		},
+		{
+			name:               "returns an error if the user has no role in the room",
+			fn:                 execAuthenticatedRequest,
+			payload:            `{"user_id": "` + userID + `", "answer": "` + answer + `"}`,
+			expectedMessage:    "only the room owner, moderators and panelists can answer questions\n",
+			expectedStatusCode: http.StatusForbidden,
+			url:                baseURL + strconv.Itoa(int(room.ID)) + "/messages/" + fakeID + "/answer",
+			setConstraint: func(t *testing.T) {
+				otherUserID := createUser(t, "other@example.com", "other user", "google", "0987654321", "")
+				setRoomOwner(t, room.ID, otherUserID)
+			},
+		},
 		{
 			name:               "returns an error if fails to set message as answered",
 			fn:                 execAuthenticatedRequest,
@@ -0,0 +1,60 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vhrboliveira/ama-go/internal/service"
+	"github.com/vhrboliveira/ama-go/internal/types"
+)
+
+func TestValkeyBroadcaster(t *testing.T) {
+	t.Run("delivers published events to every listening instance", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		publisher := service.NewValkeyBroadcaster(ValkeyClient)
+		received := make(chan types.Message, 2)
+
+		for range 2 {
+			listener := service.NewValkeyBroadcaster(ValkeyClient)
+			listener.Listen(ctx, func(msg types.Message) {
+				received <- msg
+			})
+		}
+
+		// Give the subscriptions time to be established before publishing
+		time.Sleep(200 * time.Millisecond)
+
+		err := publisher.Publish(ctx, types.Message{
+			Kind:   types.MessageKindMessageAnswered,
+			RoomID: 42,
+			Topic:  types.RoomTopic(42),
+			Value:  types.MessageAnswered{ID: "message-id", Answer: "the answer"},
+		})
+		require.NoError(t, err)
+
+		for range 2 {
+			select {
+			case msg := <-received:
+				assert.Equal(t, "room:42", msg.Topic)
+				assert.Equal(t, types.MessageKindMessageAnswered, msg.Kind)
+				assert.Equal(t, int64(42), msg.RoomID)
+
+				value, ok := msg.Value.(json.RawMessage)
+				require.True(t, ok, "expected the event value to be raw JSON")
+
+				var answered types.MessageAnswered
+				require.NoError(t, json.Unmarshal(value, &answered))
+				assert.Equal(t, "message-id", answered.ID)
+				assert.Equal(t, "the answer", answered.Answer)
+			case <-time.After(2 * time.Second):
+				t.Fatal("timed out waiting for the broadcast event")
+			}
+		}
+	})
+}
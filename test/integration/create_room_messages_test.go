@@ -59,6 +59,7 @@ func TestCreateRoomMessages(t *testing.T) {
 		ws, err := connectAuthenticatedWS(t, wsURL)
 		require.NoError(t, err)
 		defer ws.Close()
+		readWSMessageOfKind(t, ws, types.MessageKindRoomSnapshot)
 
 		want := "Is Go awesome?"
 		newURL := strings.Replace(baseURL, "room_id", strconv.Itoa(int(room.ID)), 1)
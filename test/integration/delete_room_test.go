@@ -0,0 +1,196 @@
+package api_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vhrboliveira/ama-go/internal/types"
+)
+
+func TestDeleteRoom(t *testing.T) {
+	type customFn func(t testing.TB, method string, url string, body io.Reader) *httptest.ResponseRecorder
+
+	const (
+		baseURL = "/api/rooms/"
+		method  = http.MethodDelete
+	)
+
+	t.Run("deletes the room and its messages", func(t *testing.T) {
+		truncateData(t)
+
+		room := createAndGetRoom(t)
+		createAndGetMessages(t, room.ID)
+
+		rr := execAuthenticatedRequest(t, method, baseURL+strconv.Itoa(int(room.ID)), nil)
+		response := rr.Result()
+		defer response.Body.Close()
+
+		assert.Equal(t, http.StatusNoContent, response.StatusCode)
+
+		rr = execAuthenticatedRequest(t, http.MethodGet, baseURL+strconv.Itoa(int(room.ID)), nil)
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		assert.Equal(t, "room not found\n", rr.Body.String())
+	})
+
+	t.Run("notifies the rooms list and closes the room subscriptions", func(t *testing.T) {
+		truncateData(t)
+
+		server := httptest.NewServer(Router)
+		defer server.Close()
+
+		room := createAndGetRoom(t)
+		wsURL := "ws" + server.URL[4:]
+
+		roomsWS, err := connectAuthenticatedWS(t, wsURL+"/subscribe")
+		require.NoError(t, err)
+		defer roomsWS.Close()
+		waitForTopicSubscribers(t, types.TopicRooms, 1)
+
+		roomWS, err := connectAuthenticatedWS(t, wsURL+"/subscribe/room/"+strconv.Itoa(int(room.ID)))
+		require.NoError(t, err)
+		defer roomWS.Close()
+		readWSMessageOfKind(t, roomWS, types.MessageKindRoomSnapshot)
+
+		rr := execAuthenticatedRequest(t, method, baseURL+strconv.Itoa(int(room.ID)), nil)
+		require.Equal(t, http.StatusNoContent, rr.Code)
+
+		msg := readWSMessageOfKind(t, roomsWS, types.MessageKindRoomDeleted)
+		var roomDeleted types.RoomDeleted
+		decodeMessageValue(t, msg, &roomDeleted)
+		assert.Equal(t, room.ID, roomDeleted.ID)
+
+		msg = readWSMessageOfKind(t, roomWS, types.MessageKindRoomDeleted)
+		assert.Equal(t, types.RoomTopic(room.ID), msg.Topic)
+
+		require.NoError(t, roomWS.SetReadDeadline(time.Now().Add(2*time.Second)))
+		_, _, err = roomWS.ReadMessage()
+		assert.True(t, websocket.IsCloseError(err, websocket.CloseNormalClosure))
+
+		waitForRoomSubscribers(t, room.ID, 0)
+
+		assert.Eventually(t, func() bool {
+			seq, err := Handler.WebsocketService.Events.Current(context.Background(), room.ID)
+			return err == nil && seq == 0
+		}, 2*time.Second, 10*time.Millisecond, "the room event log is dropped")
+	})
+
+	t.Run("does not tell the rooms list about rooms that are not public", func(t *testing.T) {
+		truncateData(t)
+
+		server := httptest.NewServer(Router)
+		defer server.Close()
+
+		room := createAndGetRoom(t)
+		setRoomSetting(t, room.ID, "visibility", "private")
+
+		roomsWS, err := connectAuthenticatedWS(t, "ws"+server.URL[4:]+"/subscribe")
+		require.NoError(t, err)
+		defer roomsWS.Close()
+		waitForTopicSubscribers(t, types.TopicRooms, 1)
+
+		rr := execAuthenticatedRequest(t, method, baseURL+strconv.Itoa(int(room.ID)), nil)
+		require.Equal(t, http.StatusNoContent, rr.Code)
+
+		require.NoError(t, roomsWS.SetReadDeadline(time.Now().Add(500*time.Millisecond)))
+		_, _, err = roomsWS.ReadMessage()
+		var netErr net.Error
+		assert.True(t, errors.As(err, &netErr) && netErr.Timeout(), "the rooms list must not hear about the room")
+	})
+
+	fakeRoomID := "999999"
+
+	truncateData(t)
+	room := createAndGetRoom(t)
+	roomURL := baseURL + strconv.Itoa(int(room.ID))
+
+	errorTestCases := []struct {
+		name               string
+		fn                 customFn
+		expectedMessage    string
+		expectedStatusCode int
+		url                string
+		setConstraint      func(t *testing.T)
+	}{
+		{
+			name: "returns unauthorized error if sessionID is not found",
+			fn: func(t testing.TB, method, url string, body io.Reader) *httptest.ResponseRecorder {
+				return execRequestWithoutCookie(method, url, body)
+			},
+			expectedMessage:    "unauthorized, session not found or invalid\n",
+			expectedStatusCode: http.StatusUnauthorized,
+			url:                roomURL,
+		},
+		{
+			name: "returns unauthorized error if cookie is different from the session",
+			fn: func(t testing.TB, method, url string, body io.Reader) *httptest.ResponseRecorder {
+				return execRequestWithInvalidCookie(method, url, body)
+			},
+			expectedMessage:    "unauthorized, session not found or invalid\n",
+			expectedStatusCode: http.StatusUnauthorized,
+			url:                roomURL,
+		},
+		{
+			name:               "returns an error if room id is not valid",
+			fn:                 execAuthenticatedRequest,
+			expectedMessage:    "invalid room id\n",
+			expectedStatusCode: http.StatusBadRequest,
+			url:                baseURL + "invalid_room_id",
+		},
+		{
+			name:               "returns an error if room does not exist",
+			fn:                 execAuthenticatedRequest,
+			expectedMessage:    "room not found\n",
+			expectedStatusCode: http.StatusBadRequest,
+			url:                baseURL + fakeRoomID,
+		},
+		{
+			name:               "returns an error if the user is not the room owner",
+			fn:                 execAuthenticatedRequest,
+			expectedMessage:    "only the room owner can change the room\n",
+			expectedStatusCode: http.StatusForbidden,
+			url:                roomURL,
+			setConstraint: func(t *testing.T) {
+				otherUserID := createUser(t, "other@example.com", "other user", "google", "0987654321", "")
+				setRoomOwner(t, room.ID, otherUserID)
+			},
+		},
+		{
+			name:               "returns an error if fails to get room",
+			fn:                 execAuthenticatedRequest,
+			expectedMessage:    "error validating room ID\n",
+			expectedStatusCode: http.StatusInternalServerError,
+			url:                roomURL,
+			setConstraint: func(t *testing.T) {
+				setRoomsConstraintFailure(t)
+			},
+		},
+	}
+
+	for _, tc := range errorTestCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.setConstraint != nil {
+				tc.setConstraint(t)
+			}
+
+			rr := tc.fn(t, method, tc.url, strings.NewReader(""))
+			response := rr.Result()
+			defer response.Body.Close()
+
+			body := parseResponseBody(t, response)
+
+			assert.Equal(t, tc.expectedStatusCode, response.StatusCode)
+			assert.Equal(t, tc.expectedMessage, body)
+		})
+	}
+}
@@ -0,0 +1,304 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vhrboliveira/ama-go/internal/store/pgstore"
+)
+
+func setRoomDates(t testing.TB, roomID int64, createdAt time.Time) {
+	t.Helper()
+
+	_, err := DBPool.Exec(context.Background(), "UPDATE rooms SET created_at = $1, updated_at = $1 WHERE id = $2", createdAt, roomID)
+	require.NoError(t, err)
+}
+
+func setRoomMessagesUpdatedAt(t testing.TB, roomID int64, updatedAt time.Time) {
+	t.Helper()
+
+	_, err := DBPool.Exec(context.Background(), "UPDATE messages SET updated_at = $1 WHERE room_id = $2", updatedAt, roomID)
+	require.NoError(t, err)
+}
+
+func TestGetRoomsListing(t *testing.T) {
+	const baseURL = "/api/rooms"
+
+	type listedRoom struct {
+		ID           int64  `json:"id"`
+		Name         string `json:"name"`
+		Description  string `json:"description"`
+		MessageCount int64  `json:"message_count"`
+	}
+
+	getPage := func(t *testing.T, url string) ([]listedRoom, string) {
+		t.Helper()
+
+		rr := execAuthenticatedRequest(t, http.MethodGet, url, nil)
+		response := rr.Result()
+		defer response.Body.Close()
+		require.Equal(t, http.StatusOK, response.StatusCode)
+
+		var rooms []listedRoom
+		require.NoError(t, json.NewDecoder(response.Body).Decode(&rooms))
+
+		next := ""
+		if link := response.Header.Get("Link"); link != "" {
+			require.True(t, strings.HasSuffix(link, `>; rel="next"`), "unexpected Link header: %s", link)
+			next = link[1:strings.Index(link, ">")]
+		}
+
+		return rooms, next
+	}
+
+	names := func(rooms []listedRoom) []string {
+		result := make([]string, 0, len(rooms))
+		for _, room := range rooms {
+			result = append(result, room.Name)
+		}
+		return result
+	}
+
+	// setupRooms creates three rooms a day apart, where "third" has the most
+	// questions and "first" the latest activity.
+	setupRooms := func(t *testing.T) map[string]pgstore.Room {
+		t.Helper()
+		truncateData(t)
+
+		createRooms(t, []string{"first", "second", "third"})
+		now := time.Now().UTC()
+
+		rooms := make(map[string]pgstore.Room)
+		for i, name := range []string{"first", "second", "third"} {
+			room := getRoomByName(t, name)
+			setRoomDates(t, room.ID, now.Add(-time.Duration(i+1)*24*time.Hour))
+			rooms[name] = room
+		}
+
+		insertMessages(t, []pgstore.InsertMessageParams{
+			{RoomID: rooms["third"].ID, Message: "first question"},
+			{RoomID: rooms["third"].ID, Message: "second question"},
+			{RoomID: rooms["first"].ID, Message: "third question"},
+		})
+		setRoomMessagesUpdatedAt(t, rooms["third"].ID, now.Add(-2*time.Hour))
+		setRoomMessagesUpdatedAt(t, rooms["first"].ID, now.Add(-time.Hour))
+
+		return rooms
+	}
+
+	t.Run("follows the next cursor until the last page", func(t *testing.T) {
+		truncateData(t)
+
+		createRooms(t, []string{"room 1", "room 2", "room 3", "room 4", "room 5"})
+
+		var listed []string
+		pages := 0
+		next := baseURL + "?limit=2"
+		for next != "" {
+			var rooms []listedRoom
+			rooms, next = getPage(t, next)
+			listed = append(listed, names(rooms)...)
+			pages++
+		}
+
+		assert.Equal(t, 3, pages)
+		assert.Equal(t, []string{"room 1", "room 2", "room 3", "room 4", "room 5"}, listed)
+	})
+
+	t.Run("keeps the filters and the sort on the next page", func(t *testing.T) {
+		truncateData(t)
+
+		createRooms(t, []string{"go 1", "rust", "go 2", "go 3"})
+
+		rooms, next := getPage(t, baseURL+"?search=go&sort=newest&limit=2")
+		assert.Equal(t, []string{"go 3", "go 2"}, names(rooms))
+		require.NotEmpty(t, next)
+
+		link, err := url.Parse(next)
+		require.NoError(t, err)
+		assert.Equal(t, "go", link.Query().Get("search"))
+		assert.Equal(t, "newest", link.Query().Get("sort"))
+
+		rooms, next = getPage(t, next)
+		assert.Equal(t, []string{"go 1"}, names(rooms))
+		assert.Empty(t, next)
+	})
+
+	t.Run("sorts the rooms", func(t *testing.T) {
+		setupRooms(t)
+
+		tests := []struct {
+			sort     string
+			expected []string
+		}{
+			{sort: "", expected: []string{"third", "second", "first"}},
+			{sort: "oldest", expected: []string{"third", "second", "first"}},
+			{sort: "newest", expected: []string{"first", "second", "third"}},
+			{sort: "most_questions", expected: []string{"third", "first", "second"}},
+			{sort: "recent_activity", expected: []string{"first", "third", "second"}},
+		}
+
+		for _, tt := range tests {
+			t.Run("sort="+tt.sort, func(t *testing.T) {
+				var listed []string
+				next := baseURL + "?limit=1&sort=" + tt.sort
+				for next != "" {
+					var rooms []listedRoom
+					rooms, next = getPage(t, next)
+					listed = append(listed, names(rooms)...)
+				}
+
+				assert.Equal(t, tt.expected, listed)
+			})
+		}
+	})
+
+	t.Run("counts the questions of each room", func(t *testing.T) {
+		setupRooms(t)
+
+		rooms, _ := getPage(t, baseURL+"?sort=most_questions")
+		require.Len(t, rooms, 3)
+		assert.Equal(t, []int64{2, 1, 0}, []int64{rooms[0].MessageCount, rooms[1].MessageCount, rooms[2].MessageCount})
+	})
+
+	t.Run("filters the rooms", func(t *testing.T) {
+		rooms := setupRooms(t)
+		now := time.Now().UTC()
+
+		_, err := DBPool.Exec(context.Background(), "UPDATE rooms SET description = 'all about 100% Go' WHERE id = $1", rooms["second"].ID)
+		require.NoError(t, err)
+
+		otherUserID := createUser(t, "other@example.com", "other user", "google", "0987654321", "")
+		setRoomOwner(t, rooms["third"].ID, otherUserID)
+
+		tests := []struct {
+			name     string
+			query    url.Values
+			expected []string
+		}{
+			{
+				name:     "by name",
+				query:    url.Values{"search": {"FIR"}},
+				expected: []string{"first"},
+			},
+			{
+				name:     "by description",
+				query:    url.Values{"search": {"go"}},
+				expected: []string{"second"},
+			},
+			{
+				name:     "matching wildcards literally",
+				query:    url.Values{"search": {"100%"}},
+				expected: []string{"second"},
+			},
+			{
+				name:     "by creator",
+				query:    url.Values{"creator_id": {otherUserID}},
+				expected: []string{"third"},
+			},
+			{
+				name:     "created after a date",
+				query:    url.Values{"created_after": {now.Add(-36 * time.Hour).Format(time.RFC3339)}},
+				expected: []string{"first"},
+			},
+			{
+				name:     "created before a date",
+				query:    url.Values{"created_before": {now.Add(-36 * time.Hour).Format(time.RFC3339)}},
+				expected: []string{"third", "second"},
+			},
+			{
+				name: "created in a date range",
+				query: url.Values{
+					"created_after":  {now.Add(-60 * time.Hour).Format(time.RFC3339)},
+					"created_before": {now.Add(-36 * time.Hour).Format(time.RFC3339)},
+				},
+				expected: []string{"second"},
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				rooms, next := getPage(t, baseURL+"?"+tt.query.Encode())
+				assert.Equal(t, tt.expected, names(rooms))
+				assert.Empty(t, next)
+			})
+		}
+	})
+
+	t.Run("returns an error if the query is not valid", func(t *testing.T) {
+		setupRooms(t)
+
+		_, next := getPage(t, baseURL+"?limit=1&sort=newest")
+		require.NotEmpty(t, next)
+		link, err := url.Parse(next)
+		require.NoError(t, err)
+		newestCursor := link.Query().Get("cursor")
+
+		tests := []struct {
+			name            string
+			query           string
+			expectedMessage string
+		}{
+			{
+				name:            "limit is not a number",
+				query:           "limit=abc",
+				expectedMessage: "invalid limit, it must be between 1 and 100\n",
+			},
+			{
+				name:            "limit is too large",
+				query:           "limit=101",
+				expectedMessage: "invalid limit, it must be between 1 and 100\n",
+			},
+			{
+				name:            "sort is unknown",
+				query:           "sort=popular",
+				expectedMessage: "invalid sort, it must be one of: newest, oldest, most_questions, recent_activity\n",
+			},
+			{
+				name:            "creator id is not a valid UUID",
+				query:           "creator_id=invalid",
+				expectedMessage: "invalid creator id\n",
+			},
+			{
+				name:            "created_after is not a valid date",
+				query:           "created_after=yesterday",
+				expectedMessage: "invalid created_after date, it must be in RFC 3339 format\n",
+			},
+			{
+				name:            "created_before is not a valid date",
+				query:           "created_before=2024-01-01",
+				expectedMessage: "invalid created_before date, it must be in RFC 3339 format\n",
+			},
+			{
+				name:            "cursor is malformed",
+				query:           "cursor=not-a-cursor",
+				expectedMessage: "invalid cursor\n",
+			},
+			{
+				name:            "cursor was created for another sort",
+				query:           "sort=oldest&cursor=" + newestCursor,
+				expectedMessage: "invalid cursor\n",
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				rr := execAuthenticatedRequest(t, http.MethodGet, baseURL+"?"+tt.query, nil)
+				response := rr.Result()
+				defer response.Body.Close()
+
+				body := parseResponseBody(t, response)
+
+				assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+				assert.Equal(t, tt.expectedMessage, body)
+			})
+		}
+	})
+}
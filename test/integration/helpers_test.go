@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/gob"
+	"encoding/json"
 	"errors"
 	"io"
 	"net/http"
@@ -19,7 +20,9 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/vhrboliveira/ama-go/internal/auth"
+	"github.com/vhrboliveira/ama-go/internal/service"
 	"github.com/vhrboliveira/ama-go/internal/store/pgstore"
+	"github.com/vhrboliveira/ama-go/internal/types"
 )
 
 func execAuthenticatedRequest(t testing.TB, method, url string, body io.Reader) *httptest.ResponseRecorder {
@@ -150,6 +153,12 @@ func connectAuthenticatedWS(t testing.TB, wsURL string) (*websocket.Conn, error)
 func connectWSWithUserSession(t testing.TB, wsURL string, userID *string) (*websocket.Conn, *http.Response, error) {
 	t.Helper()
 
+	return connectWSWithDialer(t, websocket.DefaultDialer, wsURL, userID)
+}
+
+func connectWSWithDialer(t testing.TB, dialer *websocket.Dialer, wsURL string, userID *string) (*websocket.Conn, *http.Response, error) {
+	t.Helper()
+
 	gothUser := mockGothUser(nil)
 	gothUser.UserID = *userID
 
@@ -172,7 +181,7 @@ func connectWSWithUserSession(t testing.TB, wsURL string, userID *string) (*webs
 	headers := http.Header{}
 	headers.Add("Cookie", values[0])
 
-	wsConn, res, err := websocket.DefaultDialer.Dial(wsURL, headers)
+	wsConn, res, err := dialer.Dial(wsURL, headers)
 
 	return wsConn, res, err
 }
@@ -249,11 +258,11 @@ func createRooms(t testing.TB, names []string) {
 
 	defer tx.Rollback(ctx)
 
-	stmt := `INSERT INTO rooms (name, user_id) VALUES ($1, $2)`
+	stmt := `INSERT INTO rooms (name, user_id, slug) VALUES ($1, $2, $3)`
 
 	batch := &pgx.Batch{}
 	for _, name := range names {
-		batch.Queue(stmt, name, userID)
+		batch.Queue(stmt, name, userID, service.Slugify(name)+"-"+uuid.NewString()[:8])
 	}
 
 	bx := tx.SendBatch(ctx, batch)
@@ -484,3 +493,101 @@ func getValkeyData(t testing.TB, sessionID string) pgstore.User {
 
 	return userSessionValues
 }
+
+func waitForRoomSubscribers(t testing.TB, roomID int64, count int) {
+	t.Helper()
+
+	waitForTopicSubscribers(t, types.RoomTopic(roomID), count)
+}
+
+func waitForTopicSubscribers(t testing.TB, topic string, count int) {
+	t.Helper()
+
+	require.Eventually(t, func() bool {
+		Handler.WebsocketService.Mutex.RLock()
+		defer Handler.WebsocketService.Mutex.RUnlock()
+
+		return len(Handler.WebsocketService.Subscribers[topic]) == count
+	}, 2*time.Second, 10*time.Millisecond, "topic subscribers were not registered")
+}
+
+func readWSMessage(t testing.TB, ws *websocket.Conn) types.Message {
+	t.Helper()
+
+	ws.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, p, err := ws.ReadMessage()
+	require.NoError(t, err, "failed to read websocket message")
+
+	var msg types.Message
+	require.NoError(t, json.Unmarshal(p, &msg), "failed to unmarshal websocket message")
+
+	return msg
+}
+
+func getSessionCookie(t testing.TB, userID string) string {
+	t.Helper()
+
+	gothUser := mockGothUser(nil)
+	gothUser.UserID = userID
+
+	gothic.GetProviderName = func(req *http.Request) (string, error) {
+		return "google", nil
+	}
+	gothic.CompleteUserAuth = func(w http.ResponseWriter, r *http.Request) (goth.User, error) {
+		return gothUser, nil
+	}
+
+	r := httptest.NewRequest("GET", "/auth/google/callback", nil)
+	rr := httptest.NewRecorder()
+
+	Router.ServeHTTP(rr, r)
+
+	response := rr.Result()
+	defer response.Body.Close()
+
+	values := response.Header.Values("Set-Cookie")
+	require.NotEmpty(t, values, "session cookie not set")
+
+	return values[0]
+}
+
+func readWSMessageOfKind(t testing.TB, ws *websocket.Conn, kind string) types.Message {
+	t.Helper()
+
+	for {
+		msg := readWSMessage(t, ws)
+		if msg.Kind == kind {
+			return msg
+		}
+	}
+}
+
+func sendWSCommand(t testing.TB, ws *websocket.Conn, kind, requestID string, value any) {
+	t.Helper()
+
+	rawValue, err := json.Marshal(value)
+	require.NoError(t, err)
+	require.NoError(t, ws.WriteJSON(types.Command{Kind: kind, RequestID: requestID, Value: rawValue}))
+}
+
+func decodeMessageValue(t testing.TB, msg types.Message, dst any) {
+	t.Helper()
+
+	jsonBytes, err := json.Marshal(msg.Value)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(jsonBytes, dst))
+}
+
+func createOtherUser(t testing.TB, email string) pgstore.User {
+	t.Helper()
+
+	id := createUser(t, email, "Other User", "google", "0987654321", "")
+	return pgstore.User{ID: uuid.MustParse(id), Email: email, Name: "Other User"}
+}
+
+func setRoomVisibility(t testing.TB, roomID int64, visibility string) {
+	t.Helper()
+
+	_, err := DBPool.Exec(context.Background(), "UPDATE rooms SET visibility = $1 WHERE id = $2", visibility, roomID)
+	require.NoError(t, err)
+}
@@ -0,0 +1,141 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vhrboliveira/ama-go/internal/store/pgstore"
+	"github.com/vhrboliveira/ama-go/internal/types"
+)
+
+func TestMessageAuthors(t *testing.T) {
+	const baseURL = "/api/rooms/"
+
+	askQuestion := func(t *testing.T, roomURL, payload string) string {
+		t.Helper()
+
+		rr := execAuthenticatedRequest(t, http.MethodPost, roomURL+"/messages", strings.NewReader(payload))
+		require.Equal(t, http.StatusCreated, rr.Code)
+
+		var body struct {
+			ID string `json:"id"`
+		}
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&body))
+		return body.ID
+	}
+
+	getMessages := func(t *testing.T, roomURL string, viewer *pgstore.User) []pgstore.GetRoomMessagesRow {
+		t.Helper()
+
+		var rr *httptest.ResponseRecorder
+		if viewer == nil {
+			rr = execAuthenticatedRequest(t, http.MethodGet, roomURL+"/messages", nil)
+		} else {
+			rr = execRequestGeneratingSession(t, http.MethodGet, roomURL+"/messages", nil, viewer)
+		}
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var messages []pgstore.GetRoomMessagesRow
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&messages))
+		return messages
+	}
+
+	t.Run("shows the author of the questions to everyone", func(t *testing.T) {
+		truncateData(t)
+
+		server := httptest.NewServer(Router)
+		defer server.Close()
+
+		room := createAndGetRoom(t)
+		roomURL := baseURL + strconv.Itoa(int(room.ID))
+		viewer := createOtherUser(t, "viewer@example.com")
+
+		ws, err := connectAuthenticatedWS(t, "ws"+server.URL[4:]+"/subscribe/room/"+strconv.Itoa(int(room.ID)))
+		require.NoError(t, err)
+		defer ws.Close()
+		readWSMessageOfKind(t, ws, types.MessageKindRoomSnapshot)
+
+		msgID := askQuestion(t, roomURL, `{"message": "who asked this?"}`)
+		authorID := generateUser(t)
+
+		msg := readWSMessageOfKind(t, ws, types.MessageKindMessageCreated)
+		var created types.MessageCreated
+		decodeMessageValue(t, msg, &created)
+		assert.Equal(t, msgID, created.ID)
+		assert.False(t, created.Anonymous)
+		require.NotNil(t, created.AuthorID)
+		assert.Equal(t, authorID, *created.AuthorID)
+		require.NotNil(t, created.AuthorName)
+		assert.Equal(t, "Test User", *created.AuthorName)
+		require.NotNil(t, created.AuthorPhoto)
+		assert.Equal(t, "http://avatar.com/test.jpg", *created.AuthorPhoto)
+
+		messages := getMessages(t, roomURL, &viewer)
+		require.Len(t, messages, 1)
+		assert.False(t, messages[0].Anonymous)
+		assert.Equal(t, authorID, uuid.UUID(messages[0].AuthorID.Bytes).String())
+		assert.Equal(t, "Test User", messages[0].AuthorName.String)
+		assert.Equal(t, "http://avatar.com/test.jpg", messages[0].AuthorPhoto.String)
+	})
+
+	t.Run("hides the author of anonymous questions from everyone but them and the room hosts", func(t *testing.T) {
+		truncateData(t)
+
+		server := httptest.NewServer(Router)
+		defer server.Close()
+
+		room := createAndGetRoom(t)
+		roomURL := baseURL + strconv.Itoa(int(room.ID))
+		owner := createOtherUser(t, "owner@example.com")
+		setRoomOwner(t, room.ID, owner.ID.String())
+		viewer := createOtherUser(t, "viewer@example.com")
+
+		ws, err := connectAuthenticatedWS(t, "ws"+server.URL[4:]+"/subscribe/room/"+strconv.Itoa(int(room.ID)))
+		require.NoError(t, err)
+		defer ws.Close()
+		readWSMessageOfKind(t, ws, types.MessageKindRoomSnapshot)
+
+		msgID := askQuestion(t, roomURL, `{"message": "guess who", "anonymous": true}`)
+		authorID := generateUser(t)
+
+		msg := readWSMessageOfKind(t, ws, types.MessageKindMessageCreated)
+		var created types.MessageCreated
+		decodeMessageValue(t, msg, &created)
+		assert.Equal(t, types.MessageCreated{ID: msgID, CreatedAt: created.CreatedAt, Message: "guess who", Anonymous: true}, created)
+
+		messages := getMessages(t, roomURL, &viewer)
+		require.Len(t, messages, 1)
+		assert.True(t, messages[0].Anonymous)
+		assert.False(t, messages[0].AuthorID.Valid)
+		assert.False(t, messages[0].AuthorName.Valid)
+		assert.False(t, messages[0].AuthorPhoto.Valid)
+
+		for _, messages := range [][]pgstore.GetRoomMessagesRow{getMessages(t, roomURL, nil), getMessages(t, roomURL, &owner)} {
+			require.Len(t, messages, 1)
+			assert.True(t, messages[0].Anonymous)
+			assert.Equal(t, authorID, uuid.UUID(messages[0].AuthorID.Bytes).String())
+			assert.Equal(t, "Test User", messages[0].AuthorName.String)
+		}
+
+		rr := execRequestGeneratingSession(t, http.MethodGet, roomURL+"/messages/"+msgID, nil, &viewer)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var message pgstore.Message
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&message))
+		assert.True(t, message.Anonymous)
+		assert.False(t, message.UserID.Valid)
+
+		rr = execRequestGeneratingSession(t, http.MethodGet, roomURL+"/messages/"+msgID, nil, &owner)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&message))
+		assert.Equal(t, authorID, uuid.UUID(message.UserID.Bytes).String())
+	})
+}
@@ -0,0 +1,279 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vhrboliveira/ama-go/internal/store/pgstore"
+	"github.com/vhrboliveira/ama-go/internal/types"
+)
+
+// askOwnQuestion posts a question as the test user and returns its ID.
+func askOwnQuestion(t testing.TB, roomID int64, message string) string {
+	t.Helper()
+
+	url := "/api/rooms/" + strconv.Itoa(int(roomID)) + "/messages"
+	rr := execAuthenticatedRequest(t, http.MethodPost, url, strings.NewReader(`{"message": "`+message+`"}`))
+	require.Equal(t, http.StatusCreated, rr.Code)
+
+	var body struct {
+		ID string `json:"id"`
+	}
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&body))
+
+	return body.ID
+}
+
+func setMessageColumn(t testing.TB, messageID, column string, value any) {
+	t.Helper()
+
+	_, err := DBPool.Exec(context.Background(), "UPDATE messages SET "+column+" = $1 WHERE id = $2", value, messageID)
+	require.NoError(t, err)
+}
+
+func TestMessageEdits(t *testing.T) {
+	type customFn func(t testing.TB, method string, url string, body io.Reader) *httptest.ResponseRecorder
+
+	const baseURL = "/api/rooms/"
+
+	t.Run("updates the question and notifies the room subscribers", func(t *testing.T) {
+		truncateData(t)
+
+		server := httptest.NewServer(Router)
+		defer server.Close()
+
+		room := createAndGetRoom(t)
+		roomURL := baseURL + strconv.Itoa(int(room.ID))
+		msgID := askOwnQuestion(t, room.ID, "wht is go?")
+
+		ws, err := connectAuthenticatedWS(t, "ws"+server.URL[4:]+"/subscribe/room/"+strconv.Itoa(int(room.ID)))
+		require.NoError(t, err)
+		defer ws.Close()
+		readWSMessageOfKind(t, ws, types.MessageKindRoomSnapshot)
+
+		rr := execAuthenticatedRequest(t, http.MethodPatch, roomURL+"/messages/"+msgID, strings.NewReader(`{"message": "what is go?"}`))
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var body types.MessageUpdated
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&body))
+
+		expected := types.MessageUpdated{ID: msgID, Message: "what is go?", UpdatedAt: body.UpdatedAt}
+		assert.Equal(t, expected, body)
+		assertValidDate(t, body.UpdatedAt)
+
+		msg := readWSMessageOfKind(t, ws, types.MessageKindMessageUpdated)
+		var updated types.MessageUpdated
+		decodeMessageValue(t, msg, &updated)
+		assert.Equal(t, expected, updated)
+
+		rr = execAuthenticatedRequest(t, http.MethodGet, roomURL+"/messages/"+msgID, nil)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var message pgstore.Message
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&message))
+		assert.Equal(t, "what is go?", message.Message)
+	})
+
+	t.Run("deletes the question and clears its pin", func(t *testing.T) {
+		truncateData(t)
+
+		server := httptest.NewServer(Router)
+		defer server.Close()
+
+		room := createAndGetRoom(t)
+		roomURL := baseURL + strconv.Itoa(int(room.ID))
+		msgID := askOwnQuestion(t, room.ID, "posted by mistake")
+
+		rr := execAuthenticatedRequest(t, http.MethodPatch, roomURL+"/messages/"+msgID+"/pin", nil)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		ws, err := connectAuthenticatedWS(t, "ws"+server.URL[4:]+"/subscribe/room/"+strconv.Itoa(int(room.ID)))
+		require.NoError(t, err)
+		defer ws.Close()
+		readWSMessageOfKind(t, ws, types.MessageKindRoomSnapshot)
+
+		rr = execAuthenticatedRequest(t, http.MethodDelete, roomURL+"/messages/"+msgID, nil)
+		require.Equal(t, http.StatusNoContent, rr.Code)
+
+		msg := readWSMessageOfKind(t, ws, types.MessageKindMessageDeleted)
+		var deleted types.MessageDeleted
+		decodeMessageValue(t, msg, &deleted)
+		assert.Equal(t, types.MessageDeleted{ID: msgID}, deleted)
+
+		msg = readWSMessageOfKind(t, ws, types.MessageKindMessagePinned)
+		var pinned types.MessagePinned
+		decodeMessageValue(t, msg, &pinned)
+		assert.Nil(t, pinned.ID)
+
+		rr = execAuthenticatedRequest(t, http.MethodGet, roomURL+"/messages/"+msgID, nil)
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	fakeMessageID := "00000000-0000-0000-0000-000000000000"
+
+	truncateData(t)
+	room := createAndGetRoom(t)
+	roomURL := baseURL + strconv.Itoa(int(room.ID))
+	setRoomSetting(t, room.ID, "max_question_length", 10)
+	answeredID := askOwnQuestion(t, room.ID, "answered")
+	expiredID := askOwnQuestion(t, room.ID, "expired")
+	hiddenID := askOwnQuestion(t, room.ID, "hidden")
+	foreignID, _ := createAndGetMessages(t, room.ID)
+	editableURL := roomURL + "/messages/" + askOwnQuestion(t, room.ID, "editable")
+
+	errorTestCases := []struct {
+		name               string
+		fn                 customFn
+		method             string
+		payload            string
+		expectedMessage    string
+		expectedStatusCode int
+		url                string
+		setConstraint      func(t *testing.T)
+	}{
+		{
+			name: "returns unauthorized error if sessionID is not found",
+			fn: func(t testing.TB, method, url string, body io.Reader) *httptest.ResponseRecorder {
+				return execRequestWithoutCookie(method, url, body)
+			},
+			method:             http.MethodDelete,
+			expectedMessage:    "unauthorized, session not found or invalid\n",
+			expectedStatusCode: http.StatusUnauthorized,
+			url:                editableURL,
+		},
+		{
+			name:               "returns an error if room id is not valid",
+			fn:                 execAuthenticatedRequest,
+			method:             http.MethodPatch,
+			payload:            `{"message": "fixed"}`,
+			expectedMessage:    "invalid room id\n",
+			expectedStatusCode: http.StatusBadRequest,
+			url:                baseURL + "invalid_room_id/messages/" + fakeMessageID,
+		},
+		{
+			name:               "returns an error if message id is not valid",
+			fn:                 execAuthenticatedRequest,
+			method:             http.MethodDelete,
+			expectedMessage:    "invalid message id\n",
+			expectedStatusCode: http.StatusBadRequest,
+			url:                roomURL + "/messages/invalid_message_id",
+		},
+		{
+			name:               "returns an error if body is not valid",
+			fn:                 execAuthenticatedRequest,
+			method:             http.MethodPatch,
+			payload:            `{"message": 1}`,
+			expectedMessage:    "invalid body\n",
+			expectedStatusCode: http.StatusBadRequest,
+			url:                editableURL,
+		},
+		{
+			name:               "returns an error if the message is missing",
+			fn:                 execAuthenticatedRequest,
+			method:             http.MethodPatch,
+			payload:            `{"message": ""}`,
+			expectedMessage:    "validation failed: missing required field(s): message\n",
+			expectedStatusCode: http.StatusBadRequest,
+			url:                editableURL,
+		},
+		{
+			name:               "returns an error if the message is longer than the room allows",
+			fn:                 execAuthenticatedRequest,
+			method:             http.MethodPatch,
+			payload:            `{"message": "eleven chars"}`,
+			expectedMessage:    "validation failed: message must have at most 10 characters\n",
+			expectedStatusCode: http.StatusBadRequest,
+			url:                editableURL,
+		},
+		{
+			name:               "returns an error if the message does not exist",
+			fn:                 execAuthenticatedRequest,
+			method:             http.MethodPatch,
+			payload:            `{"message": "fixed"}`,
+			expectedMessage:    "message not found\n",
+			expectedStatusCode: http.StatusNotFound,
+			url:                roomURL + "/messages/" + fakeMessageID,
+		},
+		{
+			name:               "returns an error if the message is hidden",
+			fn:                 execAuthenticatedRequest,
+			method:             http.MethodDelete,
+			expectedMessage:    "message not found\n",
+			expectedStatusCode: http.StatusNotFound,
+			url:                roomURL + "/messages/" + hiddenID,
+			setConstraint: func(t *testing.T) {
+				setMessageColumn(t, hiddenID, "hidden", true)
+			},
+		},
+		{
+			name:               "returns an error if the user is not the author",
+			fn:                 execAuthenticatedRequest,
+			method:             http.MethodPatch,
+			payload:            `{"message": "fixed"}`,
+			expectedMessage:    "only the author can change the question\n",
+			expectedStatusCode: http.StatusForbidden,
+			url:                roomURL + "/messages/" + foreignID,
+		},
+		{
+			name:               "returns an error if the message has been answered",
+			fn:                 execAuthenticatedRequest,
+			method:             http.MethodDelete,
+			expectedMessage:    "answered questions cannot be changed\n",
+			expectedStatusCode: http.StatusConflict,
+			url:                roomURL + "/messages/" + answeredID,
+			setConstraint: func(t *testing.T) {
+				setMessageColumn(t, answeredID, "answered", true)
+			},
+		},
+		{
+			name:               "returns an error if the edit window is over",
+			fn:                 execAuthenticatedRequest,
+			method:             http.MethodPatch,
+			payload:            `{"message": "fixed"}`,
+			expectedMessage:    "the time to change the question is over\n",
+			expectedStatusCode: http.StatusConflict,
+			url:                roomURL + "/messages/" + expiredID,
+			setConstraint: func(t *testing.T) {
+				setMessageColumn(t, expiredID, "created_at", time.Now().UTC().Add(-time.Hour))
+			},
+		},
+		{
+			name:               "returns an error if the room is closed",
+			fn:                 execAuthenticatedRequest,
+			method:             http.MethodPatch,
+			payload:            `{"message": "fixed"}`,
+			expectedMessage:    "the room is closed and is not accepting questions\n",
+			expectedStatusCode: http.StatusConflict,
+			url:                editableURL,
+			setConstraint: func(t *testing.T) {
+				setRoomStatus(t, room.ID, "closed", nil, nil)
+			},
+		},
+	}
+
+	for _, tc := range errorTestCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.setConstraint != nil {
+				tc.setConstraint(t)
+			}
+
+			rr := tc.fn(t, tc.method, tc.url, strings.NewReader(tc.payload))
+			response := rr.Result()
+			defer response.Body.Close()
+
+			body := parseResponseBody(t, response)
+
+			assert.Equal(t, tc.expectedStatusCode, response.StatusCode)
+			assert.Equal(t, tc.expectedMessage, body)
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package api_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vhrboliveira/ama-go/internal/store/pgstore"
+	"github.com/vhrboliveira/ama-go/internal/types"
+)
+
+func TestMessagePins(t *testing.T) {
+	const baseURL = "/api/rooms/"
+
+	getPinnedMessageID := func(t *testing.T, roomURL string) string {
+		t.Helper()
+
+		rr := execAuthenticatedRequest(t, http.MethodGet, roomURL, nil)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var room pgstore.GetRoomWithUserRow
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&room))
+		if !room.PinnedMessageID.Valid {
+			return ""
+		}
+
+		return uuid.UUID(room.PinnedMessageID.Bytes).String()
+	}
+
+	t.Run("pins the question being answered and notifies the room subscribers", func(t *testing.T) {
+		truncateData(t)
+
+		server := httptest.NewServer(Router)
+		defer server.Close()
+
+		room := createAndGetRoom(t)
+		roomURL := baseURL + strconv.Itoa(int(room.ID))
+		first := insertTimedMessage(t, room.ID, "first", time.Now().UTC(), nil)
+		second := insertTimedMessage(t, room.ID, "second", time.Now().UTC(), nil)
+		moderator := createOtherUser(t, "moderator@example.com")
+		addRoomMember(t, room.ID, moderator.ID.String(), "moderator")
+
+		ws, err := connectAuthenticatedWS(t, "ws"+server.URL[4:]+"/subscribe/room/"+strconv.Itoa(int(room.ID)))
+		require.NoError(t, err)
+		defer ws.Close()
+		readWSMessageOfKind(t, ws, types.MessageKindRoomSnapshot)
+
+		readPinned := func() types.MessagePinned {
+			msg := readWSMessageOfKind(t, ws, types.MessageKindMessagePinned)
+			var pinned types.MessagePinned
+			decodeMessageValue(t, msg, &pinned)
+			return pinned
+		}
+
+		rr := execRequestGeneratingSession(t, http.MethodPatch, roomURL+"/messages/"+first+"/pin", nil, &moderator)
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.JSONEq(t, `{"id": "`+first+`"}`, rr.Body.String())
+		assert.Equal(t, types.MessagePinned{ID: &first}, readPinned())
+		assert.Equal(t, first, getPinnedMessageID(t, roomURL))
+
+		rr = execAuthenticatedRequest(t, http.MethodPatch, roomURL+"/messages/"+second+"/pin", nil)
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, types.MessagePinned{ID: &second}, readPinned())
+		assert.Equal(t, second, getPinnedMessageID(t, roomURL))
+
+		rr = execAuthenticatedRequest(t, http.MethodDelete, roomURL+"/messages/"+first+"/pin", nil)
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, second, getPinnedMessageID(t, roomURL))
+
+		rr = execAuthenticatedRequest(t, http.MethodDelete, roomURL+"/messages/"+second+"/pin", nil)
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.JSONEq(t, `{"id": null}`, rr.Body.String())
+		assert.Equal(t, types.MessagePinned{}, readPinned())
+		assert.Empty(t, getPinnedMessageID(t, roomURL))
+	})
+
+	t.Run("unpins the question when it is hidden", func(t *testing.T) {
+		truncateData(t)
+
+		server := httptest.NewServer(Router)
+		defer server.Close()
+
+		room := createAndGetRoom(t)
+		roomURL := baseURL + strconv.Itoa(int(room.ID))
+		msgID, _ := createAndGetMessages(t, room.ID)
+
+		rr := execAuthenticatedRequest(t, http.MethodPatch, roomURL+"/messages/"+msgID+"/pin", nil)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		ws, err := connectAuthenticatedWS(t, "ws"+server.URL[4:]+"/subscribe/room/"+strconv.Itoa(int(room.ID)))
+		require.NoError(t, err)
+		defer ws.Close()
+		readWSMessageOfKind(t, ws, types.MessageKindRoomSnapshot)
+
+		rr = execAuthenticatedRequest(t, http.MethodPatch, roomURL+"/messages/"+msgID+"/hide", strings.NewReader(`{"hidden": true}`))
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		readWSMessageOfKind(t, ws, types.MessageKindMessageHidden)
+		msg := readWSMessageOfKind(t, ws, types.MessageKindMessagePinned)
+		var pinned types.MessagePinned
+		decodeMessageValue(t, msg, &pinned)
+		assert.Nil(t, pinned.ID)
+		assert.Empty(t, getPinnedMessageID(t, roomURL))
+
+		rr = execAuthenticatedRequest(t, http.MethodPatch, roomURL+"/messages/"+msgID+"/pin", nil)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Equal(t, "hidden questions cannot be pinned\n", rr.Body.String())
+	})
+
+	truncateData(t)
+	room := createAndGetRoom(t)
+	roomURL := baseURL + strconv.Itoa(int(room.ID))
+	msgID, _ := createAndGetMessages(t, room.ID)
+	createRooms(t, []string{"other"})
+	otherMsgID, _ := createAndGetMessages(t, getRoomByName(t, "other").ID)
+
+	errorTestCases := []struct {
+		name               string
+		fn                 func(t testing.TB, method string, url string, body io.Reader) *httptest.ResponseRecorder
+		method             string
+		url                string
+		expectedMessage    string
+		expectedStatusCode int
+		setConstraint      func(t *testing.T)
+	}{
+		{
+			name: "returns unauthorized error if sessionID is not found",
+			fn: func(t testing.TB, method, url string, body io.Reader) *httptest.ResponseRecorder {
+				return execRequestWithoutCookie(method, url, body)
+			},
+			method:             http.MethodPatch,
+			url:                roomURL + "/messages/" + msgID + "/pin",
+			expectedMessage:    "unauthorized, session not found or invalid\n",
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:               "returns an error if message id is not valid",
+			fn:                 execAuthenticatedRequest,
+			method:             http.MethodPatch,
+			url:                roomURL + "/messages/invalid_message_id/pin",
+			expectedMessage:    "invalid message id\n",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "returns an error if the message is in another room",
+			fn:                 execAuthenticatedRequest,
+			method:             http.MethodPatch,
+			url:                roomURL + "/messages/" + otherMsgID + "/pin",
+			expectedMessage:    "message not found\n",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "returns an error if the user is not a room host",
+			fn:                 execAuthenticatedRequest,
+			method:             http.MethodDelete,
+			url:                roomURL + "/messages/" + msgID + "/pin",
+			expectedMessage:    "only the room owner, moderators and panelists can pin questions\n",
+			expectedStatusCode: http.StatusForbidden,
+			setConstraint: func(t *testing.T) {
+				otherUser := createOtherUser(t, "other@example.com")
+				setRoomOwner(t, room.ID, otherUser.ID.String())
+			},
+		},
+	}
+
+	for _, tc := range errorTestCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.setConstraint != nil {
+				tc.setConstraint(t)
+			}
+
+			rr := tc.fn(t, tc.method, tc.url, nil)
+			response := rr.Result()
+			defer response.Body.Close()
+
+			body := parseResponseBody(t, response)
+
+			assert.Equal(t, tc.expectedStatusCode, response.StatusCode)
+			assert.Equal(t, tc.expectedMessage, body)
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vhrboliveira/ama-go/internal/types"
+)
+
+func TestSubscribeToTopics(t *testing.T) {
+	server := httptest.NewServer(Router)
+	defer server.Close()
+
+	wsURL := "ws" + server.URL[4:] + "/subscribe/multiplex"
+
+	connect := func(t *testing.T) *websocket.Conn {
+		t.Helper()
+
+		ws, err := connectAuthenticatedWS(t, wsURL)
+		require.NoError(t, err)
+		t.Cleanup(func() { ws.Close() })
+
+		return ws
+	}
+
+	subscribe := func(t *testing.T, ws *websocket.Conn, topic string) {
+		t.Helper()
+
+		sendWSCommand(t, ws, types.CommandKindSubscribe, "sub-"+topic, types.TopicSubscription{Topic: topic})
+		ack := readWSMessageOfKind(t, ws, types.MessageKindCommandAck)
+		assert.Equal(t, topic, ack.Topic)
+	}
+
+	t.Run("delivers the events of every subscribed topic tagged with it", func(t *testing.T) {
+		truncateData(t)
+
+		createRooms(t, []string{"first room", "second room"})
+		firstRoom := getRoomByName(t, "first room")
+		secondRoom := getRoomByName(t, "second room")
+
+		ws := connect(t)
+		subscribe(t, ws, types.TopicRooms)
+		subscribe(t, ws, types.RoomTopic(firstRoom.ID))
+		subscribe(t, ws, types.RoomTopic(secondRoom.ID))
+
+		Handler.WebsocketService.NotifyRoomsListClients(types.Message{
+			Kind:  types.MessageKindRoomCreated,
+			Value: types.RoomCreated{ID: 1, Name: "room"},
+		})
+		msg := readWSMessage(t, ws)
+		assert.Equal(t, types.MessageKindRoomCreated, msg.Kind)
+		assert.Equal(t, types.TopicRooms, msg.Topic)
+
+		for _, room := range []int64{firstRoom.ID, secondRoom.ID} {
+			Handler.WebsocketService.NotifyRoomClient(types.Message{
+				Kind:   types.MessageKindMessageAnswered,
+				RoomID: room,
+				Value:  types.MessageAnswered{ID: "message-id", Answer: "answer"},
+			})
+
+			msg := readWSMessage(t, ws)
+			assert.Equal(t, types.MessageKindMessageAnswered, msg.Kind)
+			assert.Equal(t, types.RoomTopic(room), msg.Topic)
+			assert.NotZero(t, msg.Seq)
+		}
+	})
+
+	t.Run("stops delivering the events of an unsubscribed topic", func(t *testing.T) {
+		truncateData(t)
+
+		room := createAndGetRoom(t)
+		ws := connect(t)
+		subscribe(t, ws, types.RoomTopic(room.ID))
+		subscribe(t, ws, types.TopicRooms)
+
+		sendWSCommand(t, ws, types.CommandKindUnsubscribe, "unsub", types.TopicSubscription{Topic: types.RoomTopic(room.ID)})
+		ack := readWSMessageOfKind(t, ws, types.MessageKindCommandAck)
+		assert.Equal(t, types.RoomTopic(room.ID), ack.Topic)
+		waitForRoomSubscribers(t, room.ID, 0)
+
+		Handler.WebsocketService.NotifyRoomClient(types.Message{
+			Kind:   types.MessageKindMessageAnswered,
+			RoomID: room.ID,
+			Value:  types.MessageAnswered{ID: "message-id", Answer: "answer"},
+		})
+		Handler.WebsocketService.NotifyRoomsListClients(types.Message{
+			Kind:  types.MessageKindRoomCreated,
+			Value: types.RoomCreated{ID: 1, Name: "room"},
+		})
+
+		msg := readWSMessage(t, ws)
+		assert.Equal(t, types.MessageKindRoomCreated, msg.Kind)
+	})
+
+	t.Run("replays the events missed since the cursor", func(t *testing.T) {
+		truncateData(t)
+
+		room := createAndGetRoom(t)
+		for _, answer := range []string{"first", "second"} {
+			Handler.WebsocketService.NotifyRoomClient(types.Message{
+				Kind:   types.MessageKindMessageAnswered,
+				RoomID: room.ID,
+				Value:  types.MessageAnswered{ID: answer, Answer: answer},
+			})
+		}
+
+		ws := connect(t)
+		since := int64(0)
+		sendWSCommand(t, ws, types.CommandKindSubscribe, "sub", types.TopicSubscription{Topic: types.RoomTopic(room.ID), Since: &since})
+
+		first := readWSMessage(t, ws)
+		second := readWSMessage(t, ws)
+		assert.Equal(t, types.MessageKindMessageAnswered, first.Kind)
+		assert.Equal(t, first.Seq+1, second.Seq)
+		assert.Equal(t, types.RoomTopic(room.ID), second.Topic)
+
+		ack := readWSMessage(t, ws)
+		assert.Equal(t, types.MessageKindCommandAck, ack.Kind)
+	})
+
+	t.Run("removes the connection from every topic when it closes", func(t *testing.T) {
+		truncateData(t)
+
+		room := createAndGetRoom(t)
+		ws := connect(t)
+		subscribe(t, ws, types.TopicRooms)
+		subscribe(t, ws, types.RoomTopic(room.ID))
+
+		ws.Close()
+
+		waitForTopicSubscribers(t, types.TopicRooms, 0)
+		waitForRoomSubscribers(t, room.ID, 0)
+	})
+
+	t.Run("returns an error if the command is not valid", func(t *testing.T) {
+		truncateData(t)
+
+		room := createAndGetRoom(t)
+		negativeSince := int64(-1)
+
+		tests := []struct {
+			name     string
+			kind     string
+			value    types.TopicSubscription
+			expected types.CommandError
+		}{
+			{
+				name:     "invalid topic",
+				kind:     types.CommandKindSubscribe,
+				value:    types.TopicSubscription{Topic: "room:invalid"},
+				expected: types.CommandError{RequestID: "req", Status: http.StatusBadRequest, Error: "invalid topic"},
+			},
+			{
+				name:     "room not found",
+				kind:     types.CommandKindSubscribe,
+				value:    types.TopicSubscription{Topic: types.RoomTopic(room.ID + 1)},
+				expected: types.CommandError{RequestID: "req", Status: http.StatusBadRequest, Error: "room not found"},
+			},
+			{
+				name:     "invalid since cursor",
+				kind:     types.CommandKindSubscribe,
+				value:    types.TopicSubscription{Topic: types.RoomTopic(room.ID), Since: &negativeSince},
+				expected: types.CommandError{RequestID: "req", Status: http.StatusBadRequest, Error: "invalid since cursor"},
+			},
+			{
+				name:     "not subscribed",
+				kind:     types.CommandKindUnsubscribe,
+				value:    types.TopicSubscription{Topic: types.TopicRooms},
+				expected: types.CommandError{RequestID: "req", Status: http.StatusBadRequest, Error: "not subscribed to topic"},
+			},
+			{
+				name:     "unknown command",
+				kind:     types.CommandKindAsk,
+				value:    types.TopicSubscription{Topic: types.TopicRooms},
+				expected: types.CommandError{RequestID: "req", Status: http.StatusBadRequest, Error: "unknown command: ask"},
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				ws := connect(t)
+
+				sendWSCommand(t, ws, tt.kind, "req", tt.value)
+
+				msg := readWSMessageOfKind(t, ws, types.MessageKindCommandError)
+				var commandError types.CommandError
+				decodeMessageValue(t, msg, &commandError)
+				assert.Equal(t, tt.expected, commandError)
+			})
+		}
+	})
+}
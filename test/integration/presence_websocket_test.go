@@ -0,0 +1,120 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vhrboliveira/ama-go/internal/types"
+)
+
+func TestRoomPresence(t *testing.T) {
+	server := httptest.NewServer(Router)
+	defer server.Close()
+
+	presenceDebounce := Handler.WebsocketService.PresenceDebounce
+	Handler.WebsocketService.PresenceDebounce = 50 * time.Millisecond
+	t.Cleanup(func() {
+		Handler.WebsocketService.PresenceDebounce = presenceDebounce
+	})
+
+	roomURL := func(roomID int64) string {
+		return "ws" + server.URL[4:] + "/subscribe/room/" + strconv.Itoa(int(roomID))
+	}
+
+	// Joins and leaves are debounced, so intermediate counts may be skipped or
+	// repeated before the expected one arrives
+	waitForPresence := func(t *testing.T, ws *websocket.Conn, count int) types.PresenceChanged {
+		t.Helper()
+
+		for {
+			var presence types.PresenceChanged
+			decodeMessageValue(t, readWSMessageOfKind(t, ws, types.MessageKindPresenceChanged), &presence)
+			if presence.Count == count {
+				return presence
+			}
+		}
+	}
+
+	t.Run("broadcasts who is watching the room when viewers join and leave", func(t *testing.T) {
+		truncateData(t)
+
+		room := createAndGetRoom(t)
+		userID := generateUser(t)
+
+		first, _, err := connectWSWithUserSession(t, roomURL(room.ID), &userID)
+		require.NoError(t, err)
+		defer first.Close()
+
+		second, _, err := connectWSWithUserSession(t, roomURL(room.ID), &userID)
+		require.NoError(t, err)
+
+		waitForRoomSubscribers(t, room.ID, 2)
+
+		presence := waitForPresence(t, first, 2)
+		require.Len(t, presence.Viewers, 1, "the same user must be listed once")
+		assert.Equal(t, userID, presence.Viewers[0].ID)
+		assert.Equal(t, "Test User", presence.Viewers[0].Name)
+		assert.Equal(t, "http://avatar.com/test.jpg", presence.Viewers[0].Photo)
+
+		second.Close()
+		waitForRoomSubscribers(t, room.ID, 1)
+
+		presence = waitForPresence(t, first, 1)
+		assert.Len(t, presence.Viewers, 1)
+	})
+
+	t.Run("hides the photo of users who disabled it", func(t *testing.T) {
+		truncateData(t)
+
+		room := createAndGetRoom(t)
+		userID := generateUser(t)
+		_, err := DBPool.Exec(context.Background(), "UPDATE users SET enable_picture = false WHERE id = $1", userID)
+		require.NoError(t, err)
+
+		ws, _, err := connectWSWithUserSession(t, roomURL(room.ID), &userID)
+		require.NoError(t, err)
+		defer ws.Close()
+
+		presence := waitForPresence(t, ws, 1)
+		require.Len(t, presence.Viewers, 1)
+		assert.Empty(t, presence.Viewers[0].Photo)
+	})
+
+	t.Run("returns the presence count with the rooms", func(t *testing.T) {
+		truncateData(t)
+
+		room := createAndGetRoom(t)
+		ws, err := connectAuthenticatedWS(t, roomURL(room.ID))
+		require.NoError(t, err)
+		defer ws.Close()
+
+		// The counts are read from the presence store, which is updated right
+		// before presence_changed goes out
+		waitForPresence(t, ws, 1)
+
+		type roomPresence struct {
+			ID       int64 `json:"id"`
+			Presence int   `json:"presence"`
+		}
+
+		rr := execAuthenticatedRequest(t, http.MethodGet, "/api/rooms", nil)
+		var rooms []roomPresence
+		require.NoError(t, json.NewDecoder(rr.Result().Body).Decode(&rooms))
+		require.Len(t, rooms, 1)
+		assert.Equal(t, 1, rooms[0].Presence)
+
+		rr = execAuthenticatedRequest(t, http.MethodGet, "/api/rooms/"+strconv.Itoa(int(room.ID)), nil)
+		var result roomPresence
+		require.NoError(t, json.NewDecoder(rr.Result().Body).Decode(&result))
+		assert.Equal(t, room.ID, result.ID)
+		assert.Equal(t, 1, result.Presence)
+	})
+}
@@ -80,6 +80,7 @@ func TestMessageReaction(t *testing.T) {
 		ws, err := connectAuthenticatedWS(t, wsURL)
 		require.NoError(t, err)
 		defer ws.Close()
+		readWSMessageOfKind(t, ws, types.MessageKindRoomSnapshot)
 
 		msgID, _ := createAndGetMessages(t, room.ID)
 		userID := getUserIDByEmail(t, gothUser.Email)
@@ -125,6 +126,7 @@ func TestMessageReaction(t *testing.T) {
 		ws, err := connectAuthenticatedWS(t, wsURL)
 		require.NoError(t, err)
 		defer ws.Close()
+		readWSMessageOfKind(t, ws, types.MessageKindRoomSnapshot)
 
 		msgID, _ := createAndGetMessages(t, room.ID)
 		userID := getUserIDByEmail(t, gothUser.Email)
@@ -0,0 +1,87 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vhrboliveira/ama-go/internal/types"
+)
+
+func TestResumeRoomSubscription(t *testing.T) {
+	server := httptest.NewServer(Router)
+	defer server.Close()
+
+	notifyMessageCreated := func(roomID int64, message string) {
+		Handler.WebsocketService.NotifyRoomClient(types.Message{
+			Kind:   types.MessageKindMessageCreated,
+			RoomID: roomID,
+			Value:  types.MessageCreated{ID: message, Message: message},
+		})
+	}
+
+	t.Run("replays the events missed since the cursor before live events", func(t *testing.T) {
+		truncateData(t)
+
+		room := createAndGetRoom(t)
+		wsURL := "ws" + server.URL[4:] + "/subscribe/room/" + strconv.Itoa(int(room.ID))
+		ws, err := connectAuthenticatedWS(t, wsURL)
+		require.NoError(t, err)
+
+		waitForRoomSubscribers(t, room.ID, 1)
+		notifyMessageCreated(room.ID, "first")
+
+		first := readWSMessageOfKind(t, ws, types.MessageKindMessageCreated)
+		require.NotZero(t, first.Seq)
+		ws.Close()
+		waitForRoomSubscribers(t, room.ID, 0)
+
+		notifyMessageCreated(room.ID, "second")
+		notifyMessageCreated(room.ID, "third")
+
+		ws, err = connectAuthenticatedWS(t, wsURL+"?since="+strconv.FormatInt(first.Seq, 10))
+		require.NoError(t, err)
+		defer ws.Close()
+
+		second := readWSMessage(t, ws)
+		third := readWSMessage(t, ws)
+		assert.Equal(t, first.Seq+1, second.Seq)
+		assert.Equal(t, first.Seq+2, third.Seq)
+		assert.Equal(t, types.MessageKindMessageCreated, second.Kind)
+
+		waitForRoomSubscribers(t, room.ID, 1)
+		notifyMessageCreated(room.ID, "fourth")
+
+		fourth := readWSMessage(t, ws)
+		assert.Equal(t, first.Seq+3, fourth.Seq)
+	})
+
+	t.Run("asks the client to refetch when the cursor is not in the log", func(t *testing.T) {
+		truncateData(t)
+
+		room := createAndGetRoom(t)
+		wsURL := "ws" + server.URL[4:] + "/subscribe/room/" + strconv.Itoa(int(room.ID)) + "?since=999999999"
+		ws, err := connectAuthenticatedWS(t, wsURL)
+		require.NoError(t, err)
+		defer ws.Close()
+
+		msg := readWSMessage(t, ws)
+		assert.Equal(t, types.MessageKindResyncRequired, msg.Kind)
+	})
+
+	t.Run("returns an error if the cursor is not valid", func(t *testing.T) {
+		truncateData(t)
+
+		room := createAndGetRoom(t)
+		wsURL := "ws" + server.URL[4:] + "/subscribe/room/" + strconv.Itoa(int(room.ID)) + "?since=invalid"
+		userID := generateUser(t)
+		_, res, err := connectWSWithUserSession(t, wsURL, &userID)
+		require.Error(t, err)
+
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+		assert.Equal(t, "invalid since cursor\n", parseResponseBody(t, res))
+	})
+}
@@ -0,0 +1,153 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vhrboliveira/ama-go/internal/types"
+)
+
+func TestRoomCommands(t *testing.T) {
+	server := httptest.NewServer(Router)
+	defer server.Close()
+
+	connect := func(t *testing.T, roomID int64) *websocket.Conn {
+		t.Helper()
+
+		wsURL := "ws" + server.URL[4:] + "/subscribe/room/" + strconv.Itoa(int(roomID))
+		ws, err := connectAuthenticatedWS(t, wsURL)
+		require.NoError(t, err)
+		t.Cleanup(func() { ws.Close() })
+
+		return ws
+	}
+
+	t.Run("asks a question and broadcasts it", func(t *testing.T) {
+		truncateData(t)
+
+		room := createAndGetRoom(t)
+		ws := connect(t, room.ID)
+
+		sendWSCommand(t, ws, types.CommandKindAsk, "req-1", map[string]string{"message": "what is Go?"})
+
+		ack := readWSMessageOfKind(t, ws, types.MessageKindCommandAck)
+		var ackValue struct {
+			RequestID string `json:"request_id"`
+			Result    struct {
+				ID string `json:"id"`
+			} `json:"result"`
+		}
+		decodeMessageValue(t, ack, &ackValue)
+		assert.Equal(t, "req-1", ackValue.RequestID)
+		assertValidUUID(t, ackValue.Result.ID)
+
+		created := readWSMessageOfKind(t, ws, types.MessageKindMessageCreated)
+		var messageCreated types.MessageCreated
+		decodeMessageValue(t, created, &messageCreated)
+		assert.Equal(t, ackValue.Result.ID, messageCreated.ID)
+		assert.Equal(t, "what is Go?", messageCreated.Message)
+	})
+
+	t.Run("reacts to a message", func(t *testing.T) {
+		truncateData(t)
+
+		room := createAndGetRoom(t)
+		msgID, _ := createAndGetMessages(t, room.ID)
+		ws := connect(t, room.ID)
+
+		sendWSCommand(t, ws, types.CommandKindReact, "req-2", map[string]string{"message_id": msgID})
+
+		ack := readWSMessageOfKind(t, ws, types.MessageKindCommandAck)
+		var ackValue struct {
+			RequestID string `json:"request_id"`
+			Result    struct {
+				Count int32 `json:"count"`
+			} `json:"result"`
+		}
+		decodeMessageValue(t, ack, &ackValue)
+		assert.Equal(t, "req-2", ackValue.RequestID)
+		assert.Equal(t, int32(1), ackValue.Result.Count)
+		assert.Equal(t, 1, getMessageReactions(t, msgID))
+	})
+
+	errorTestCases := []struct {
+		name           string
+		kind           string
+		requestID      string
+		value          any
+		expectedStatus int
+		expectedError  string
+	}{
+		{
+			name:           "returns an error if the request id is missing",
+			kind:           types.CommandKindAsk,
+			value:          map[string]string{"message": "question"},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "validation failed, missing required field(s): RequestID",
+		},
+		{
+			name:           "returns an error if the command is unknown",
+			kind:           "shout",
+			requestID:      "req",
+			value:          map[string]string{},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "unknown command: shout",
+		},
+		{
+			name:           "returns an error if the question is empty",
+			kind:           types.CommandKindAsk,
+			requestID:      "req",
+			value:          map[string]string{"message": ""},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "validation failed: missing required field(s): message",
+		},
+		{
+			name:           "returns an error if message id is not valid",
+			kind:           types.CommandKindReact,
+			requestID:      "req",
+			value:          map[string]string{"message_id": "invalid"},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "invalid message id",
+		},
+		{
+			name:           "returns an error if the message does not exist",
+			kind:           types.CommandKindAnswer,
+			requestID:      "req",
+			value:          map[string]string{"message_id": "9b2e8b38-3f3c-4b8c-9d84-54d2bb4f3f1e", "answer": "answer"},
+			expectedStatus: http.StatusNotFound,
+			expectedError:  "message not found",
+		},
+		{
+			name:           "returns an error if the answer is blank",
+			kind:           types.CommandKindAnswer,
+			requestID:      "req",
+			value:          map[string]string{"message_id": "9b2e8b38-3f3c-4b8c-9d84-54d2bb4f3f1e", "answer": "   "},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "validation failed, missing required field(s): MessageID, Answer",
+		},
+	}
+
+	for _, tc := range errorTestCases {
+		t.Run(tc.name, func(t *testing.T) {
+			truncateData(t)
+
+			room := createAndGetRoom(t)
+			ws := connect(t, room.ID)
+
+			sendWSCommand(t, ws, tc.kind, tc.requestID, tc.value)
+
+			reply := readWSMessageOfKind(t, ws, types.MessageKindCommandError)
+			var commandError types.CommandError
+			decodeMessageValue(t, reply, &commandError)
+
+			assert.Equal(t, tc.requestID, commandError.RequestID)
+			assert.Equal(t, tc.expectedStatus, commandError.Status)
+			assert.Equal(t, tc.expectedError, commandError.Error)
+		})
+	}
+}
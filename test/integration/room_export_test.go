@@ -0,0 +1,212 @@
+package api_test
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportRoom(t *testing.T) {
+	type customFn func(t testing.TB, method string, url string, body io.Reader) *httptest.ResponseRecorder
+
+	type exportedMessage struct {
+		ID            string `json:"id"`
+		Message       string `json:"message"`
+		Answer        string `json:"answer"`
+		Answered      bool   `json:"answered"`
+		CreatedAt     string `json:"created_at"`
+		UpdatedAt     string `json:"updated_at"`
+		ReactionCount int64  `json:"reaction_count"`
+	}
+
+	type export struct {
+		Room struct {
+			ID   int64  `json:"id"`
+			Name string `json:"name"`
+			Slug string `json:"slug"`
+		} `json:"room"`
+		Messages []exportedMessage `json:"messages"`
+	}
+
+	const (
+		baseURL = "/api/rooms/"
+		method  = http.MethodGet
+	)
+
+	// setup creates a room with an answered question, a more reacted unanswered
+	// one and a hidden one, which is never exported.
+	setup := func(t *testing.T) (roomURL string, roomID int64, first, second string) {
+		truncateData(t)
+
+		room := createAndGetRoom(t)
+		setRoomSlug(t, room.ID, "weekly-ama")
+		start := time.Now().UTC().Truncate(time.Second).Add(-time.Hour)
+
+		first = insertTimedMessage(t, room.ID, "first, \"quoted\"", start, offset(time.Minute))
+		_, err := DBPool.Exec(context.Background(), "UPDATE messages SET answer = $1 WHERE id = $2", "the answer", first)
+		require.NoError(t, err)
+
+		second = insertTimedMessage(t, room.ID, "second\nline", start.Add(time.Minute), nil)
+		hidden := insertTimedMessage(t, room.ID, "hidden", start.Add(2*time.Minute), nil)
+		_, err = DBPool.Exec(context.Background(), "UPDATE messages SET hidden = true WHERE id = $1", hidden)
+		require.NoError(t, err)
+
+		insertReaction(t, second, generateUser(t))
+		insertReaction(t, second, createOtherUser(t, "reactor@example.com").ID.String())
+		insertReaction(t, first, generateUser(t))
+
+		return baseURL + strconv.Itoa(int(room.ID)) + "/export", room.ID, first, second
+	}
+
+	t.Run("exports the room as JSON by default", func(t *testing.T) {
+		url, roomID, first, second := setup(t)
+
+		rr := execAuthenticatedRequest(t, method, url, nil)
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+		assert.Equal(t, `attachment; filename="weekly-ama.json"`, rr.Header().Get("Content-Disposition"))
+
+		var body export
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&body))
+
+		assert.Equal(t, roomID, body.Room.ID)
+		assert.Equal(t, "weekly-ama", body.Room.Slug)
+		require.Len(t, body.Messages, 2)
+		assert.Equal(t, first, body.Messages[0].ID)
+		assert.Equal(t, "the answer", body.Messages[0].Answer)
+		assert.True(t, body.Messages[0].Answered)
+		assert.Equal(t, int64(1), body.Messages[0].ReactionCount)
+		assertValidDate(t, body.Messages[0].CreatedAt)
+		assertValidDate(t, body.Messages[0].UpdatedAt)
+		assert.Equal(t, second, body.Messages[1].ID)
+		assert.False(t, body.Messages[1].Answered)
+		assert.Equal(t, int64(2), body.Messages[1].ReactionCount)
+	})
+
+	t.Run("exports the room as CSV", func(t *testing.T) {
+		url, roomID, first, second := setup(t)
+
+		rr := execAuthenticatedRequest(t, method, url+"?format=csv", nil)
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "text/csv; charset=utf-8", rr.Header().Get("Content-Type"))
+
+		records, err := csv.NewReader(rr.Body).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, records, 3)
+
+		rawRoomID := strconv.Itoa(int(roomID))
+		assert.Equal(t, []string{"room_id", "room_slug", "room_name", "id", "message", "answer", "answered", "created_at", "updated_at", "reaction_count"}, records[0])
+		assert.Equal(t, []string{rawRoomID, "weekly-ama", "room", first, "first, \"quoted\"", "the answer", "true"}, records[1][:7])
+		assert.Equal(t, "1", records[1][9])
+		assert.Equal(t, []string{rawRoomID, "weekly-ama", "room", second, "second\nline", "", "false"}, records[2][:7])
+		assert.Equal(t, "2", records[2][9])
+	})
+
+	t.Run("exports the room as a Markdown transcript sorted by reactions", func(t *testing.T) {
+		url, _, _, _ := setup(t)
+
+		rr := execAuthenticatedRequest(t, method, url+"?format=md", nil)
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "text/markdown; charset=utf-8", rr.Header().Get("Content-Type"))
+
+		transcript := rr.Body.String()
+		assert.True(t, strings.HasPrefix(transcript, "# room\n"))
+		assert.Contains(t, transcript, "## 1. second line\n\n_2 reactions, asked at ")
+		assert.Contains(t, transcript, "Not answered.\n")
+		assert.Contains(t, transcript, "## 2. first, \"quoted\"\n\n_1 reaction, asked at ")
+		assert.Contains(t, transcript, "> the answer\n")
+		assert.NotContains(t, transcript, "hidden")
+	})
+
+	t.Run("lets the room moderators export it", func(t *testing.T) {
+		url, roomID, _, _ := setup(t)
+
+		moderator := createOtherUser(t, "moderator@example.com")
+		addRoomMember(t, roomID, moderator.ID.String(), "moderator")
+
+		rr := execRequestGeneratingSession(t, method, url, nil, &moderator)
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	truncateData(t)
+	room := createAndGetRoom(t)
+	roomURL := baseURL + strconv.Itoa(int(room.ID)) + "/export"
+
+	errorTestCases := []struct {
+		name               string
+		fn                 customFn
+		expectedMessage    string
+		expectedStatusCode int
+		url                string
+		setConstraint      func(t *testing.T)
+	}{
+		{
+			name: "returns unauthorized error if sessionID is not found",
+			fn: func(t testing.TB, method, url string, body io.Reader) *httptest.ResponseRecorder {
+				return execRequestWithoutCookie(method, url, body)
+			},
+			expectedMessage:    "unauthorized, session not found or invalid\n",
+			expectedStatusCode: http.StatusUnauthorized,
+			url:                roomURL,
+		},
+		{
+			name:               "returns an error if room id is not valid",
+			fn:                 execAuthenticatedRequest,
+			expectedMessage:    "invalid room id\n",
+			expectedStatusCode: http.StatusBadRequest,
+			url:                baseURL + "invalid_room_id/export",
+		},
+		{
+			name:               "returns an error if the format is not valid",
+			fn:                 execAuthenticatedRequest,
+			expectedMessage:    "validation failed: format must be one of: json, csv, md\n",
+			expectedStatusCode: http.StatusBadRequest,
+			url:                roomURL + "?format=pdf",
+		},
+		{
+			name:               "returns an error if room does not exist",
+			fn:                 execAuthenticatedRequest,
+			expectedMessage:    "room not found\n",
+			expectedStatusCode: http.StatusBadRequest,
+			url:                baseURL + "999999/export",
+		},
+		{
+			name:               "returns an error if the user is not a room host",
+			fn:                 execAuthenticatedRequest,
+			expectedMessage:    "only the room owner, moderators and panelists can export the room\n",
+			expectedStatusCode: http.StatusForbidden,
+			url:                roomURL,
+			setConstraint: func(t *testing.T) {
+				otherUser := createOtherUser(t, "other@example.com")
+				setRoomOwner(t, room.ID, otherUser.ID.String())
+			},
+		},
+	}
+
+	for _, tc := range errorTestCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.setConstraint != nil {
+				tc.setConstraint(t)
+			}
+
+			rr := tc.fn(t, method, tc.url, strings.NewReader(""))
+			response := rr.Result()
+			defer response.Body.Close()
+
+			body := parseResponseBody(t, response)
+
+			assert.Equal(t, tc.expectedStatusCode, response.StatusCode)
+			assert.Equal(t, tc.expectedMessage, body)
+		})
+	}
+}
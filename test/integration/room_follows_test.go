@@ -0,0 +1,234 @@
+package api_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vhrboliveira/ama-go/internal/service"
+	"github.com/vhrboliveira/ama-go/internal/store/pgstore"
+	"github.com/vhrboliveira/ama-go/internal/types"
+)
+
+func TestRoomFollows(t *testing.T) {
+	type customFn func(t testing.TB, method string, url string, body io.Reader) *httptest.ResponseRecorder
+
+	const baseURL = "/api/rooms/"
+
+	getFollowedRooms := func(t *testing.T) []pgstore.GetFollowedRoomsRow {
+		t.Helper()
+
+		rr := execAuthenticatedRequest(t, http.MethodGet, "/api/follows", nil)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var rooms []pgstore.GetFollowedRoomsRow
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&rooms))
+		return rooms
+	}
+
+	getNotifications := func(t *testing.T) []types.Notification {
+		t.Helper()
+
+		rr := execAuthenticatedRequest(t, http.MethodGet, "/api/notifications", nil)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var body types.Notifications
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&body))
+		return body.Notifications
+	}
+
+	t.Run("follows, lists and unfollows rooms", func(t *testing.T) {
+		truncateData(t)
+
+		createRooms(t, []string{"first room", "second room"})
+		first := getRoomByName(t, "first room")
+		second := getRoomByName(t, "second room")
+
+		for _, room := range []pgstore.Room{first, second, second} {
+			rr := execAuthenticatedRequest(t, http.MethodPost, baseURL+strconv.Itoa(int(room.ID))+"/follow", nil)
+			require.Equal(t, http.StatusNoContent, rr.Code)
+		}
+
+		rooms := getFollowedRooms(t)
+		require.Len(t, rooms, 2)
+		assert.Equal(t, second.ID, rooms[0].ID)
+		assert.Equal(t, "second room", rooms[0].Name)
+		assert.Equal(t, first.ID, rooms[1].ID)
+
+		rr := execAuthenticatedRequest(t, http.MethodDelete, baseURL+strconv.Itoa(int(first.ID))+"/follow", nil)
+		require.Equal(t, http.StatusNoContent, rr.Code)
+
+		rooms = getFollowedRooms(t)
+		require.Len(t, rooms, 1)
+		assert.Equal(t, second.ID, rooms[0].ID)
+
+		// a private room the user can no longer access is not listed
+		owner := createOtherUser(t, "owner@example.com")
+		setRoomOwner(t, second.ID, owner.ID.String())
+		setRoomVisibility(t, second.ID, service.RoomVisibilityPrivate)
+
+		assert.Empty(t, getFollowedRooms(t))
+	})
+
+	t.Run("notifies the followers when the room opens and the questions they reacted to are answered", func(t *testing.T) {
+		truncateData(t)
+
+		server := httptest.NewServer(Router)
+		defer server.Close()
+
+		room := createAndGetRoom(t)
+		roomURL := baseURL + strconv.Itoa(int(room.ID))
+		owner := createOtherUser(t, "owner@example.com")
+		setRoomOwner(t, room.ID, owner.ID.String())
+
+		userID := generateUser(t)
+		rr := execAuthenticatedRequest(t, http.MethodPost, roomURL+"/follow", nil)
+		require.Equal(t, http.StatusNoContent, rr.Code)
+		setRoomStatus(t, room.ID, service.RoomStatusDraft, nil, nil)
+
+		reacted := insertTimedMessage(t, room.ID, "reacted", time.Now().UTC(), nil)
+		notReacted := insertTimedMessage(t, room.ID, "not reacted", time.Now().UTC(), nil)
+		insertReaction(t, reacted, userID)
+
+		rr = execRequestGeneratingSession(t, http.MethodPatch, roomURL+"/status", strings.NewReader(`{"status": "open"}`), &owner)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		for _, msgID := range []string{reacted, notReacted} {
+			payload := `{"user_id": "` + owner.ID.String() + `", "answer": "the answer"}`
+			rr = execRequestGeneratingSession(t, http.MethodPatch, roomURL+"/messages/"+msgID+"/answer", strings.NewReader(payload), &owner)
+			require.Equal(t, http.StatusOK, rr.Code)
+		}
+
+		notifications := getNotifications(t)
+		require.Len(t, notifications, 2)
+
+		answered := notifications[0]
+		assert.Equal(t, service.NotificationKindMessageAnswered, answered.Kind)
+		assert.Equal(t, room.ID, answered.RoomID)
+		assert.Equal(t, "room", answered.RoomName)
+		require.NotNil(t, answered.MessageID)
+		assert.Equal(t, reacted, *answered.MessageID)
+		require.NotNil(t, answered.Message)
+		assert.Equal(t, "reacted", *answered.Message)
+		assertValidDate(t, answered.CreatedAt)
+
+		opened := notifications[1]
+		assert.Equal(t, service.NotificationKindRoomOpened, opened.Kind)
+		assert.Equal(t, room.ID, opened.RoomID)
+		assert.Nil(t, opened.MessageID)
+		assert.Nil(t, opened.Message)
+
+		// opening the room again does not notify the followers twice
+		rr = execRequestGeneratingSession(t, http.MethodPatch, roomURL+"/status", strings.NewReader(`{"status": "open"}`), &owner)
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.Len(t, getNotifications(t), 2)
+
+		ws, err := connectAuthenticatedWS(t, "ws"+server.URL[4:]+"/subscribe")
+		require.NoError(t, err)
+		defer ws.Close()
+
+		msg := readWSMessageOfKind(t, ws, types.MessageKindNotifications)
+		var delivered types.Notifications
+		decodeMessageValue(t, msg, &delivered)
+		assert.Equal(t, notifications, delivered.Notifications)
+
+		rr = execAuthenticatedRequest(t, http.MethodPatch, "/api/notifications/read", nil)
+		require.Equal(t, http.StatusNoContent, rr.Code)
+
+		assert.Empty(t, getNotifications(t))
+	})
+
+	t.Run("does not notify users who stopped following the room", func(t *testing.T) {
+		truncateData(t)
+
+		room := createAndGetRoom(t)
+		roomURL := baseURL + strconv.Itoa(int(room.ID))
+		owner := createOtherUser(t, "owner@example.com")
+		setRoomOwner(t, room.ID, owner.ID.String())
+
+		rr := execAuthenticatedRequest(t, http.MethodPost, roomURL+"/follow", nil)
+		require.Equal(t, http.StatusNoContent, rr.Code)
+
+		rr = execAuthenticatedRequest(t, http.MethodDelete, roomURL+"/follow", nil)
+		require.Equal(t, http.StatusNoContent, rr.Code)
+		setRoomStatus(t, room.ID, service.RoomStatusDraft, nil, nil)
+
+		rr = execRequestGeneratingSession(t, http.MethodPatch, roomURL+"/status", strings.NewReader(`{"status": "open"}`), &owner)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		assert.Empty(t, getNotifications(t))
+	})
+
+	truncateData(t)
+	room := createAndGetRoom(t)
+	roomURL := baseURL + strconv.Itoa(int(room.ID)) + "/follow"
+
+	errorTestCases := []struct {
+		name               string
+		fn                 customFn
+		expectedMessage    string
+		expectedStatusCode int
+		url                string
+		setConstraint      func(t *testing.T)
+	}{
+		{
+			name: "returns unauthorized error if sessionID is not found",
+			fn: func(t testing.TB, method, url string, body io.Reader) *httptest.ResponseRecorder {
+				return execRequestWithoutCookie(method, url, body)
+			},
+			expectedMessage:    "unauthorized, session not found or invalid\n",
+			expectedStatusCode: http.StatusUnauthorized,
+			url:                roomURL,
+		},
+		{
+			name:               "returns an error if room id is not valid",
+			fn:                 execAuthenticatedRequest,
+			expectedMessage:    "invalid room id\n",
+			expectedStatusCode: http.StatusBadRequest,
+			url:                baseURL + "invalid_room_id/follow",
+		},
+		{
+			name:               "returns an error if room does not exist",
+			fn:                 execAuthenticatedRequest,
+			expectedMessage:    "room not found\n",
+			expectedStatusCode: http.StatusBadRequest,
+			url:                baseURL + "999999/follow",
+		},
+		{
+			name:               "returns an error if the user cannot access the private room",
+			fn:                 execAuthenticatedRequest,
+			expectedMessage:    "room not found\n",
+			expectedStatusCode: http.StatusBadRequest,
+			url:                roomURL,
+			setConstraint: func(t *testing.T) {
+				otherUser := createOtherUser(t, "other@example.com")
+				setRoomOwner(t, room.ID, otherUser.ID.String())
+				setRoomVisibility(t, room.ID, service.RoomVisibilityPrivate)
+			},
+		},
+	}
+
+	for _, tc := range errorTestCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.setConstraint != nil {
+				tc.setConstraint(t)
+			}
+
+			rr := tc.fn(t, http.MethodPost, tc.url, nil)
+			response := rr.Result()
+			defer response.Body.Close()
+
+			body := parseResponseBody(t, response)
+
+			assert.Equal(t, tc.expectedStatusCode, response.StatusCode)
+			assert.Equal(t, tc.expectedMessage, body)
+		})
+	}
+}
@@ -0,0 +1,251 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vhrboliveira/ama-go/internal/service"
+)
+
+func TestImportRoom(t *testing.T) {
+	type customFn func(t testing.TB, method string, url string, body io.Reader) *httptest.ResponseRecorder
+
+	type importResponse struct {
+		ID         int64  `json:"id"`
+		Name       string `json:"name"`
+		Slug       string `json:"slug"`
+		Status     string `json:"status"`
+		Visibility string `json:"visibility"`
+		Created    bool   `json:"created"`
+		Imported   int64  `json:"imported"`
+		Skipped    int64  `json:"skipped"`
+	}
+
+	type importedMessage struct {
+		Message   string
+		Answer    string
+		Answered  bool
+		CreatedAt time.Time
+	}
+
+	const (
+		url    = "/api/rooms/import"
+		method = http.MethodPost
+	)
+
+	exportJSON := `{
+		"room": {"id": 42, "name": "Weekly AMA", "description": "Ask away", "slug": "weekly-ama", "status": "closed"},
+		"messages": [
+			{"id": "c0a8bd2e-4e2b-4b8f-9d0c-2f9d7c6f5a10", "message": "first", "answer": "the answer", "answered": true, "created_at": "2024-05-01T10:00:00Z", "updated_at": "2024-05-01T10:05:00Z", "reaction_count": 3},
+			{"id": "6f1e7c3a-9b2d-4f6e-8a1c-3d5b7e9f0a21", "message": "second", "answer": "", "answered": false, "created_at": "2024-05-01T10:01:00Z", "updated_at": "2024-05-01T10:01:00Z", "reaction_count": 0}
+		]
+	}`
+
+	doImport := func(t *testing.T, query, payload string, expectedStatus int) importResponse {
+		t.Helper()
+
+		rr := execAuthenticatedRequest(t, method, url+query, strings.NewReader(payload))
+		require.Equal(t, expectedStatus, rr.Code, rr.Body.String())
+
+		var body importResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&body))
+		return body
+	}
+
+	getImportedMessages := func(t *testing.T, roomID int64) []importedMessage {
+		t.Helper()
+
+		rows, err := DBPool.Query(
+			context.Background(),
+			"SELECT message, answer, answered, created_at FROM messages WHERE room_id = $1 ORDER BY created_at",
+			roomID,
+		)
+		require.NoError(t, err)
+		defer rows.Close()
+
+		var messages []importedMessage
+		for rows.Next() {
+			var m importedMessage
+			require.NoError(t, rows.Scan(&m.Message, &m.Answer, &m.Answered, &m.CreatedAt))
+			messages = append(messages, m)
+		}
+		require.NoError(t, rows.Err())
+
+		return messages
+	}
+
+	t.Run("imports a JSON export into a new draft room and skips it when imported again", func(t *testing.T) {
+		truncateData(t)
+
+		body := doImport(t, "", exportJSON, http.StatusCreated)
+		assert.NotEqual(t, int64(42), body.ID)
+		assert.Equal(t, "Weekly AMA", body.Name)
+		assert.Equal(t, "weekly-ama", body.Slug)
+		assert.Equal(t, service.RoomStatusDraft, body.Status)
+		assert.Equal(t, service.RoomVisibilityPublic, body.Visibility)
+		assert.True(t, body.Created)
+		assert.Equal(t, int64(2), body.Imported)
+		assert.Equal(t, int64(0), body.Skipped)
+
+		other := createOtherUser(t, "other@example.com")
+		rr := execRequestGeneratingSession(t, http.MethodGet, "/api/rooms", nil, &other)
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.JSONEq(t, "[]", rr.Body.String(), "the imported draft is not listed to other users")
+
+		expected := []importedMessage{
+			{Message: "first", Answer: "the answer", Answered: true, CreatedAt: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)},
+			{Message: "second", CreatedAt: time.Date(2024, 5, 1, 10, 1, 0, 0, time.UTC)},
+		}
+		assert.Equal(t, expected, getImportedMessages(t, body.ID))
+
+		again := doImport(t, "", exportJSON, http.StatusOK)
+		assert.Equal(t, body.ID, again.ID)
+		assert.False(t, again.Created)
+		assert.Equal(t, int64(0), again.Imported)
+		assert.Equal(t, int64(2), again.Skipped)
+		assert.Len(t, getImportedMessages(t, body.ID), 2)
+	})
+
+	t.Run("imports a CSV export and only adds the new questions", func(t *testing.T) {
+		truncateData(t)
+
+		first := doImport(t, "", exportJSON, http.StatusCreated)
+
+		csv := "room_id,room_slug,room_name,id,message,answer,answered,created_at,updated_at,reaction_count\n" +
+			"42,weekly-ama,Weekly AMA,c0a8bd2e-4e2b-4b8f-9d0c-2f9d7c6f5a10,first,the answer,true,2024-05-01T10:00:00Z,2024-05-01T10:05:00Z,3\n" +
+			"42,weekly-ama,Weekly AMA,,\"third, with a comma\",,,2024-05-01T10:02:00Z,,\n"
+
+		body := doImport(t, "?format=csv", csv, http.StatusOK)
+		assert.Equal(t, first.ID, body.ID)
+		assert.Equal(t, int64(1), body.Imported)
+		assert.Equal(t, int64(1), body.Skipped)
+
+		messages := getImportedMessages(t, first.ID)
+		require.Len(t, messages, 3)
+		assert.Equal(t, importedMessage{Message: "third, with a comma", CreatedAt: time.Date(2024, 5, 1, 10, 2, 0, 0, time.UTC)}, messages[2])
+	})
+
+	t.Run("creates a room with another slug when the slug belongs to someone else", func(t *testing.T) {
+		truncateData(t)
+
+		room := createAndGetRoom(t)
+		setRoomSlug(t, room.ID, "weekly-ama")
+		otherUser := createOtherUser(t, "other@example.com")
+		setRoomOwner(t, room.ID, otherUser.ID.String())
+
+		body := doImport(t, "", exportJSON, http.StatusCreated)
+		assert.NotEqual(t, room.ID, body.ID)
+		assert.Equal(t, "weekly-ama-2", body.Slug)
+
+		again := doImport(t, "", exportJSON, http.StatusOK)
+		assert.Equal(t, body.ID, again.ID)
+		assert.Equal(t, int64(2), again.Skipped)
+		assert.Empty(t, getImportedMessages(t, room.ID))
+	})
+
+	t.Run("imports into the room after it is renamed", func(t *testing.T) {
+		truncateData(t)
+
+		body := doImport(t, "", exportJSON, http.StatusCreated)
+
+		roomURL := "/api/rooms/" + strconv.Itoa(int(body.ID))
+		rr := execAuthenticatedRequest(t, http.MethodPatch, roomURL, strings.NewReader(`{"slug": "monthly-ama"}`))
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		again := doImport(t, "", exportJSON, http.StatusOK)
+		assert.Equal(t, body.ID, again.ID)
+		assert.Equal(t, "monthly-ama", again.Slug)
+	})
+
+	errorTestCases := []struct {
+		name               string
+		fn                 customFn
+		url                string
+		payload            string
+		expectedMessage    string
+		expectedStatusCode int
+	}{
+		{
+			name: "returns unauthorized error if sessionID is not found",
+			fn: func(t testing.TB, method, url string, body io.Reader) *httptest.ResponseRecorder {
+				return execRequestWithoutCookie(method, url, body)
+			},
+			url:                url,
+			payload:            exportJSON,
+			expectedMessage:    "unauthorized, session not found or invalid\n",
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:               "returns an error if the format is not valid",
+			fn:                 execAuthenticatedRequest,
+			url:                url + "?format=md",
+			payload:            exportJSON,
+			expectedMessage:    "validation failed: format must be one of: json, csv\n",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "returns an error if the JSON is not valid",
+			fn:                 execAuthenticatedRequest,
+			url:                url,
+			payload:            `{"room": `,
+			expectedMessage:    "validation failed: invalid json: unexpected EOF\n",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "returns an error if the room has no name",
+			fn:                 execAuthenticatedRequest,
+			url:                url,
+			payload:            `{"room": {"slug": "weekly-ama"}, "messages": []}`,
+			expectedMessage:    "validation failed: room name is required\n",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "returns an error if a question is empty",
+			fn:                 execAuthenticatedRequest,
+			url:                url,
+			payload:            `{"room": {"name": "Weekly AMA"}, "messages": [{"message": "first"}, {"message": ""}]}`,
+			expectedMessage:    "validation failed: question 2 is empty\n",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "returns an error if a question has an invalid time",
+			fn:                 execAuthenticatedRequest,
+			url:                url,
+			payload:            `{"room": {"name": "Weekly AMA"}, "messages": [{"message": "first", "created_at": "yesterday"}]}`,
+			expectedMessage:    "validation failed: question 1 has an invalid created_at, it must be an RFC 3339 time\n",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "returns an error if the CSV has no message column",
+			fn:                 execAuthenticatedRequest,
+			url:                url + "?format=csv",
+			payload:            "room_name,question\nWeekly AMA,first\n",
+			expectedMessage:    "validation failed: csv is missing the message column\n",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range errorTestCases {
+		t.Run(tc.name, func(t *testing.T) {
+			truncateData(t)
+
+			rr := tc.fn(t, method, tc.url, strings.NewReader(tc.payload))
+			response := rr.Result()
+			defer response.Body.Close()
+
+			body := parseResponseBody(t, response)
+
+			assert.Equal(t, tc.expectedStatusCode, response.StatusCode)
+			assert.Equal(t, tc.expectedMessage, body)
+		})
+	}
+}
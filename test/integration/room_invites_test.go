@@ -0,0 +1,305 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vhrboliveira/ama-go/internal/store/pgstore"
+)
+
+func createInvite(t testing.TB, roomID int64, payload string) pgstore.RoomInvite {
+	t.Helper()
+
+	url := "/api/rooms/" + strconv.Itoa(int(roomID)) + "/invites"
+	rr := execAuthenticatedRequest(t, http.MethodPost, url, strings.NewReader(payload))
+	require.Equal(t, http.StatusCreated, rr.Code)
+
+	var invite pgstore.RoomInvite
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&invite))
+
+	return invite
+}
+
+func getInviteUses(t testing.TB, token string) int32 {
+	t.Helper()
+
+	var uses int32
+	err := DBPool.QueryRow(context.Background(), "SELECT uses FROM room_invites WHERE token = $1", token).Scan(&uses)
+	require.NoError(t, err)
+
+	return uses
+}
+
+func TestRoomInvites(t *testing.T) {
+	setupPrivateRoom := func(t *testing.T) (pgstore.Room, string) {
+		t.Helper()
+		truncateData(t)
+
+		room := createAndGetRoom(t)
+		setRoomVisibility(t, room.ID, "private")
+
+		return room, "/api/rooms/" + strconv.Itoa(int(room.ID))
+	}
+
+	acceptURL := func(token string) string {
+		return "/api/invites/" + token + "/accept"
+	}
+
+	t.Run("creates an invite with the given restrictions", func(t *testing.T) {
+		room, _ := setupPrivateRoom(t)
+
+		expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+		invite := createInvite(t, room.ID, `{"expires_at": "`+expiresAt.Format(time.RFC3339)+`", "max_uses": 5, "email_domains": ["@Example.com", "example.com", "ama.dev"]}`)
+
+		assert.NotEmpty(t, invite.Token)
+		assert.Equal(t, room.ID, invite.RoomID)
+		assert.True(t, invite.ExpiresAt.Valid)
+		assert.True(t, expiresAt.Equal(invite.ExpiresAt.Time))
+		assert.Equal(t, int32(5), invite.MaxUses.Int32)
+		assert.Equal(t, int32(0), invite.Uses)
+		assert.Equal(t, []string{"example.com", "ama.dev"}, invite.EmailDomains)
+	})
+
+	t.Run("lists and deletes the room invites", func(t *testing.T) {
+		room, roomURL := setupPrivateRoom(t)
+
+		first := createInvite(t, room.ID, `{}`)
+		second := createInvite(t, room.ID, `{"max_uses": 1}`)
+
+		rr := execAuthenticatedRequest(t, http.MethodGet, roomURL+"/invites", nil)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var invites []pgstore.RoomInvite
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&invites))
+		assert.ElementsMatch(t, []string{first.Token, second.Token}, []string{invites[0].Token, invites[1].Token})
+
+		rr = execAuthenticatedRequest(t, http.MethodDelete, roomURL+"/invites/"+first.Token, nil)
+		assert.Equal(t, http.StatusNoContent, rr.Code)
+
+		rr = execAuthenticatedRequest(t, http.MethodDelete, roomURL+"/invites/"+first.Token, nil)
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		assert.Equal(t, "invite not found\n", rr.Body.String())
+	})
+
+	t.Run("gives access to the private room once accepted", func(t *testing.T) {
+		room, roomURL := setupPrivateRoom(t)
+		other := createOtherUser(t, "other@example.com")
+		invite := createInvite(t, room.ID, `{"max_uses": 1}`)
+
+		rr := execRequestGeneratingSession(t, http.MethodGet, roomURL, nil, &other)
+		require.Equal(t, http.StatusNotFound, rr.Code)
+
+		rr = execRequestGeneratingSession(t, http.MethodPost, acceptURL(invite.Token), nil, &other)
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.JSONEq(t, `{"room_id": `+strconv.Itoa(int(room.ID))+`}`, rr.Body.String())
+
+		rr = execRequestGeneratingSession(t, http.MethodGet, roomURL, nil, &other)
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		rr = execRequestGeneratingSession(t, http.MethodGet, roomURL+"/messages", nil, &other)
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		rr = execRequestGeneratingSession(t, http.MethodGet, "/api/rooms", nil, &other)
+		var rooms []pgstore.GetRoomsRow
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&rooms))
+		require.Len(t, rooms, 1)
+		assert.Equal(t, room.ID, rooms[0].ID)
+
+		// accepting again does not use up the invite
+		rr = execRequestGeneratingSession(t, http.MethodPost, acceptURL(invite.Token), nil, &other)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, int32(1), getInviteUses(t, invite.Token))
+	})
+
+	t.Run("does not use up the invite when the owner accepts it", func(t *testing.T) {
+		room, _ := setupPrivateRoom(t)
+		invite := createInvite(t, room.ID, `{"max_uses": 1}`)
+
+		rr := execAuthenticatedRequest(t, http.MethodPost, acceptURL(invite.Token), nil)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, int32(0), getInviteUses(t, invite.Token))
+	})
+
+	t.Run("lets members in with an invite that was used up", func(t *testing.T) {
+		room, _ := setupPrivateRoom(t)
+		other := createOtherUser(t, "other@example.com")
+		addRoomMember(t, room.ID, other.ID.String(), "moderator")
+		invite := createInvite(t, room.ID, `{"max_uses": 1}`)
+
+		_, err := DBPool.Exec(context.Background(), "UPDATE room_invites SET uses = 1 WHERE token = $1", invite.Token)
+		require.NoError(t, err)
+
+		rr := execRequestGeneratingSession(t, http.MethodPost, acceptURL(invite.Token), nil, &other)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.JSONEq(t, `{"room_id": `+strconv.Itoa(int(room.ID))+`}`, rr.Body.String())
+		assert.Equal(t, int32(1), getInviteUses(t, invite.Token))
+	})
+
+	t.Run("returns an error if the invite is not valid anymore", func(t *testing.T) {
+		tests := []struct {
+			name               string
+			payload            string
+			prepare            func(t *testing.T, invite pgstore.RoomInvite)
+			expectedMessage    string
+			expectedStatusCode int
+		}{
+			{
+				name:    "expired",
+				payload: `{}`,
+				prepare: func(t *testing.T, invite pgstore.RoomInvite) {
+					_, err := DBPool.Exec(context.Background(), "UPDATE room_invites SET expires_at = NOW() - INTERVAL '1 minute' WHERE token = $1", invite.Token)
+					require.NoError(t, err)
+				},
+				expectedMessage:    "the invite has expired\n",
+				expectedStatusCode: http.StatusGone,
+			},
+			{
+				name:    "usage limit reached",
+				payload: `{"max_uses": 1}`,
+				prepare: func(t *testing.T, invite pgstore.RoomInvite) {
+					_, err := DBPool.Exec(context.Background(), "UPDATE room_invites SET uses = 1 WHERE token = $1", invite.Token)
+					require.NoError(t, err)
+				},
+				expectedMessage:    "the invite has reached its usage limit\n",
+				expectedStatusCode: http.StatusGone,
+			},
+			{
+				name:               "email domain not allowed",
+				payload:            `{"email_domains": ["ama.dev"]}`,
+				expectedMessage:    "your email domain is not allowed to accept this invite\n",
+				expectedStatusCode: http.StatusForbidden,
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				room, _ := setupPrivateRoom(t)
+				other := createOtherUser(t, "other@example.com")
+				invite := createInvite(t, room.ID, tt.payload)
+				if tt.prepare != nil {
+					tt.prepare(t, invite)
+				}
+
+				rr := execRequestGeneratingSession(t, http.MethodPost, acceptURL(invite.Token), nil, &other)
+				assert.Equal(t, tt.expectedStatusCode, rr.Code)
+				assert.Equal(t, tt.expectedMessage, rr.Body.String())
+			})
+		}
+	})
+
+	room, roomURL := setupPrivateRoom(t)
+	invite := createInvite(t, room.ID, `{}`)
+	other := createOtherUser(t, "other@example.com")
+	pastDate := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+
+	errorTestCases := []struct {
+		name               string
+		method             string
+		url                string
+		payload            string
+		user               *pgstore.User
+		expectedMessage    string
+		expectedStatusCode int
+	}{
+		{
+			name:               "returns an error if room id is not valid",
+			method:             http.MethodPost,
+			url:                "/api/rooms/invalid_room_id/invites",
+			payload:            `{}`,
+			expectedMessage:    "invalid room id\n",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "returns an error if body is not valid",
+			method:             http.MethodPost,
+			url:                roomURL + "/invites",
+			payload:            `{"max_uses": "one"}`,
+			expectedMessage:    "invalid body\n",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "returns an error if expires_at is in the past",
+			method:             http.MethodPost,
+			url:                roomURL + "/invites",
+			payload:            `{"expires_at": "` + pastDate + `"}`,
+			expectedMessage:    "validation failed: expires_at must be in the future\n",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "returns an error if max_uses is lower than 1",
+			method:             http.MethodPost,
+			url:                roomURL + "/invites",
+			payload:            `{"max_uses": 0}`,
+			expectedMessage:    "validation failed: max_uses must be at least 1\n",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "returns an error if an email domain is not valid",
+			method:             http.MethodPost,
+			url:                roomURL + "/invites",
+			payload:            `{"email_domains": ["user@example.com"]}`,
+			expectedMessage:    "validation failed: invalid email domain: user@example.com\n",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "returns an error if a non owner creates an invite",
+			method:             http.MethodPost,
+			url:                roomURL + "/invites",
+			payload:            `{}`,
+			user:               &other,
+			expectedMessage:    "only the room owner can change the room\n",
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:               "returns an error if a non owner lists the invites",
+			method:             http.MethodGet,
+			url:                roomURL + "/invites",
+			user:               &other,
+			expectedMessage:    "only the room owner can change the room\n",
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:               "returns an error if a non owner deletes an invite",
+			method:             http.MethodDelete,
+			url:                roomURL + "/invites/" + invite.Token,
+			user:               &other,
+			expectedMessage:    "only the room owner can change the room\n",
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:               "returns an error if the invite does not exist",
+			method:             http.MethodPost,
+			url:                acceptURL("unknown"),
+			user:               &other,
+			expectedMessage:    "invite not found\n",
+			expectedStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range errorTestCases {
+		t.Run(tc.name, func(t *testing.T) {
+			payload := strings.NewReader(tc.payload)
+
+			exec := execAuthenticatedRequest
+			if tc.user != nil {
+				user := tc.user
+				exec = func(t testing.TB, method, url string, body io.Reader) *httptest.ResponseRecorder {
+					return execRequestGeneratingSession(t, method, url, body, user)
+				}
+			}
+
+			response := exec(t, tc.method, tc.url, payload)
+			assert.Equal(t, tc.expectedStatusCode, response.Code)
+			assert.Equal(t, tc.expectedMessage, response.Body.String())
+		})
+	}
+}
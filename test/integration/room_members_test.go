@@ -0,0 +1,290 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vhrboliveira/ama-go/internal/store/pgstore"
+	"github.com/vhrboliveira/ama-go/internal/types"
+)
+
+func addRoomMember(t testing.TB, roomID int64, userID, role string) {
+	t.Helper()
+
+	_, err := DBPool.Exec(context.Background(), "INSERT INTO room_members (room_id, user_id, role) VALUES ($1, $2, $3)", roomID, userID, role)
+	require.NoError(t, err)
+}
+
+func TestRoomMembers(t *testing.T) {
+	const baseURL = "/api/rooms/"
+
+	t.Run("adds, lists and removes the room members", func(t *testing.T) {
+		truncateData(t)
+
+		room := createAndGetRoom(t)
+		other := createOtherUser(t, "other@example.com")
+		membersURL := baseURL + strconv.Itoa(int(room.ID)) + "/members"
+
+		rr := execAuthenticatedRequest(t, http.MethodPost, membersURL, strings.NewReader(`{"email": "other@example.com", "role": "moderator"}`))
+		require.Equal(t, http.StatusCreated, rr.Code)
+
+		var member pgstore.RoomMember
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&member))
+		assert.Equal(t, room.ID, member.RoomID)
+		assert.Equal(t, other.ID, member.UserID)
+		assert.Equal(t, "moderator", member.Role)
+
+		// adding the member again changes the role
+		rr = execAuthenticatedRequest(t, http.MethodPost, membersURL, strings.NewReader(`{"email": "other@example.com", "role": "panelist"}`))
+		require.Equal(t, http.StatusCreated, rr.Code)
+
+		_, err := DBPool.Exec(context.Background(), "UPDATE users SET photo = 'http://avatar.com/other.jpg', enable_picture = false WHERE id = $1", other.ID)
+		require.NoError(t, err)
+
+		rr = execRequestGeneratingSession(t, http.MethodGet, membersURL, nil, &other)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var members []pgstore.GetRoomMembersRow
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&members))
+		require.Len(t, members, 1)
+		assert.Equal(t, other.ID, members[0].UserID)
+		assert.Equal(t, "Other User", members[0].Name)
+		assert.Equal(t, "panelist", members[0].Role)
+		assert.False(t, members[0].Photo.Valid, "the photo is hidden unless the user shows it")
+
+		rr = execAuthenticatedRequest(t, http.MethodDelete, membersURL+"/"+other.ID.String(), nil)
+		assert.Equal(t, http.StatusNoContent, rr.Code)
+
+		rr = execAuthenticatedRequest(t, http.MethodDelete, membersURL+"/"+other.ID.String(), nil)
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		assert.Equal(t, "member not found\n", rr.Body.String())
+	})
+
+	t.Run("lets moderators and panelists answer questions", func(t *testing.T) {
+		for _, role := range []string{"moderator", "panelist"} {
+			t.Run(role, func(t *testing.T) {
+				truncateData(t)
+
+				room := createAndGetRoom(t)
+				msgID, _ := createAndGetMessages(t, room.ID)
+				member := createOtherUser(t, "member@example.com")
+				addRoomMember(t, room.ID, member.ID.String(), role)
+
+				url := baseURL + strconv.Itoa(int(room.ID)) + "/messages/" + msgID + "/answer"
+				payload := strings.NewReader(`{"user_id": "` + member.ID.String() + `", "answer": "the answer"}`)
+				rr := execRequestGeneratingSession(t, http.MethodPatch, url, payload, &member)
+				assert.Equal(t, http.StatusOK, rr.Code)
+			})
+		}
+	})
+
+	t.Run("gives the members access to private rooms", func(t *testing.T) {
+		truncateData(t)
+
+		room := createAndGetRoom(t)
+		setRoomVisibility(t, room.ID, "private")
+		member := createOtherUser(t, "member@example.com")
+		addRoomMember(t, room.ID, member.ID.String(), "moderator")
+
+		rr := execRequestGeneratingSession(t, http.MethodGet, baseURL+strconv.Itoa(int(room.ID)), nil, &member)
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("hides questions from the users without a role", func(t *testing.T) {
+		truncateData(t)
+
+		server := httptest.NewServer(Router)
+		defer server.Close()
+
+		room := createAndGetRoom(t)
+		msgID, _ := createAndGetMessages(t, room.ID)
+		roomURL := baseURL + strconv.Itoa(int(room.ID))
+		moderator := createOtherUser(t, "moderator@example.com")
+		addRoomMember(t, room.ID, moderator.ID.String(), "moderator")
+		viewerID := createUser(t, "viewer@example.com", "viewer", "google", "1122334455", "")
+		viewer := pgstore.User{ID: uuid.MustParse(viewerID), Email: "viewer@example.com"}
+
+		ws, err := connectAuthenticatedWS(t, "ws"+server.URL[4:]+"/subscribe/room/"+strconv.Itoa(int(room.ID)))
+		require.NoError(t, err)
+		defer ws.Close()
+		readWSMessageOfKind(t, ws, types.MessageKindRoomSnapshot)
+
+		rr := execRequestGeneratingSession(t, http.MethodPatch, roomURL+"/messages/"+msgID+"/hide", strings.NewReader(`{"hidden": true}`), &moderator)
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.JSONEq(t, `{"id": "`+msgID+`", "hidden": true}`, rr.Body.String())
+
+		msg := readWSMessageOfKind(t, ws, types.MessageKindMessageHidden)
+		var messageHidden types.MessageHidden
+		decodeMessageValue(t, msg, &messageHidden)
+		assert.Equal(t, types.MessageHidden{ID: msgID, Hidden: true}, messageHidden)
+
+		countMessages := func(user *pgstore.User) int {
+			rr := execRequestGeneratingSession(t, http.MethodGet, roomURL+"/messages", nil, user)
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			var messages []pgstore.GetRoomMessagesRow
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&messages))
+			return len(messages)
+		}
+
+		assert.Equal(t, 0, countMessages(&viewer))
+		assert.Equal(t, 1, countMessages(&moderator))
+
+		rr = execRequestGeneratingSession(t, http.MethodGet, roomURL+"/messages/"+msgID, nil, &viewer)
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+
+		rr = execRequestGeneratingSession(t, http.MethodGet, roomURL+"/messages/"+msgID, nil, &moderator)
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		rr = execAuthenticatedRequest(t, http.MethodPatch, roomURL+"/messages/"+msgID+"/hide", strings.NewReader(`{"hidden": false}`))
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, 1, countMessages(&viewer))
+	})
+
+	t.Run("does not act on messages of other rooms", func(t *testing.T) {
+		truncateData(t)
+
+		createRooms(t, []string{"moderated", "other"})
+		moderated := getRoomByName(t, "moderated")
+		other := getRoomByName(t, "other")
+		msgID, _ := createAndGetMessages(t, other.ID)
+
+		url := baseURL + strconv.Itoa(int(moderated.ID)) + "/messages/" + msgID + "/hide"
+		rr := execAuthenticatedRequest(t, http.MethodPatch, url, strings.NewReader(`{"hidden": true}`))
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		assert.Equal(t, "message not found\n", rr.Body.String())
+	})
+
+	truncateData(t)
+	room := createAndGetRoom(t)
+	roomURL := baseURL + strconv.Itoa(int(room.ID))
+	msgID, _ := createAndGetMessages(t, room.ID)
+	stranger := createOtherUser(t, "stranger@example.com")
+	createUser(t, "member@example.com", "member", "google", "5544332211", "")
+	ownerID := room.UserID.String()
+
+	errorTestCases := []struct {
+		name               string
+		method             string
+		url                string
+		payload            string
+		user               *pgstore.User
+		expectedMessage    string
+		expectedStatusCode int
+	}{
+		{
+			name:               "returns an error if the member email is missing",
+			method:             http.MethodPost,
+			url:                roomURL + "/members",
+			payload:            `{"role": "moderator"}`,
+			expectedMessage:    "validation failed, missing required field(s): Email\n",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "returns an error if the member email is not valid",
+			method:             http.MethodPost,
+			url:                roomURL + "/members",
+			payload:            `{"email": "member", "role": "moderator"}`,
+			expectedMessage:    "validation failed: Email must be a valid email address\n",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "returns an error if the role is not valid",
+			method:             http.MethodPost,
+			url:                roomURL + "/members",
+			payload:            `{"email": "member@example.com", "role": "owner"}`,
+			expectedMessage:    "validation failed: Role must be one of: moderator, panelist\n",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "returns an error if the user does not exist",
+			method:             http.MethodPost,
+			url:                roomURL + "/members",
+			payload:            `{"email": "nobody@example.com", "role": "moderator"}`,
+			expectedMessage:    "user not found\n",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "returns an error if the owner is added as a member",
+			method:             http.MethodPost,
+			url:                roomURL + "/members",
+			payload:            `{"email": "` + mockGothUser(nil).Email + `", "role": "moderator"}`,
+			expectedMessage:    "the room owner cannot be added as a member\n",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "returns an error if a non owner adds a member",
+			method:             http.MethodPost,
+			url:                roomURL + "/members",
+			payload:            `{"email": "member@example.com", "role": "moderator"}`,
+			user:               &stranger,
+			expectedMessage:    "only the room owner can change the room\n",
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:               "returns an error if a non owner removes a member",
+			method:             http.MethodDelete,
+			url:                roomURL + "/members/" + ownerID,
+			user:               &stranger,
+			expectedMessage:    "only the room owner can change the room\n",
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:               "returns an error if the member id is not valid",
+			method:             http.MethodDelete,
+			url:                roomURL + "/members/invalid_user_id",
+			expectedMessage:    "invalid user id\n",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "returns an error if hidden is missing",
+			method:             http.MethodPatch,
+			url:                roomURL + "/messages/" + msgID + "/hide",
+			payload:            `{}`,
+			expectedMessage:    "validation failed, missing required field(s): Hidden\n",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "returns an error if a user without a role hides a message",
+			method:             http.MethodPatch,
+			url:                roomURL + "/messages/" + msgID + "/hide",
+			payload:            `{"hidden": true}`,
+			user:               &stranger,
+			expectedMessage:    "only the room owner, moderators and panelists can hide questions\n",
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:               "returns an error if a user without a role answers a message",
+			method:             http.MethodPatch,
+			url:                roomURL + "/messages/" + msgID + "/answer",
+			payload:            `{"user_id": "` + stranger.ID.String() + `", "answer": "answer"}`,
+			user:               &stranger,
+			expectedMessage:    "only the room owner, moderators and panelists can answer questions\n",
+			expectedStatusCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range errorTestCases {
+		t.Run(tc.name, func(t *testing.T) {
+			payload := strings.NewReader(tc.payload)
+
+			var rr *httptest.ResponseRecorder
+			if tc.user != nil {
+				rr = execRequestGeneratingSession(t, tc.method, tc.url, payload, tc.user)
+			} else {
+				rr = execAuthenticatedRequest(t, tc.method, tc.url, payload)
+			}
+
+			assert.Equal(t, tc.expectedStatusCode, rr.Code)
+			assert.Equal(t, tc.expectedMessage, rr.Body.String())
+		})
+	}
+}
@@ -0,0 +1,364 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vhrboliveira/ama-go/internal/service"
+	"github.com/vhrboliveira/ama-go/internal/store/pgstore"
+	"github.com/vhrboliveira/ama-go/internal/types"
+)
+
+// setRoomSetting sets a single setting column of the room as is, skipping the
+// validations.
+func setRoomSetting(t testing.TB, roomID int64, setting string, value any) {
+	t.Helper()
+
+	_, err := DBPool.Exec(context.Background(), "UPDATE rooms SET "+setting+" = $1 WHERE id = $2", value, roomID)
+	require.NoError(t, err)
+}
+
+func TestRoomSettings(t *testing.T) {
+	type customFn func(t testing.TB, method string, url string, body io.Reader) *httptest.ResponseRecorder
+
+	const (
+		baseURL = "/api/rooms/"
+		method  = http.MethodPatch
+	)
+
+	t.Run("updates the settings and notifies the room subscribers", func(t *testing.T) {
+		truncateData(t)
+
+		server := httptest.NewServer(Router)
+		defer server.Close()
+
+		room := createAndGetRoom(t)
+		roomID := strconv.Itoa(int(room.ID))
+
+		ws, err := connectAuthenticatedWS(t, "ws"+server.URL[4:]+"/subscribe/room/"+roomID)
+		require.NoError(t, err)
+		defer ws.Close()
+		readWSMessageOfKind(t, ws, types.MessageKindRoomSnapshot)
+
+		payload := `{"max_question_length": 500, "slow_mode_seconds": 30, "reactions_allowed": false}`
+		rr := execAuthenticatedRequest(t, method, baseURL+roomID+"/settings", strings.NewReader(payload))
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var body types.RoomSettingsUpdated
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&body))
+
+		expected := types.RoomSettingsUpdated{
+			ID:                 room.ID,
+			AnonymousQuestions: true,
+			MaxQuestionLength:  500,
+			SlowModeSeconds:    30,
+			ReactionsAllowed:   false,
+			PublicRead:         false,
+			UpdatedAt:          body.UpdatedAt,
+		}
+		assert.Equal(t, expected, body)
+		assertValidDate(t, body.UpdatedAt)
+
+		msg := readWSMessageOfKind(t, ws, types.MessageKindRoomSettingsUpdated)
+		var settingsUpdated types.RoomSettingsUpdated
+		decodeMessageValue(t, msg, &settingsUpdated)
+		assert.Equal(t, expected, settingsUpdated)
+
+		rr = execAuthenticatedRequest(t, http.MethodGet, baseURL+roomID, nil)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var roomBody pgstore.GetRoomWithUserRow
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&roomBody))
+		assert.Equal(t, int32(500), roomBody.MaxQuestionLength)
+		assert.Equal(t, int32(30), roomBody.SlowModeSeconds)
+		assert.False(t, roomBody.ReactionsAllowed)
+	})
+
+	t.Run("rejects questions longer than the room allows", func(t *testing.T) {
+		truncateData(t)
+
+		room := createAndGetRoom(t)
+		setRoomSetting(t, room.ID, "max_question_length", 10)
+		url := baseURL + strconv.Itoa(int(room.ID)) + "/messages"
+
+		rr := execAuthenticatedRequest(t, http.MethodPost, url, strings.NewReader(`{"message": "ten chars!"}`))
+		assert.Equal(t, http.StatusCreated, rr.Code)
+
+		rr = execAuthenticatedRequest(t, http.MethodPost, url, strings.NewReader(`{"message": "eleven chars"}`))
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Equal(t, "validation failed: message must have at most 10 characters\n", rr.Body.String())
+	})
+
+	t.Run("rejects anonymous questions when the room does not allow them", func(t *testing.T) {
+		truncateData(t)
+
+		room := createAndGetRoom(t)
+		setRoomSetting(t, room.ID, "anonymous_questions", false)
+		url := baseURL + strconv.Itoa(int(room.ID)) + "/messages"
+
+		rr := execAuthenticatedRequest(t, http.MethodPost, url, strings.NewReader(`{"message": "guess who", "anonymous": true}`))
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Equal(t, "validation failed: this room does not accept anonymous questions\n", rr.Body.String())
+
+		rr = execAuthenticatedRequest(t, http.MethodPost, url, strings.NewReader(`{"message": "it was me"}`))
+		assert.Equal(t, http.StatusCreated, rr.Code)
+	})
+
+	t.Run("accepts questions longer than the old limit", func(t *testing.T) {
+		truncateData(t)
+
+		room := createAndGetRoom(t)
+		setRoomSetting(t, room.ID, "max_question_length", service.MaxQuestionLengthLimit)
+		url := baseURL + strconv.Itoa(int(room.ID)) + "/messages"
+
+		payload := `{"message": "` + strings.Repeat("a", service.MaxQuestionLengthLimit) + `"}`
+		rr := execAuthenticatedRequest(t, http.MethodPost, url, strings.NewReader(payload))
+		assert.Equal(t, http.StatusCreated, rr.Code)
+	})
+
+	t.Run("slows down askers but not the room hosts", func(t *testing.T) {
+		truncateData(t)
+
+		room := createAndGetRoom(t)
+		owner := createOtherUser(t, "owner@example.com")
+		setRoomOwner(t, room.ID, owner.ID.String())
+		setRoomSetting(t, room.ID, "slow_mode_seconds", 60)
+		url := baseURL + strconv.Itoa(int(room.ID)) + "/messages"
+
+		rr := execAuthenticatedRequest(t, http.MethodPost, url, strings.NewReader(`{"message": "first"}`))
+		require.Equal(t, http.StatusCreated, rr.Code)
+
+		rr = execAuthenticatedRequest(t, http.MethodPost, url, strings.NewReader(`{"message": "second"}`))
+		assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+		assert.Contains(t, rr.Body.String(), "slow mode is on, wait")
+		retryAfter, err := strconv.Atoi(rr.Header().Get("Retry-After"))
+		require.NoError(t, err)
+		assert.InDelta(t, 60, retryAfter, 2)
+
+		for _, msg := range []string{"owner first", "owner second"} {
+			rr = execRequestGeneratingSession(t, http.MethodPost, url, strings.NewReader(`{"message": "`+msg+`"}`), &owner)
+			assert.Equal(t, http.StatusCreated, rr.Code)
+		}
+	})
+
+	t.Run("does not slow down askers whose question was not inserted", func(t *testing.T) {
+		truncateData(t)
+
+		room := createAndGetRoom(t)
+		owner := createOtherUser(t, "owner@example.com")
+		setRoomOwner(t, room.ID, owner.ID.String())
+		setRoomSetting(t, room.ID, "slow_mode_seconds", 60)
+		url := baseURL + strconv.Itoa(int(room.ID)) + "/messages"
+
+		// Postgres rejects the NUL character in text columns
+		rr := execAuthenticatedRequest(t, http.MethodPost, url, strings.NewReader(`{"message": "broken \u0000"}`))
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		assert.Equal(t, "error inserting message\n", rr.Body.String())
+
+		rr = execAuthenticatedRequest(t, http.MethodPost, url, strings.NewReader(`{"message": "first"}`))
+		assert.Equal(t, http.StatusCreated, rr.Code)
+	})
+
+	t.Run("rejects reactions when the room does not allow them", func(t *testing.T) {
+		truncateData(t)
+
+		room := createAndGetRoom(t)
+		msgID, _ := createAndGetMessages(t, room.ID)
+		setRoomSetting(t, room.ID, "reactions_allowed", false)
+
+		userID := generateUser(t)
+		url := baseURL + strconv.Itoa(int(room.ID)) + "/messages/" + msgID + "/react"
+		rr := execAuthenticatedRequest(t, http.MethodPatch, url, strings.NewReader(`{"user_id": "`+userID+`"}`))
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.Equal(t, "reactions are disabled in this room\n", rr.Body.String())
+	})
+
+	t.Run("lets visitors read the rooms that allow it", func(t *testing.T) {
+		truncateData(t)
+
+		room := createAndGetRoom(t)
+		msgID, _ := createAndGetMessages(t, room.ID)
+		roomURL := baseURL + strconv.Itoa(int(room.ID))
+		setRoomSlug(t, room.ID, "open-to-all")
+
+		readURLs := []string{roomURL, roomURL + "/messages", roomURL + "/messages/" + msgID, baseURL + "open-to-all"}
+
+		for _, url := range readURLs {
+			rr := execRequestWithoutCookie(http.MethodGet, url, nil)
+			assert.Equal(t, http.StatusUnauthorized, rr.Code, url)
+		}
+
+		setRoomSetting(t, room.ID, "public_read", true)
+
+		for _, url := range readURLs {
+			rr := execRequestWithoutCookie(http.MethodGet, url, nil)
+			assert.Equal(t, http.StatusOK, rr.Code, url)
+		}
+
+		_, err := DBPool.Exec(context.Background(), "UPDATE users SET enable_picture = false WHERE id = $1", room.UserID)
+		require.NoError(t, err)
+
+		rr := execRequestWithoutCookie(http.MethodGet, roomURL, nil)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var roomBody map[string]any
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&roomBody))
+		assert.NotContains(t, roomBody, "email", "visitors must not see the email of the creator")
+		assert.Nil(t, roomBody["photo"], "the photo is hidden unless the creator shows it")
+
+		rr = execRequestWithoutCookie(http.MethodPost, roomURL+"/messages", strings.NewReader(`{"message": "visitor"}`))
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+
+		rr = execRequestWithoutCookie(http.MethodGet, roomURL+"/reactions", nil)
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+
+		setRoomVisibility(t, room.ID, service.RoomVisibilityPrivate)
+
+		rr = execRequestWithoutCookie(http.MethodGet, roomURL, nil)
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("lets visitors follow the rooms that allow it live", func(t *testing.T) {
+		truncateData(t)
+
+		server := httptest.NewServer(Router)
+		defer server.Close()
+
+		room := createAndGetRoom(t)
+		wsURL := "ws" + server.URL[4:] + "/subscribe/room/" + strconv.Itoa(int(room.ID))
+		sseURL := server.URL + "/subscribe/sse/room/" + strconv.Itoa(int(room.ID))
+
+		_, res, err := websocket.DefaultDialer.Dial(wsURL, nil)
+		require.Error(t, err)
+		assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+		res, err = http.Get(sseURL)
+		require.NoError(t, err)
+		res.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+		setRoomSetting(t, room.ID, "public_read", true)
+
+		ws, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+		require.NoError(t, err)
+		defer ws.Close()
+		readWSMessageOfKind(t, ws, types.MessageKindRoomSnapshot)
+
+		// visitors only read the room
+		sendWSCommand(t, ws, types.CommandKindAsk, "req-1", map[string]string{"message": "visitor"})
+		var commandError types.CommandError
+		decodeMessageValue(t, readWSMessageOfKind(t, ws, types.MessageKindCommandError), &commandError)
+		assert.Equal(t, http.StatusBadRequest, commandError.Status)
+		assert.Equal(t, "commands are not supported on this subscription", commandError.Error)
+
+		res, err = http.Get(sseURL)
+		require.NoError(t, err)
+		res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+	})
+
+	truncateData(t)
+	room := createAndGetRoom(t)
+	roomURL := baseURL + strconv.Itoa(int(room.ID)) + "/settings"
+
+	errorTestCases := []struct {
+		name               string
+		fn                 customFn
+		payload            string
+		expectedMessage    string
+		expectedStatusCode int
+		url                string
+		setConstraint      func(t *testing.T)
+	}{
+		{
+			name: "returns unauthorized error if sessionID is not found",
+			fn: func(t testing.TB, method, url string, body io.Reader) *httptest.ResponseRecorder {
+				return execRequestWithoutCookie(method, url, body)
+			},
+			payload:            `{"public_read": true}`,
+			expectedMessage:    "unauthorized, session not found or invalid\n",
+			expectedStatusCode: http.StatusUnauthorized,
+			url:                roomURL,
+		},
+		{
+			name:               "returns an error if room id is not valid",
+			fn:                 execAuthenticatedRequest,
+			payload:            `{"public_read": true}`,
+			expectedMessage:    "invalid room id\n",
+			expectedStatusCode: http.StatusBadRequest,
+			url:                baseURL + "invalid_room_id/settings",
+		},
+		{
+			name:               "returns an error if body is not valid",
+			fn:                 execAuthenticatedRequest,
+			payload:            `{"public_read": "yes"}`,
+			expectedMessage:    "invalid body\n",
+			expectedStatusCode: http.StatusBadRequest,
+			url:                roomURL,
+		},
+		{
+			name:               "returns an error if no setting is provided",
+			fn:                 execAuthenticatedRequest,
+			payload:            `{}`,
+			expectedMessage:    "validation failed, missing required field(s): AnonymousQuestions, MaxQuestionLength, SlowModeSeconds, ReactionsAllowed or PublicRead\n",
+			expectedStatusCode: http.StatusBadRequest,
+			url:                roomURL,
+		},
+		{
+			name:               "returns an error if the max question length is out of range",
+			fn:                 execAuthenticatedRequest,
+			payload:            `{"max_question_length": 2001}`,
+			expectedMessage:    "validation failed: max_question_length must be between 1 and 2000\n",
+			expectedStatusCode: http.StatusBadRequest,
+			url:                roomURL,
+		},
+		{
+			name:               "returns an error if the slow mode interval is out of range",
+			fn:                 execAuthenticatedRequest,
+			payload:            `{"slow_mode_seconds": -1}`,
+			expectedMessage:    "validation failed: slow_mode_seconds must be between 0 and 3600\n",
+			expectedStatusCode: http.StatusBadRequest,
+			url:                roomURL,
+		},
+		{
+			name:               "returns an error if the user is not the room owner",
+			fn:                 execAuthenticatedRequest,
+			payload:            `{"public_read": true}`,
+			expectedMessage:    "only the room owner can change the room\n",
+			expectedStatusCode: http.StatusForbidden,
+			url:                roomURL,
+			setConstraint: func(t *testing.T) {
+				otherUser := createOtherUser(t, "other@example.com")
+				setRoomOwner(t, room.ID, otherUser.ID.String())
+			},
+		},
+	}
+
+	for _, tc := range errorTestCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.setConstraint != nil {
+				tc.setConstraint(t)
+			}
+
+			rr := tc.fn(t, method, tc.url, strings.NewReader(tc.payload))
+			response := rr.Result()
+			defer response.Body.Close()
+
+			body := parseResponseBody(t, response)
+
+			assert.Equal(t, tc.expectedStatusCode, response.StatusCode)
+			assert.Equal(t, tc.expectedMessage, body)
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vhrboliveira/ama-go/internal/store/pgstore"
+	"github.com/vhrboliveira/ama-go/internal/types"
+)
+
+func setRoomSlug(t testing.TB, roomID int64, slug string) {
+	t.Helper()
+
+	_, err := DBPool.Exec(context.Background(), "UPDATE rooms SET slug = $1 WHERE id = $2", slug, roomID)
+	require.NoError(t, err)
+}
+
+func TestRoomSlugs(t *testing.T) {
+	type customFn func(t testing.TB, method string, url string, body io.Reader) *httptest.ResponseRecorder
+
+	const baseURL = "/api/rooms/"
+
+	t.Run("generates a unique slug from the room name", func(t *testing.T) {
+		truncateData(t)
+
+		userID := generateUser(t)
+		payload := `{"name": "  Go & Postgres: Q&A!  ", "user_id": "` + userID + `"}`
+
+		expectedSlugs := []string{"go-postgres-q-a", "go-postgres-q-a-2", "go-postgres-q-a-3"}
+		for _, expected := range expectedSlugs {
+			rr := execAuthenticatedRequest(t, http.MethodPost, "/api/rooms", strings.NewReader(payload))
+			require.Equal(t, http.StatusCreated, rr.Code)
+
+			var body struct {
+				Slug string `json:"slug"`
+			}
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&body))
+			assert.Equal(t, expected, body.Slug)
+		}
+	})
+
+	t.Run("gets the room by its slug", func(t *testing.T) {
+		truncateData(t)
+
+		room := createAndGetRoom(t)
+		setRoomSlug(t, room.ID, "weekly-ama")
+
+		rr := execAuthenticatedRequest(t, http.MethodGet, baseURL+"weekly-ama", nil)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var body pgstore.GetRoomWithUserRow
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&body))
+		assert.Equal(t, room.ID, body.ID)
+		assert.Equal(t, "weekly-ama", body.Slug)
+
+		rr = execAuthenticatedRequest(t, http.MethodGet, baseURL+"weekly-ama/messages", nil)
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("redirects the old slug to the new one", func(t *testing.T) {
+		truncateData(t)
+
+		room := createAndGetRoom(t)
+		setRoomSlug(t, room.ID, "old-slug")
+
+		rr := execAuthenticatedRequest(t, http.MethodPatch, baseURL+"old-slug", strings.NewReader(`{"slug": "new-slug"}`))
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var updated types.RoomUpdated
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&updated))
+		assert.Equal(t, "new-slug", updated.Slug)
+
+		rr = execAuthenticatedRequest(t, http.MethodGet, baseURL+"old-slug/messages?limit=10", nil)
+		assert.Equal(t, http.StatusPermanentRedirect, rr.Code)
+		assert.Equal(t, baseURL+"new-slug/messages?limit=10", rr.Header().Get("Location"))
+
+		rr = execAuthenticatedRequest(t, http.MethodGet, baseURL+"new-slug", nil)
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		// The room can take its old slug back
+		rr = execAuthenticatedRequest(t, http.MethodPatch, baseURL+"new-slug", strings.NewReader(`{"slug": "old-slug"}`))
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		rr = execAuthenticatedRequest(t, http.MethodGet, baseURL+"old-slug", nil)
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		rr = execAuthenticatedRequest(t, http.MethodGet, baseURL+"new-slug", nil)
+		assert.Equal(t, http.StatusPermanentRedirect, rr.Code)
+		assert.Equal(t, baseURL+"old-slug", rr.Header().Get("Location"))
+	})
+
+	t.Run("subscribes to the room by its slug", func(t *testing.T) {
+		truncateData(t)
+
+		server := httptest.NewServer(Router)
+		defer server.Close()
+
+		room := createAndGetRoom(t)
+		setRoomSlug(t, room.ID, "live-room")
+
+		ws, err := connectAuthenticatedWS(t, "ws"+server.URL[4:]+"/subscribe/room/live-room")
+		require.NoError(t, err)
+		defer ws.Close()
+
+		msg := readWSMessageOfKind(t, ws, types.MessageKindRoomSnapshot)
+		assert.Equal(t, types.RoomTopic(room.ID), msg.Topic)
+	})
+
+	truncateData(t)
+	room := createAndGetRoom(t)
+	roomURL := baseURL + strconv.Itoa(int(room.ID))
+	otherRooms := []string{"other room", "renamed room"}
+	createRooms(t, otherRooms)
+	other := getRoomByName(t, "other room")
+	setRoomSlug(t, other.ID, "taken-slug")
+	renamed := getRoomByName(t, "renamed room")
+	setRoomSlug(t, renamed.ID, "redirected-slug")
+	rr := execAuthenticatedRequest(t, http.MethodPatch, baseURL+strconv.Itoa(int(renamed.ID)), strings.NewReader(`{"slug": "current-slug"}`))
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	errorTestCases := []struct {
+		name               string
+		fn                 customFn
+		method             string
+		payload            string
+		expectedMessage    string
+		expectedStatusCode int
+		url                string
+		setConstraint      func(t *testing.T)
+	}{
+		{
+			name:               "returns an error if the slug is not valid",
+			fn:                 execAuthenticatedRequest,
+			method:             http.MethodPatch,
+			payload:            `{"slug": "Not A Slug"}`,
+			expectedMessage:    "validation failed: Slug must have at most 60 lowercase letters, digits and single dashes, and cannot be only digits\n",
+			expectedStatusCode: http.StatusBadRequest,
+			url:                roomURL,
+		},
+		{
+			name:               "returns an error if the slug is only digits",
+			fn:                 execAuthenticatedRequest,
+			method:             http.MethodPatch,
+			payload:            `{"slug": "12345"}`,
+			expectedMessage:    "validation failed: Slug must have at most 60 lowercase letters, digits and single dashes, and cannot be only digits\n",
+			expectedStatusCode: http.StatusBadRequest,
+			url:                roomURL,
+		},
+		{
+			name:               "returns an error if the slug is used by another room",
+			fn:                 execAuthenticatedRequest,
+			method:             http.MethodPatch,
+			payload:            `{"slug": "taken-slug"}`,
+			expectedMessage:    "slug is already taken\n",
+			expectedStatusCode: http.StatusConflict,
+			url:                roomURL,
+		},
+		{
+			name:               "returns an error if the slug was used by another room",
+			fn:                 execAuthenticatedRequest,
+			method:             http.MethodPatch,
+			payload:            `{"slug": "redirected-slug"}`,
+			expectedMessage:    "slug is already taken\n",
+			expectedStatusCode: http.StatusConflict,
+			url:                roomURL,
+		},
+		{
+			name:               "returns an error if no room has the slug",
+			fn:                 execAuthenticatedRequest,
+			method:             http.MethodGet,
+			expectedMessage:    "room not found\n",
+			expectedStatusCode: http.StatusNotFound,
+			url:                baseURL + "unknown-slug",
+		},
+		{
+			name:               "returns an error if the user is not the room owner",
+			fn:                 execAuthenticatedRequest,
+			method:             http.MethodPatch,
+			payload:            `{"slug": "my-slug"}`,
+			expectedMessage:    "only the room owner can change the room\n",
+			expectedStatusCode: http.StatusForbidden,
+			url:                baseURL + "taken-slug",
+			setConstraint: func(t *testing.T) {
+				otherUser := createOtherUser(t, "other@example.com")
+				setRoomOwner(t, other.ID, otherUser.ID.String())
+			},
+		},
+	}
+
+	for _, tc := range errorTestCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.setConstraint != nil {
+				tc.setConstraint(t)
+			}
+
+			rr := tc.fn(t, tc.method, tc.url, strings.NewReader(tc.payload))
+			response := rr.Result()
+			defer response.Body.Close()
+
+			body := parseResponseBody(t, response)
+
+			assert.Equal(t, tc.expectedStatusCode, response.StatusCode)
+			assert.Equal(t, tc.expectedMessage, body)
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package api_test
+
+import (
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vhrboliveira/ama-go/internal/types"
+)
+
+func TestRoomSnapshot(t *testing.T) {
+	server := httptest.NewServer(Router)
+	defer server.Close()
+
+	type snapshotValue struct {
+		Room struct {
+			ID   int64  `json:"id"`
+			Name string `json:"name"`
+		} `json:"room"`
+		Messages []struct {
+			ID            string `json:"id"`
+			ReactionCount int64  `json:"reaction_count"`
+		} `json:"messages"`
+		ReactedIDs []string `json:"reacted_ids"`
+	}
+
+	t.Run("sends the room state as the first frame", func(t *testing.T) {
+		truncateData(t)
+
+		room := createAndGetRoom(t)
+		msgID, _ := createAndGetMessages(t, room.ID)
+		userID := generateUser(t)
+		setMessageReactionWithUserID(t, msgID, userID)
+
+		wsURL := "ws" + server.URL[4:] + "/subscribe/room/" + strconv.Itoa(int(room.ID))
+		ws, _, err := connectWSWithUserSession(t, wsURL, &userID)
+		require.NoError(t, err)
+		defer ws.Close()
+
+		msg := readWSMessage(t, ws)
+		require.Equal(t, types.MessageKindRoomSnapshot, msg.Kind)
+
+		var snapshot snapshotValue
+		decodeMessageValue(t, msg, &snapshot)
+		assert.Equal(t, room.ID, snapshot.Room.ID)
+		assert.Equal(t, room.Name, snapshot.Room.Name)
+		require.Len(t, snapshot.Messages, 1)
+		assert.Equal(t, msgID, snapshot.Messages[0].ID)
+		assert.Equal(t, int64(1), snapshot.Messages[0].ReactionCount)
+		assert.Equal(t, []string{msgID}, snapshot.ReactedIDs)
+	})
+
+	t.Run("stamps the snapshot with the room sequence live events continue from", func(t *testing.T) {
+		truncateData(t)
+
+		room := createAndGetRoom(t)
+		notifyMessageAnswered := func() {
+			Handler.WebsocketService.NotifyRoomClient(types.Message{
+				Kind:   types.MessageKindMessageAnswered,
+				RoomID: room.ID,
+				Value:  types.MessageAnswered{ID: "message-id", Answer: "answer"},
+			})
+		}
+		notifyMessageAnswered()
+
+		wsURL := "ws" + server.URL[4:] + "/subscribe/room/" + strconv.Itoa(int(room.ID))
+		ws, err := connectAuthenticatedWS(t, wsURL)
+		require.NoError(t, err)
+		defer ws.Close()
+
+		snapshot := readWSMessage(t, ws)
+		require.Equal(t, types.MessageKindRoomSnapshot, snapshot.Kind)
+		assert.NotZero(t, snapshot.Seq)
+
+		waitForRoomSubscribers(t, room.ID, 1)
+		notifyMessageAnswered()
+
+		msg := readWSMessageOfKind(t, ws, types.MessageKindMessageAnswered)
+		assert.Equal(t, snapshot.Seq+1, msg.Seq)
+	})
+
+	t.Run("does not send a snapshot when resuming from a cursor", func(t *testing.T) {
+		truncateData(t)
+
+		room := createAndGetRoom(t)
+		wsURL := "ws" + server.URL[4:] + "/subscribe/room/" + strconv.Itoa(int(room.ID)) + "?since=0"
+		ws, err := connectAuthenticatedWS(t, wsURL)
+		require.NoError(t, err)
+		defer ws.Close()
+
+		waitForRoomSubscribers(t, room.ID, 1)
+		Handler.WebsocketService.NotifyRoomClient(types.Message{
+			Kind:   types.MessageKindMessageAnswered,
+			RoomID: room.ID,
+			Value:  types.MessageAnswered{ID: "message-id", Answer: "answer"},
+		})
+
+		msg := readWSMessage(t, ws)
+		assert.NotEqual(t, types.MessageKindRoomSnapshot, msg.Kind)
+	})
+}
@@ -0,0 +1,221 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// insertTimedMessage inserts a question asked at createdAt and, unless
+// answeredAfter is nil, answered that long after it. The question is updated
+// now, as if it was changed after the answer.
+func insertTimedMessage(t testing.TB, roomID int64, message string, createdAt time.Time, answeredAfter *time.Duration) string {
+	t.Helper()
+
+	var answeredAt *time.Time
+	if answeredAfter != nil {
+		at := createdAt.Add(*answeredAfter)
+		answeredAt = &at
+	}
+
+	var id string
+	err := DBPool.QueryRow(
+		context.Background(),
+		`INSERT INTO messages (room_id, message, answered, created_at, answered_at)
+		VALUES ($1, $2, $3, $4, $5) RETURNING id::text`,
+		roomID, message, answeredAfter != nil, createdAt, answeredAt,
+	).Scan(&id)
+	require.NoError(t, err)
+
+	return id
+}
+
+func insertReaction(t testing.TB, messageID, userID string) {
+	t.Helper()
+
+	_, err := DBPool.Exec(context.Background(), "INSERT INTO messages_reactions (message_id, user_id) VALUES ($1, $2)", messageID, userID)
+	require.NoError(t, err)
+}
+
+func TestRoomStats(t *testing.T) {
+	type customFn func(t testing.TB, method string, url string, body io.Reader) *httptest.ResponseRecorder
+
+	type reactedMessage struct {
+		ID        string `json:"id"`
+		Message   string `json:"message"`
+		Reactions int64  `json:"reactions"`
+	}
+
+	type minuteQuestions struct {
+		Minute    string `json:"minute"`
+		Questions int64  `json:"questions"`
+	}
+
+	type stats struct {
+		TotalQuestions        int64             `json:"total_questions"`
+		AnsweredQuestions     int64             `json:"answered_questions"`
+		UnansweredQuestions   int64             `json:"unanswered_questions"`
+		MedianSecondsToAnswer *float64          `json:"median_seconds_to_answer"`
+		UniqueAskers          int64             `json:"unique_askers"`
+		TotalReactions        int64             `json:"total_reactions"`
+		TopReacted            []reactedMessage  `json:"top_reacted"`
+		QuestionsPerMinute    []minuteQuestions `json:"questions_per_minute"`
+		UniqueReactors        int64             `json:"unique_reactors"`
+	}
+
+	const (
+		baseURL = "/api/rooms/"
+		method  = http.MethodGet
+	)
+
+	t.Run("sums up the questions and reactions of the room", func(t *testing.T) {
+		truncateData(t)
+
+		room := createAndGetRoom(t)
+		start := time.Now().UTC().Truncate(time.Minute).Add(-time.Hour)
+
+		first := insertTimedMessage(t, room.ID, "first", start, offset(time.Minute))
+		second := insertTimedMessage(t, room.ID, "second", start.Add(10*time.Second), offset(3*time.Minute))
+		third := insertTimedMessage(t, room.ID, "third", start.Add(2*time.Minute), nil)
+
+		createRooms(t, []string{"other room"})
+		otherRoom := getRoomByName(t, "other room")
+		elsewhere := insertTimedMessage(t, otherRoom.ID, "elsewhere", start, offset(time.Hour))
+
+		userID := generateUser(t)
+		otherUserID := createOtherUser(t, "reactor@example.com").ID.String()
+		setMessageColumn(t, first, "user_id", userID)
+		setMessageColumn(t, second, "user_id", userID)
+		setMessageColumn(t, third, "user_id", otherUserID)
+		setMessageColumn(t, elsewhere, "user_id", createOtherUser(t, "elsewhere@example.com").ID.String())
+		insertReaction(t, first, userID)
+		insertReaction(t, second, userID)
+		insertReaction(t, second, otherUserID)
+
+		rr := execAuthenticatedRequest(t, method, baseURL+strconv.Itoa(int(room.ID))+"/stats", nil)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var body stats
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&body))
+
+		median := float64(120)
+		expected := stats{
+			TotalQuestions:        3,
+			AnsweredQuestions:     2,
+			UnansweredQuestions:   1,
+			MedianSecondsToAnswer: &median,
+			UniqueAskers:          2,
+			TotalReactions:        3,
+			TopReacted: []reactedMessage{
+				{ID: second, Message: "second", Reactions: 2},
+				{ID: first, Message: "first", Reactions: 1},
+			},
+			QuestionsPerMinute: []minuteQuestions{
+				{Minute: start.Format(time.RFC3339), Questions: 2},
+				{Minute: start.Add(2 * time.Minute).Format(time.RFC3339), Questions: 1},
+			},
+			UniqueReactors: 2,
+		}
+		assert.Equal(t, expected, body)
+	})
+
+	t.Run("returns empty stats for a room without questions", func(t *testing.T) {
+		truncateData(t)
+
+		room := createAndGetRoom(t)
+
+		rr := execAuthenticatedRequest(t, method, baseURL+strconv.Itoa(int(room.ID))+"/stats", nil)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var body stats
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&body))
+
+		assert.Equal(t, stats{TopReacted: []reactedMessage{}, QuestionsPerMinute: []minuteQuestions{}}, body)
+	})
+
+	fakeRoomID := "999999"
+
+	truncateData(t)
+	room := createAndGetRoom(t)
+	roomURL := baseURL + strconv.Itoa(int(room.ID)) + "/stats"
+
+	errorTestCases := []struct {
+		name               string
+		fn                 customFn
+		expectedMessage    string
+		expectedStatusCode int
+		url                string
+		setConstraint      func(t *testing.T)
+	}{
+		{
+			name: "returns unauthorized error if sessionID is not found",
+			fn: func(t testing.TB, method, url string, body io.Reader) *httptest.ResponseRecorder {
+				return execRequestWithoutCookie(method, url, body)
+			},
+			expectedMessage:    "unauthorized, session not found or invalid\n",
+			expectedStatusCode: http.StatusUnauthorized,
+			url:                roomURL,
+		},
+		{
+			name:               "returns an error if room id is not valid",
+			fn:                 execAuthenticatedRequest,
+			expectedMessage:    "invalid room id\n",
+			expectedStatusCode: http.StatusBadRequest,
+			url:                baseURL + "invalid_room_id/stats",
+		},
+		{
+			name:               "returns an error if room does not exist",
+			fn:                 execAuthenticatedRequest,
+			expectedMessage:    "room not found\n",
+			expectedStatusCode: http.StatusBadRequest,
+			url:                baseURL + fakeRoomID + "/stats",
+		},
+		{
+			name:               "returns an error if fails to get the stats",
+			fn:                 execAuthenticatedRequest,
+			expectedMessage:    "error getting room stats\n",
+			expectedStatusCode: http.StatusInternalServerError,
+			url:                roomURL,
+			setConstraint: func(t *testing.T) {
+				setMessagesConstraintFailure(t)
+			},
+		},
+		{
+			name:               "returns an error if the user is not the room owner",
+			fn:                 execAuthenticatedRequest,
+			expectedMessage:    "only the room owner can change the room\n",
+			expectedStatusCode: http.StatusForbidden,
+			url:                roomURL,
+			setConstraint: func(t *testing.T) {
+				otherUser := createOtherUser(t, "other@example.com")
+				setRoomOwner(t, room.ID, otherUser.ID.String())
+			},
+		},
+	}
+
+	for _, tc := range errorTestCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.setConstraint != nil {
+				tc.setConstraint(t)
+			}
+
+			rr := tc.fn(t, method, tc.url, strings.NewReader(""))
+			response := rr.Result()
+			defer response.Body.Close()
+
+			body := parseResponseBody(t, response)
+
+			assert.Equal(t, tc.expectedStatusCode, response.StatusCode)
+			assert.Equal(t, tc.expectedMessage, body)
+		})
+	}
+}
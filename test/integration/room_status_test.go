@@ -0,0 +1,488 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vhrboliveira/ama-go/internal/service"
+	"github.com/vhrboliveira/ama-go/internal/store/pgstore"
+	"github.com/vhrboliveira/ama-go/internal/types"
+)
+
+// setRoomStatus sets the room status as is, skipping the validations, with
+// opensAt and closesAt as offsets from now, or nil to leave them empty.
+func setRoomStatus(t testing.TB, roomID int64, status string, opensAt, closesAt *time.Duration) {
+	t.Helper()
+
+	timestamp := func(offset *time.Duration) any {
+		if offset == nil {
+			return nil
+		}
+		return time.Now().UTC().Add(*offset)
+	}
+
+	_, err := DBPool.Exec(
+		context.Background(),
+		"UPDATE rooms SET status = $1, opens_at = $2, closes_at = $3 WHERE id = $4",
+		status, timestamp(opensAt), timestamp(closesAt), roomID,
+	)
+	require.NoError(t, err)
+}
+
+func getRoomStatus(t testing.TB, roomID int64) string {
+	t.Helper()
+
+	var status string
+	err := DBPool.QueryRow(context.Background(), "SELECT status FROM rooms WHERE id = $1", roomID).Scan(&status)
+	require.NoError(t, err)
+
+	return status
+}
+
+func offset(d time.Duration) *time.Duration {
+	return &d
+}
+
+func TestRoomStatus(t *testing.T) {
+	type customFn func(t testing.TB, method string, url string, body io.Reader) *httptest.ResponseRecorder
+
+	const (
+		baseURL = "/api/rooms/"
+		method  = http.MethodPatch
+	)
+
+	t.Run("opens new rooms unless told otherwise", func(t *testing.T) {
+		truncateData(t)
+
+		userID := generateUser(t)
+		opensAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second).Format(time.RFC3339)
+
+		tests := []struct {
+			payload        string
+			expectedStatus string
+			expectedOpens  *string
+		}{
+			{payload: `{"name": "open room", "user_id": "` + userID + `"}`, expectedStatus: "open"},
+			{payload: `{"name": "draft room", "user_id": "` + userID + `", "status": "draft"}`, expectedStatus: "draft"},
+			{payload: `{"name": "scheduled room", "user_id": "` + userID + `", "opens_at": "` + opensAt + `"}`, expectedStatus: "scheduled", expectedOpens: &opensAt},
+		}
+
+		for _, tt := range tests {
+			rr := execAuthenticatedRequest(t, http.MethodPost, "/api/rooms", strings.NewReader(tt.payload))
+			require.Equal(t, http.StatusCreated, rr.Code)
+
+			var result struct {
+				Status   string  `json:"status"`
+				OpensAt  *string `json:"opens_at"`
+				ClosesAt *string `json:"closes_at"`
+			}
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&result))
+			assert.Equal(t, tt.expectedStatus, result.Status)
+			assert.Equal(t, tt.expectedOpens, result.OpensAt)
+			assert.Nil(t, result.ClosesAt)
+		}
+	})
+
+	t.Run("schedules the room and notifies the subscribers", func(t *testing.T) {
+		truncateData(t)
+
+		server := httptest.NewServer(Router)
+		defer server.Close()
+
+		room := createAndGetRoom(t)
+		wsURL := "ws" + server.URL[4:]
+
+		roomsWS, err := connectAuthenticatedWS(t, wsURL+"/subscribe")
+		require.NoError(t, err)
+		defer roomsWS.Close()
+		waitForTopicSubscribers(t, types.TopicRooms, 1)
+
+		roomWS, err := connectAuthenticatedWS(t, wsURL+"/subscribe/room/"+strconv.Itoa(int(room.ID)))
+		require.NoError(t, err)
+		defer roomWS.Close()
+		readWSMessageOfKind(t, roomWS, types.MessageKindRoomSnapshot)
+
+		opensAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second).Format(time.RFC3339)
+		closesAt := time.Now().Add(2 * time.Hour).UTC().Truncate(time.Second).Format(time.RFC3339)
+		payload := strings.NewReader(`{"status": "scheduled", "opens_at": "` + opensAt + `", "closes_at": "` + closesAt + `"}`)
+		rr := execAuthenticatedRequest(t, method, baseURL+strconv.Itoa(int(room.ID))+"/status", payload)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var result types.RoomStatusChanged
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&result))
+		assert.Equal(t, room.ID, result.ID)
+		assert.Equal(t, "scheduled", result.Status)
+		assert.Equal(t, &opensAt, result.OpensAt)
+		assert.Equal(t, &closesAt, result.ClosesAt)
+		assertValidDate(t, result.UpdatedAt)
+
+		for _, msg := range []types.Message{
+			readWSMessageOfKind(t, roomsWS, types.MessageKindRoomStatusChanged),
+			readWSMessageOfKind(t, roomWS, types.MessageKindRoomStatusChanged),
+		} {
+			var statusChanged types.RoomStatusChanged
+			decodeMessageValue(t, msg, &statusChanged)
+			assert.Equal(t, result, statusChanged)
+		}
+	})
+
+	t.Run("hides drafts from the users who do not host them", func(t *testing.T) {
+		truncateData(t)
+
+		room := createAndGetRoom(t)
+		setRoomStatus(t, room.ID, service.RoomStatusDraft, nil, nil)
+		roomURL := baseURL + strconv.Itoa(int(room.ID))
+		subscribeURL := "/subscribe/room/" + strconv.Itoa(int(room.ID))
+
+		moderator := createOtherUser(t, "moderator@example.com")
+		addRoomMember(t, room.ID, moderator.ID.String(), "moderator")
+		other := createOtherUser(t, "other@example.com")
+
+		listedRooms := func(user pgstore.User) []int64 {
+			rr := execRequestGeneratingSession(t, http.MethodGet, "/api/rooms", nil, &user)
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			var rooms []pgstore.GetRoomsRow
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&rooms))
+
+			ids := []int64{}
+			for _, room := range rooms {
+				ids = append(ids, room.ID)
+			}
+			return ids
+		}
+
+		assert.Equal(t, []int64{room.ID}, listedRooms(moderator))
+		assert.Empty(t, listedRooms(other))
+
+		rr := execRequestGeneratingSession(t, http.MethodGet, roomURL, nil, &moderator)
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		rr = execRequestGeneratingSession(t, http.MethodGet, roomURL, nil, &other)
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		assert.Equal(t, "room not found\n", rr.Body.String())
+
+		for _, url := range []string{subscribeURL, "/subscribe/sse/room/" + strconv.Itoa(int(room.ID))} {
+			rr = execRequestGeneratingSession(t, http.MethodGet, url, nil, &other)
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+			assert.Equal(t, "room not found\n", rr.Body.String())
+		}
+
+		rr = execAuthenticatedRequest(t, method, roomURL+"/status", strings.NewReader(`{"status": "open"}`))
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		assert.Equal(t, []int64{room.ID}, listedRooms(other))
+
+		rr = execRequestGeneratingSession(t, http.MethodGet, roomURL, nil, &other)
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("announces drafts on the rooms list only once they are opened", func(t *testing.T) {
+		truncateData(t)
+
+		server := httptest.NewServer(Router)
+		defer server.Close()
+
+		userID := generateUser(t)
+		roomsWS, err := connectAuthenticatedWS(t, "ws"+server.URL[4:]+"/subscribe")
+		require.NoError(t, err)
+		defer roomsWS.Close()
+		waitForTopicSubscribers(t, types.TopicRooms, 1)
+
+		rr := execAuthenticatedRequest(t, http.MethodPost, "/api/rooms", strings.NewReader(`{"name": "draft room", "user_id": "`+userID+`", "status": "draft"}`))
+		require.Equal(t, http.StatusCreated, rr.Code)
+
+		var draft struct {
+			ID int64 `json:"id"`
+		}
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&draft))
+
+		rr = execAuthenticatedRequest(t, http.MethodPost, "/api/rooms", strings.NewReader(`{"name": "open room", "user_id": "`+userID+`"}`))
+		require.Equal(t, http.StatusCreated, rr.Code)
+
+		var created types.RoomCreated
+		decodeMessageValue(t, readWSMessageOfKind(t, roomsWS, types.MessageKindRoomCreated), &created)
+		assert.Equal(t, "open room", created.Name, "the draft is not announced")
+
+		rr = execAuthenticatedRequest(t, method, baseURL+strconv.Itoa(int(draft.ID))+"/status", strings.NewReader(`{"status": "open"}`))
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		msg := readWSMessageOfKind(t, roomsWS, types.MessageKindRoomStatusChanged)
+		assert.Equal(t, draft.ID, msg.RoomID)
+	})
+
+	t.Run("rejects questions and reactions unless the room is open", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			status   string
+			opensAt  *time.Duration
+			closesAt *time.Duration
+			current  string
+		}{
+			{name: "draft", status: "draft", current: "draft"},
+			{name: "scheduled", status: "scheduled", opensAt: offset(time.Hour), current: "scheduled"},
+			{name: "closed", status: "closed", current: "closed"},
+			{name: "archived", status: "archived", current: "archived"},
+			{name: "open past its closing time", status: "open", closesAt: offset(-time.Minute), current: "closed"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				truncateData(t)
+
+				room := createAndGetRoom(t)
+				msgID, _ := createAndGetMessages(t, room.ID)
+				setRoomStatus(t, room.ID, tt.status, tt.opensAt, tt.closesAt)
+				roomURL := baseURL + strconv.Itoa(int(room.ID))
+
+				rr := execAuthenticatedRequest(t, http.MethodPost, roomURL+"/messages", strings.NewReader(`{"message": "question"}`))
+				assert.Equal(t, http.StatusConflict, rr.Code)
+				assert.Equal(t, "the room is "+tt.current+" and is not accepting questions\n", rr.Body.String())
+
+				payload := `{"user_id": "` + generateUser(t) + `"}`
+				for _, method := range []string{http.MethodPatch, http.MethodDelete} {
+					rr = execAuthenticatedRequest(t, method, roomURL+"/messages/"+msgID+"/react", strings.NewReader(payload))
+					assert.Equal(t, http.StatusConflict, rr.Code)
+					assert.Equal(t, "the room is "+tt.current+" and is not accepting reactions\n", rr.Body.String())
+				}
+			})
+		}
+	})
+
+	t.Run("accepts questions once a scheduled room reaches its opening time", func(t *testing.T) {
+		truncateData(t)
+
+		room := createAndGetRoom(t)
+		setRoomStatus(t, room.ID, "scheduled", offset(-time.Minute), nil)
+
+		rr := execAuthenticatedRequest(t, http.MethodPost, baseURL+strconv.Itoa(int(room.ID))+"/messages", strings.NewReader(`{"message": "question"}`))
+		assert.Equal(t, http.StatusCreated, rr.Code)
+	})
+
+	t.Run("opens and closes the rooms that are due", func(t *testing.T) {
+		truncateData(t)
+
+		server := httptest.NewServer(Router)
+		defer server.Close()
+
+		createRooms(t, []string{"opening", "closing", "not yet", "opening and closing"})
+		opening := getRoomByName(t, "opening")
+		closing := getRoomByName(t, "closing")
+		notYet := getRoomByName(t, "not yet")
+		both := getRoomByName(t, "opening and closing")
+
+		setRoomStatus(t, opening.ID, "scheduled", offset(-time.Minute), offset(time.Hour))
+		setRoomStatus(t, closing.ID, "open", nil, offset(-time.Minute))
+		setRoomStatus(t, notYet.ID, "scheduled", offset(time.Hour), nil)
+		setRoomStatus(t, both.ID, "scheduled", offset(-2*time.Minute), offset(-time.Minute))
+
+		ws, err := connectAuthenticatedWS(t, "ws"+server.URL[4:]+"/subscribe")
+		require.NoError(t, err)
+		defer ws.Close()
+		waitForTopicSubscribers(t, types.TopicRooms, 1)
+
+		scheduler := service.NewRoomScheduler(pgstore.New(DBPool), Handler.WebsocketService)
+		require.NoError(t, scheduler.RunDue(context.Background()))
+
+		assert.Equal(t, "open", getRoomStatus(t, opening.ID))
+		assert.Equal(t, "closed", getRoomStatus(t, closing.ID))
+		assert.Equal(t, "scheduled", getRoomStatus(t, notYet.ID))
+		assert.Equal(t, "closed", getRoomStatus(t, both.ID))
+
+		changes := map[int64][]string{}
+		for range 4 {
+			msg := readWSMessageOfKind(t, ws, types.MessageKindRoomStatusChanged)
+			var statusChanged types.RoomStatusChanged
+			decodeMessageValue(t, msg, &statusChanged)
+			changes[statusChanged.ID] = append(changes[statusChanged.ID], statusChanged.Status)
+		}
+
+		assert.Equal(t, map[int64][]string{
+			opening.ID: {"open"},
+			closing.ID: {"closed"},
+			both.ID:    {"open", "closed"},
+		}, changes)
+
+		// nothing is left to do on the next run
+		require.NoError(t, scheduler.RunDue(context.Background()))
+		assert.Equal(t, "closed", getRoomStatus(t, both.ID))
+	})
+
+	fakeRoomID := "999999"
+	future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	later := time.Now().Add(2 * time.Hour).UTC().Format(time.RFC3339)
+	past := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+
+	truncateData(t)
+	room := createAndGetRoom(t)
+	roomURL := baseURL + strconv.Itoa(int(room.ID)) + "/status"
+
+	errorTestCases := []struct {
+		name               string
+		fn                 customFn
+		payload            string
+		expectedMessage    string
+		expectedStatusCode int
+		url                string
+		setConstraint      func(t *testing.T)
+	}{
+		{
+			name: "returns unauthorized error if sessionID is not found",
+			fn: func(t testing.TB, method, url string, body io.Reader) *httptest.ResponseRecorder {
+				return execRequestWithoutCookie(method, url, body)
+			},
+			payload:            `{"status": "closed"}`,
+			expectedMessage:    "unauthorized, session not found or invalid\n",
+			expectedStatusCode: http.StatusUnauthorized,
+			url:                roomURL,
+		},
+		{
+			name: "returns unauthorized error if cookie is different from the session",
+			fn: func(t testing.TB, method, url string, body io.Reader) *httptest.ResponseRecorder {
+				return execRequestWithInvalidCookie(method, url, body)
+			},
+			payload:            `{"status": "closed"}`,
+			expectedMessage:    "unauthorized, session not found or invalid\n",
+			expectedStatusCode: http.StatusUnauthorized,
+			url:                roomURL,
+		},
+		{
+			name:               "returns an error if room id is not valid",
+			fn:                 execAuthenticatedRequest,
+			payload:            `{"status": "closed"}`,
+			expectedMessage:    "invalid room id\n",
+			expectedStatusCode: http.StatusBadRequest,
+			url:                baseURL + "invalid_room_id/status",
+		},
+		{
+			name:               "returns an error if body is not valid",
+			fn:                 execAuthenticatedRequest,
+			payload:            `{"status": 1}`,
+			expectedMessage:    "invalid body\n",
+			expectedStatusCode: http.StatusBadRequest,
+			url:                roomURL,
+		},
+		{
+			name:               "returns an error if status is missing",
+			fn:                 execAuthenticatedRequest,
+			payload:            `{}`,
+			expectedMessage:    "validation failed, missing required field(s): Status\n",
+			expectedStatusCode: http.StatusBadRequest,
+			url:                roomURL,
+		},
+		{
+			name:               "returns an error if status is not valid",
+			fn:                 execAuthenticatedRequest,
+			payload:            `{"status": "paused"}`,
+			expectedMessage:    "validation failed: Status must be one of: draft, scheduled, open, closed, archived\n",
+			expectedStatusCode: http.StatusBadRequest,
+			url:                roomURL,
+		},
+		{
+			name:               "returns an error if a scheduled room has no opening time",
+			fn:                 execAuthenticatedRequest,
+			payload:            `{"status": "scheduled"}`,
+			expectedMessage:    "validation failed: opens_at is required when the status is scheduled\n",
+			expectedStatusCode: http.StatusBadRequest,
+			url:                roomURL,
+		},
+		{
+			name:               "returns an error if the opening time is set on a room that is not scheduled",
+			fn:                 execAuthenticatedRequest,
+			payload:            `{"status": "open", "opens_at": "` + future + `"}`,
+			expectedMessage:    "validation failed: opens_at can only be set when the status is scheduled\n",
+			expectedStatusCode: http.StatusBadRequest,
+			url:                roomURL,
+		},
+		{
+			name:               "returns an error if the opening time is in the past",
+			fn:                 execAuthenticatedRequest,
+			payload:            `{"status": "scheduled", "opens_at": "` + past + `"}`,
+			expectedMessage:    "validation failed: opens_at must be in the future\n",
+			expectedStatusCode: http.StatusBadRequest,
+			url:                roomURL,
+		},
+		{
+			name:               "returns an error if the closing time is set on a closed room",
+			fn:                 execAuthenticatedRequest,
+			payload:            `{"status": "closed", "closes_at": "` + future + `"}`,
+			expectedMessage:    "validation failed: closes_at can only be set when the status is scheduled or open\n",
+			expectedStatusCode: http.StatusBadRequest,
+			url:                roomURL,
+		},
+		{
+			name:               "returns an error if the closing time is in the past",
+			fn:                 execAuthenticatedRequest,
+			payload:            `{"status": "open", "closes_at": "` + past + `"}`,
+			expectedMessage:    "validation failed: closes_at must be in the future\n",
+			expectedStatusCode: http.StatusBadRequest,
+			url:                roomURL,
+		},
+		{
+			name:               "returns an error if the closing time is before the opening time",
+			fn:                 execAuthenticatedRequest,
+			payload:            `{"status": "scheduled", "opens_at": "` + later + `", "closes_at": "` + future + `"}`,
+			expectedMessage:    "validation failed: closes_at must be after opens_at\n",
+			expectedStatusCode: http.StatusBadRequest,
+			url:                roomURL,
+		},
+		{
+			name:               "returns an error if room does not exist",
+			fn:                 execAuthenticatedRequest,
+			payload:            `{"status": "closed"}`,
+			expectedMessage:    "room not found\n",
+			expectedStatusCode: http.StatusBadRequest,
+			url:                baseURL + fakeRoomID + "/status",
+		},
+		{
+			name:               "returns an error if the user is not the room owner",
+			fn:                 execAuthenticatedRequest,
+			payload:            `{"status": "closed"}`,
+			expectedMessage:    "only the room owner can change the room\n",
+			expectedStatusCode: http.StatusForbidden,
+			url:                roomURL,
+			setConstraint: func(t *testing.T) {
+				otherUserID := createUser(t, "other@example.com", "other user", "google", "0987654321", "")
+				setRoomOwner(t, room.ID, otherUserID)
+			},
+		},
+		{
+			name:               "returns an error if fails to get room",
+			fn:                 execAuthenticatedRequest,
+			payload:            `{"status": "closed"}`,
+			expectedMessage:    "error validating room ID\n",
+			expectedStatusCode: http.StatusInternalServerError,
+			url:                roomURL,
+			setConstraint: func(t *testing.T) {
+				setRoomsConstraintFailure(t)
+			},
+		},
+	}
+
+	for _, tc := range errorTestCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.setConstraint != nil {
+				tc.setConstraint(t)
+			}
+
+			payload := strings.NewReader(tc.payload)
+			rr := tc.fn(t, method, tc.url, payload)
+			response := rr.Result()
+			defer response.Body.Close()
+
+			body := parseResponseBody(t, response)
+
+			assert.Equal(t, tc.expectedStatusCode, response.StatusCode)
+			assert.Equal(t, tc.expectedMessage, body)
+		})
+	}
+}
@@ -0,0 +1,279 @@
+package api_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vhrboliveira/ama-go/internal/store/pgstore"
+	"github.com/vhrboliveira/ama-go/internal/types"
+)
+
+func TestRoomTransfers(t *testing.T) {
+	type customFn func(t testing.TB, method string, url string, body io.Reader) *httptest.ResponseRecorder
+
+	const baseURL = "/api/rooms/"
+
+	t.Run("transfers the room once the new owner accepts it", func(t *testing.T) {
+		truncateData(t)
+
+		server := httptest.NewServer(Router)
+		defer server.Close()
+
+		room := createAndGetRoom(t)
+		roomURL := baseURL + strconv.Itoa(int(room.ID))
+		newOwner := createOtherUser(t, "new-owner@example.com")
+		addRoomMember(t, room.ID, newOwner.ID.String(), "moderator")
+
+		ws, err := connectAuthenticatedWS(t, "ws"+server.URL[4:]+"/subscribe/room/"+strconv.Itoa(int(room.ID)))
+		require.NoError(t, err)
+		defer ws.Close()
+		readWSMessageOfKind(t, ws, types.MessageKindRoomSnapshot)
+
+		rr := execAuthenticatedRequest(t, http.MethodPost, roomURL+"/transfer", strings.NewReader(`{"email": "new-owner@example.com"}`))
+		require.Equal(t, http.StatusCreated, rr.Code)
+
+		var transfer pgstore.RoomTransfer
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&transfer))
+		assert.Equal(t, room.ID, transfer.RoomID)
+		assert.Equal(t, room.UserID, transfer.FromUserID)
+		assert.Equal(t, newOwner.ID, transfer.ToUserID)
+
+		// the room stays with its owner until the transfer is accepted
+		rr = execRequestGeneratingSession(t, http.MethodPatch, roomURL+"/settings", strings.NewReader(`{"public_read": true}`), &newOwner)
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+
+		rr = execRequestGeneratingSession(t, http.MethodGet, "/api/transfers", nil, &newOwner)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var transfers []pgstore.GetIncomingRoomTransfersRow
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&transfers))
+		require.Len(t, transfers, 1)
+		assert.Equal(t, room.ID, transfers[0].RoomID)
+		assert.Equal(t, "room", transfers[0].RoomName)
+		assert.Equal(t, "Test User", transfers[0].FromUserName)
+
+		rr = execRequestGeneratingSession(t, http.MethodPost, roomURL+"/transfer/accept", nil, &newOwner)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var accepted pgstore.GetRoomWithUserRow
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&accepted))
+		assert.Equal(t, newOwner.ID, accepted.UserID)
+		assert.Equal(t, "Other User", accepted.CreatorName.String)
+
+		msg := readWSMessageOfKind(t, ws, types.MessageKindRoomOwnerChanged)
+		var ownerChanged types.RoomOwnerChanged
+		decodeMessageValue(t, msg, &ownerChanged)
+		assert.Equal(t, room.ID, ownerChanged.ID)
+		assert.Equal(t, newOwner.ID.String(), ownerChanged.UserID)
+		assert.Equal(t, "Other User", ownerChanged.CreatorName)
+		assertValidDate(t, ownerChanged.UpdatedAt)
+
+		// the new owner is no longer listed as a moderator of the room
+		rr = execRequestGeneratingSession(t, http.MethodGet, roomURL+"/members", nil, &newOwner)
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.JSONEq(t, `[]`, rr.Body.String())
+
+		rr = execRequestGeneratingSession(t, http.MethodPatch, roomURL+"/settings", strings.NewReader(`{"public_read": true}`), &newOwner)
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		rr = execAuthenticatedRequest(t, http.MethodPatch, roomURL+"/settings", strings.NewReader(`{"public_read": false}`))
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+
+	t.Run("lets the owner cancel and the new owner decline a transfer", func(t *testing.T) {
+		truncateData(t)
+
+		room := createAndGetRoom(t)
+		roomURL := baseURL + strconv.Itoa(int(room.ID))
+		newOwner := createOtherUser(t, "new-owner@example.com")
+
+		rr := execAuthenticatedRequest(t, http.MethodPost, roomURL+"/transfer", strings.NewReader(`{"email": "new-owner@example.com"}`))
+		require.Equal(t, http.StatusCreated, rr.Code)
+
+		rr = execAuthenticatedRequest(t, http.MethodDelete, roomURL+"/transfer", nil)
+		assert.Equal(t, http.StatusNoContent, rr.Code)
+
+		rr = execRequestGeneratingSession(t, http.MethodPost, roomURL+"/transfer/accept", nil, &newOwner)
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		assert.Equal(t, "transfer not found\n", rr.Body.String())
+
+		rr = execAuthenticatedRequest(t, http.MethodPost, roomURL+"/transfer", strings.NewReader(`{"email": "new-owner@example.com"}`))
+		require.Equal(t, http.StatusCreated, rr.Code)
+
+		rr = execRequestGeneratingSession(t, http.MethodDelete, roomURL+"/transfer", nil, &newOwner)
+		assert.Equal(t, http.StatusNoContent, rr.Code)
+
+		rr = execRequestGeneratingSession(t, http.MethodGet, "/api/transfers", nil, &newOwner)
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.JSONEq(t, `[]`, rr.Body.String())
+	})
+
+	t.Run("keeps the member role when the offer is stale", func(t *testing.T) {
+		truncateData(t)
+
+		room := createAndGetRoom(t)
+		roomURL := baseURL + strconv.Itoa(int(room.ID))
+		newOwner := createOtherUser(t, "new-owner@example.com")
+		addRoomMember(t, room.ID, newOwner.ID.String(), "moderator")
+
+		rr := execAuthenticatedRequest(t, http.MethodPost, roomURL+"/transfer", strings.NewReader(`{"email": "new-owner@example.com"}`))
+		require.Equal(t, http.StatusCreated, rr.Code)
+
+		// the room changes hands before the offer is accepted
+		thirdOwner := createOtherUser(t, "third-owner@example.com")
+		setRoomOwner(t, room.ID, thirdOwner.ID.String())
+
+		rr = execRequestGeneratingSession(t, http.MethodPost, roomURL+"/transfer/accept", nil, &newOwner)
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		assert.Equal(t, "transfer not found\n", rr.Body.String())
+
+		rr = execRequestGeneratingSession(t, http.MethodGet, roomURL+"/members", nil, &newOwner)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var members []pgstore.GetRoomMembersRow
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&members))
+		require.Len(t, members, 1)
+		assert.Equal(t, newOwner.ID, members[0].UserID)
+		assert.Equal(t, "moderator", members[0].Role)
+	})
+
+	t.Run("hands the rooms over to the successor of a deleted user", func(t *testing.T) {
+		truncateData(t)
+
+		createRooms(t, []string{"first room", "second room"})
+		first := getRoomByName(t, "first room")
+		second := getRoomByName(t, "second room")
+		successor := createOtherUser(t, "successor@example.com")
+		addRoomMember(t, second.ID, successor.ID.String(), "panelist")
+
+		url := "/api/user/" + first.UserID.String() + "?successor_email=unknown@example.com"
+		rr := execAuthenticatedRequest(t, http.MethodDelete, url, nil)
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		assert.Equal(t, "successor not found\n", rr.Body.String())
+
+		url = "/api/user/" + first.UserID.String() + "?successor_email=successor@example.com"
+		rr = execAuthenticatedRequest(t, http.MethodDelete, url, nil)
+		require.Equal(t, http.StatusNoContent, rr.Code)
+
+		assert.Empty(t, getUserIDByEmail(t, "test@example.com"))
+
+		for _, room := range []pgstore.Room{first, second} {
+			rr = execRequestGeneratingSession(t, http.MethodGet, baseURL+strconv.Itoa(int(room.ID)), nil, &successor)
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			var body pgstore.GetRoomWithUserRow
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&body))
+			assert.Equal(t, successor.ID, body.UserID)
+		}
+	})
+
+	truncateData(t)
+	room := createAndGetRoom(t)
+	roomURL := baseURL + strconv.Itoa(int(room.ID)) + "/transfer"
+	createOtherUser(t, "new-owner@example.com")
+
+	errorTestCases := []struct {
+		name               string
+		fn                 customFn
+		payload            string
+		expectedMessage    string
+		expectedStatusCode int
+		url                string
+		setConstraint      func(t *testing.T)
+	}{
+		{
+			name: "returns unauthorized error if sessionID is not found",
+			fn: func(t testing.TB, method, url string, body io.Reader) *httptest.ResponseRecorder {
+				return execRequestWithoutCookie(method, url, body)
+			},
+			payload:            `{"email": "new-owner@example.com"}`,
+			expectedMessage:    "unauthorized, session not found or invalid\n",
+			expectedStatusCode: http.StatusUnauthorized,
+			url:                roomURL,
+		},
+		{
+			name:               "returns an error if room id is not valid",
+			fn:                 execAuthenticatedRequest,
+			payload:            `{"email": "new-owner@example.com"}`,
+			expectedMessage:    "invalid room id\n",
+			expectedStatusCode: http.StatusBadRequest,
+			url:                baseURL + "invalid_room_id/transfer",
+		},
+		{
+			name:               "returns an error if body is not valid",
+			fn:                 execAuthenticatedRequest,
+			payload:            `{"email": 1}`,
+			expectedMessage:    "invalid body\n",
+			expectedStatusCode: http.StatusBadRequest,
+			url:                roomURL,
+		},
+		{
+			name:               "returns an error if the email is missing",
+			fn:                 execAuthenticatedRequest,
+			payload:            `{}`,
+			expectedMessage:    "validation failed, missing required field(s): Email\n",
+			expectedStatusCode: http.StatusBadRequest,
+			url:                roomURL,
+		},
+		{
+			name:               "returns an error if the email is not valid",
+			fn:                 execAuthenticatedRequest,
+			payload:            `{"email": "not-an-email"}`,
+			expectedMessage:    "validation failed: Email must be a valid email address\n",
+			expectedStatusCode: http.StatusBadRequest,
+			url:                roomURL,
+		},
+		{
+			name:               "returns an error if the user does not exist",
+			fn:                 execAuthenticatedRequest,
+			payload:            `{"email": "nobody@example.com"}`,
+			expectedMessage:    "user not found\n",
+			expectedStatusCode: http.StatusNotFound,
+			url:                roomURL,
+		},
+		{
+			name:               "returns an error if the room already belongs to the user",
+			fn:                 execAuthenticatedRequest,
+			payload:            `{"email": "test@example.com"}`,
+			expectedMessage:    "the room already belongs to this user\n",
+			expectedStatusCode: http.StatusBadRequest,
+			url:                roomURL,
+		},
+		{
+			name:               "returns an error if the user is not the room owner",
+			fn:                 execAuthenticatedRequest,
+			payload:            `{"email": "new-owner@example.com"}`,
+			expectedMessage:    "only the room owner can change the room\n",
+			expectedStatusCode: http.StatusForbidden,
+			url:                roomURL,
+			setConstraint: func(t *testing.T) {
+				otherUser := createOtherUser(t, "other@example.com")
+				setRoomOwner(t, room.ID, otherUser.ID.String())
+			},
+		},
+	}
+
+	for _, tc := range errorTestCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.setConstraint != nil {
+				tc.setConstraint(t)
+			}
+
+			rr := tc.fn(t, http.MethodPost, tc.url, strings.NewReader(tc.payload))
+			response := rr.Result()
+			defer response.Body.Close()
+
+			body := parseResponseBody(t, response)
+
+			assert.Equal(t, tc.expectedStatusCode, response.StatusCode)
+			assert.Equal(t, tc.expectedMessage, body)
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vhrboliveira/ama-go/internal/store/pgstore"
+	"github.com/vhrboliveira/ama-go/internal/types"
+)
+
+func TestRoomVisibility(t *testing.T) {
+	const baseURL = "/api/rooms"
+
+	// setupRooms creates a public, an unlisted and a private room owned by the
+	// test user, and another user without access to any of them.
+	setupRooms := func(t *testing.T) (map[string]pgstore.Room, pgstore.User) {
+		t.Helper()
+		truncateData(t)
+
+		rooms := make(map[string]pgstore.Room)
+		createRooms(t, []string{"public", "unlisted", "private"})
+		for _, visibility := range []string{"public", "unlisted", "private"} {
+			room := getRoomByName(t, visibility)
+			setRoomVisibility(t, room.ID, visibility)
+			rooms[visibility] = room
+		}
+
+		return rooms, createOtherUser(t, "other@example.com")
+	}
+
+	listedNames := func(t *testing.T, rr *httptest.ResponseRecorder) []string {
+		t.Helper()
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var rooms []pgstore.GetRoomsRow
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&rooms))
+
+		names := make([]string, 0, len(rooms))
+		for _, room := range rooms {
+			names = append(names, room.Name)
+		}
+		return names
+	}
+
+	t.Run("creates a room with the given visibility", func(t *testing.T) {
+		truncateData(t)
+
+		userID := generateUser(t)
+		payload := strings.NewReader(`{"name": "all-hands", "user_id": "` + userID + `", "visibility": "private"}`)
+		rr := execAuthenticatedRequest(t, http.MethodPost, baseURL, payload)
+		require.Equal(t, http.StatusCreated, rr.Code)
+
+		var result struct {
+			Visibility string `json:"visibility"`
+		}
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&result))
+		assert.Equal(t, "private", result.Visibility)
+	})
+
+	t.Run("lists the public rooms to everyone and the others to their owner", func(t *testing.T) {
+		_, other := setupRooms(t)
+
+		rr := execAuthenticatedRequest(t, http.MethodGet, baseURL, nil)
+		assert.Equal(t, []string{"public", "unlisted", "private"}, listedNames(t, rr))
+
+		rr = execRequestGeneratingSession(t, http.MethodGet, baseURL, nil, &other)
+		assert.Equal(t, []string{"public"}, listedNames(t, rr))
+	})
+
+	t.Run("opens unlisted rooms to anyone with the link", func(t *testing.T) {
+		rooms, other := setupRooms(t)
+		roomURL := baseURL + "/" + strconv.Itoa(int(rooms["unlisted"].ID))
+
+		rr := execRequestGeneratingSession(t, http.MethodGet, roomURL, nil, &other)
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		rr = execRequestGeneratingSession(t, http.MethodGet, roomURL+"/messages", nil, &other)
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("hides private rooms from users without access", func(t *testing.T) {
+		rooms, other := setupRooms(t)
+		roomURL := baseURL + "/" + strconv.Itoa(int(rooms["private"].ID))
+		msgID, _ := createAndGetMessages(t, rooms["private"].ID)
+
+		rr := execRequestGeneratingSession(t, http.MethodGet, roomURL, nil, &other)
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		assert.Equal(t, "room not found\n", rr.Body.String())
+
+		tests := []struct {
+			method string
+			url    string
+			body   string
+		}{
+			{method: http.MethodGet, url: roomURL + "/messages"},
+			{method: http.MethodPost, url: roomURL + "/messages", body: `{"message": "question"}`},
+			{method: http.MethodGet, url: roomURL + "/messages/" + msgID},
+			{method: http.MethodPatch, url: roomURL + "/messages/" + msgID + "/react", body: `{"user_id": "` + other.ID.String() + `"}`},
+			{method: http.MethodGet, url: roomURL + "/reactions?user_id=" + other.ID.String()},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.method+" "+strings.TrimPrefix(tt.url, roomURL), func(t *testing.T) {
+				rr := execRequestGeneratingSession(t, tt.method, tt.url, strings.NewReader(tt.body), &other)
+				assert.Equal(t, http.StatusBadRequest, rr.Code)
+				assert.Equal(t, "room not found\n", rr.Body.String())
+			})
+		}
+
+		rr = execAuthenticatedRequest(t, http.MethodGet, roomURL+"/messages", nil)
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("refuses room subscriptions to users without access", func(t *testing.T) {
+		rooms, other := setupRooms(t)
+
+		server := httptest.NewServer(Router)
+		defer server.Close()
+
+		otherID := other.ID.String()
+		wsURL := "ws" + server.URL[4:] + "/subscribe/room/" + strconv.Itoa(int(rooms["private"].ID))
+		_, response, err := connectWSWithUserSession(t, wsURL, &otherID)
+		require.Error(t, err)
+		assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+
+		ws, _, err := connectWSWithUserSession(t, "ws"+server.URL[4:]+"/subscribe/multiplex", &otherID)
+		require.NoError(t, err)
+		defer ws.Close()
+
+		sendWSCommand(t, ws, types.CommandKindSubscribe, "sub", types.TopicSubscription{Topic: types.RoomTopic(rooms["private"].ID)})
+		msg := readWSMessageOfKind(t, ws, types.MessageKindCommandError)
+		var commandError types.CommandError
+		decodeMessageValue(t, msg, &commandError)
+		assert.Equal(t, "room not found", commandError.Error)
+	})
+
+	t.Run("announces only public rooms to the rooms list", func(t *testing.T) {
+		truncateData(t)
+
+		server := httptest.NewServer(Router)
+		defer server.Close()
+
+		ws, err := connectAuthenticatedWS(t, "ws"+server.URL[4:]+"/subscribe")
+		require.NoError(t, err)
+		defer ws.Close()
+		waitForTopicSubscribers(t, types.TopicRooms, 1)
+
+		userID := generateUser(t)
+		for _, room := range []struct{ name, visibility string }{
+			{name: "secret", visibility: "private"},
+			{name: "open", visibility: "public"},
+		} {
+			payload := strings.NewReader(`{"name": "` + room.name + `", "user_id": "` + userID + `", "visibility": "` + room.visibility + `"}`)
+			rr := execAuthenticatedRequest(t, http.MethodPost, baseURL, payload)
+			require.Equal(t, http.StatusCreated, rr.Code)
+		}
+
+		msg := readWSMessage(t, ws)
+		var roomCreated types.RoomCreated
+		decodeMessageValue(t, msg, &roomCreated)
+		assert.Equal(t, types.MessageKindRoomCreated, msg.Kind)
+		assert.Equal(t, "open", roomCreated.Name)
+	})
+
+	t.Run("tells the rooms list when a room stops being public", func(t *testing.T) {
+		truncateData(t)
+
+		server := httptest.NewServer(Router)
+		defer server.Close()
+
+		room := createAndGetRoom(t)
+
+		ws, err := connectAuthenticatedWS(t, "ws"+server.URL[4:]+"/subscribe")
+		require.NoError(t, err)
+		defer ws.Close()
+		waitForTopicSubscribers(t, types.TopicRooms, 1)
+
+		payload := strings.NewReader(`{"visibility": "unlisted"}`)
+		rr := execAuthenticatedRequest(t, http.MethodPatch, baseURL+"/"+strconv.Itoa(int(room.ID)), payload)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		msg := readWSMessage(t, ws)
+		var roomHidden types.RoomHidden
+		decodeMessageValue(t, msg, &roomHidden)
+		assert.Equal(t, types.MessageKindRoomHidden, msg.Kind)
+		assert.Equal(t, room.ID, roomHidden.ID)
+	})
+
+	t.Run("returns an error if the visibility is not valid", func(t *testing.T) {
+		truncateData(t)
+
+		room := createAndGetRoom(t)
+		userID := generateUser(t)
+		expected := "validation failed: Visibility must be one of: public, unlisted, private\n"
+
+		payload := strings.NewReader(`{"name": "room", "user_id": "` + userID + `", "visibility": "secret"}`)
+		rr := execAuthenticatedRequest(t, http.MethodPost, baseURL, payload)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Equal(t, expected, rr.Body.String())
+
+		payload = strings.NewReader(`{"visibility": "secret"}`)
+		rr = execAuthenticatedRequest(t, http.MethodPatch, baseURL+"/"+strconv.Itoa(int(room.ID)), payload)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Equal(t, expected, rr.Body.String())
+	})
+}
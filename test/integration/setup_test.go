@@ -87,8 +87,9 @@ func setup() {
 	roomService := service.NewRoomService(q)
 	messageService := service.NewMessageService(q)
 	userService := service.NewUserService(q)
-	wsService := service.NewWebSocketService()
-	Handler = web.NewHandler(roomService, messageService, userService, wsService)
+	importService := service.NewImportService(DBPool, q)
+	wsService := service.NewWebSocketService(service.NewLocalBroadcaster(), service.NewMemoryEventLog(service.RoomEventLogSizeFromEnv()), service.NewMemoryPresenceStore())
+	Handler = web.NewHandler(roomService, messageService, userService, importService, wsService)
 	Router = router.SetupRouter(Handler, userService, &ValkeyClient)
 }
 
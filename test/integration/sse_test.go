@@ -0,0 +1,145 @@
+package api_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vhrboliveira/ama-go/internal/types"
+)
+
+func TestStreamRoom(t *testing.T) {
+	server := httptest.NewServer(Router)
+	defer server.Close()
+	baseURL := server.URL + "/subscribe/sse"
+
+	openStream := func(t *testing.T, url string, headers http.Header) *http.Response {
+		t.Helper()
+
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		require.NoError(t, err)
+		req.Header = headers
+
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { res.Body.Close() })
+
+		return res
+	}
+
+	readEvent := func(t *testing.T, reader *bufio.Reader) (id string, msg types.Message) {
+		t.Helper()
+
+		for {
+			line, err := reader.ReadString('\n')
+			require.NoError(t, err, "failed to read event stream")
+			line = strings.TrimSuffix(line, "\n")
+
+			switch {
+			case strings.HasPrefix(line, "id: "):
+				id = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "data: "):
+				require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &msg))
+			case line == "" && msg.Kind != "":
+				return id, msg
+			}
+		}
+	}
+
+	t.Run("streams room events", func(t *testing.T) {
+		truncateData(t)
+
+		room := createAndGetRoom(t)
+		headers := http.Header{}
+		headers.Add("Cookie", getSessionCookie(t, generateUser(t)))
+
+		res := openStream(t, baseURL+"/room/"+strconv.Itoa(int(room.ID)), headers)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+
+		waitForRoomSubscribers(t, room.ID, 1)
+
+		Handler.WebsocketService.NotifyRoomClient(types.Message{
+			Kind:   types.MessageKindMessageAnswered,
+			RoomID: room.ID,
+			Value:  types.MessageAnswered{ID: "message-id", Answer: "answer"},
+		})
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+
+			reader := bufio.NewReader(res.Body)
+
+			_, snapshot := readEvent(t, reader)
+			assert.Equal(t, types.MessageKindRoomSnapshot, snapshot.Kind)
+
+			id, msg := readEvent(t, reader)
+			assert.Equal(t, types.MessageKindMessageAnswered, msg.Kind)
+			assert.Equal(t, strconv.FormatInt(msg.Seq, 10), id)
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for the event")
+		}
+	})
+
+	t.Run("streams rooms list events", func(t *testing.T) {
+		truncateData(t)
+
+		headers := http.Header{}
+		headers.Add("Cookie", getSessionCookie(t, generateUser(t)))
+
+		res := openStream(t, baseURL, headers)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		waitForTopicSubscribers(t, types.TopicRooms, 1)
+
+		Handler.WebsocketService.NotifyRoomsListClients(types.Message{
+			Kind:  types.MessageKindRoomCreated,
+			Value: types.RoomCreated{ID: 1, Name: "room"},
+		})
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+
+			_, msg := readEvent(t, bufio.NewReader(res.Body))
+			assert.Equal(t, types.MessageKindRoomCreated, msg.Kind)
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for the event")
+		}
+	})
+
+	t.Run("returns unauthorized error if sessionID is not found", func(t *testing.T) {
+		res := openStream(t, baseURL, http.Header{})
+
+		assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+		assert.Equal(t, "unauthorized, session not found or invalid\n", parseResponseBody(t, res))
+	})
+
+	t.Run("returns an error if room does not exist", func(t *testing.T) {
+		truncateData(t)
+
+		headers := http.Header{}
+		headers.Add("Cookie", getSessionCookie(t, generateUser(t)))
+
+		res := openStream(t, baseURL+"/room/42", headers)
+
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+		assert.Equal(t, "room not found\n", parseResponseBody(t, res))
+	})
+}
@@ -5,11 +5,13 @@ import (
 	"net/http/httptest"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/vhrboliveira/ama-go/internal/types"
 )
 
 func TestSubscribeToRoom(t *testing.T) {
@@ -84,3 +86,119 @@ func TestSubscribeToRoom(t *testing.T) {
 		})
 	}
 }
+
+func TestSlowConsumerEviction(t *testing.T) {
+	server := httptest.NewServer(Router)
+	defer server.Close()
+
+	t.Run("closes the connection with a policy violation when the send queue overflows", func(t *testing.T) {
+		truncateData(t)
+
+		queueSize := Handler.WebsocketService.SendQueueSize
+		Handler.WebsocketService.SendQueueSize = 1
+		t.Cleanup(func() {
+			Handler.WebsocketService.SendQueueSize = queueSize
+		})
+
+		room := createAndGetRoom(t)
+		wsURL := "ws" + server.URL[4:] + "/subscribe/room/" + strconv.Itoa(int(room.ID))
+		ws, err := connectAuthenticatedWS(t, wsURL)
+		require.NoError(t, err)
+		defer ws.Close()
+
+		waitForRoomSubscribers(t, room.ID, 1)
+
+		for i := range 1000 {
+			Handler.WebsocketService.NotifyRoomClient(types.Message{
+				Kind:   types.MessageKindMessageCreated,
+				RoomID: room.ID,
+				Value:  types.MessageCreated{ID: strconv.Itoa(i), Message: "flood"},
+			})
+		}
+
+		ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+		for {
+			if _, _, err = ws.ReadMessage(); err != nil {
+				break
+			}
+		}
+
+		assert.True(t, websocket.IsCloseError(err, websocket.ClosePolicyViolation), "expected policy violation close, got %v", err)
+	})
+}
+
+func TestWebSocketHeartbeat(t *testing.T) {
+	server := httptest.NewServer(Router)
+	defer server.Close()
+
+	t.Run("pings connected clients", func(t *testing.T) {
+		truncateData(t)
+
+		pingInterval := Handler.WebsocketService.PingInterval
+		Handler.WebsocketService.PingInterval = 50 * time.Millisecond
+		t.Cleanup(func() {
+			Handler.WebsocketService.PingInterval = pingInterval
+		})
+
+		room := createAndGetRoom(t)
+		wsURL := "ws" + server.URL[4:] + "/subscribe/room/" + strconv.Itoa(int(room.ID))
+		ws, err := connectAuthenticatedWS(t, wsURL)
+		require.NoError(t, err)
+		defer ws.Close()
+
+		pinged := make(chan struct{}, 1)
+		ws.SetPingHandler(func(string) error {
+			select {
+			case pinged <- struct{}{}:
+			default:
+			}
+			return nil
+		})
+
+		go func() {
+			for {
+				if _, _, err := ws.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}()
+
+		select {
+		case <-pinged:
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for a ping")
+		}
+	})
+
+	t.Run("removes the subscriber as soon as the peer goes away", func(t *testing.T) {
+		truncateData(t)
+
+		room := createAndGetRoom(t)
+		wsURL := "ws" + server.URL[4:] + "/subscribe/room/" + strconv.Itoa(int(room.ID))
+		ws, err := connectAuthenticatedWS(t, wsURL)
+		require.NoError(t, err)
+
+		waitForRoomSubscribers(t, room.ID, 1)
+
+		require.NoError(t, ws.UnderlyingConn().Close())
+
+		waitForRoomSubscribers(t, room.ID, 0)
+	})
+
+	t.Run("removes the subscriber when the client sends a close frame", func(t *testing.T) {
+		truncateData(t)
+
+		room := createAndGetRoom(t)
+		wsURL := "ws" + server.URL[4:] + "/subscribe/room/" + strconv.Itoa(int(room.ID))
+		ws, err := connectAuthenticatedWS(t, wsURL)
+		require.NoError(t, err)
+		defer ws.Close()
+
+		waitForRoomSubscribers(t, room.ID, 1)
+
+		closeMessage := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "bye")
+		require.NoError(t, ws.WriteControl(websocket.CloseMessage, closeMessage, time.Now().Add(time.Second)))
+
+		waitForRoomSubscribers(t, room.ID, 0)
+	})
+}
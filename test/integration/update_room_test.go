@@ -0,0 +1,223 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vhrboliveira/ama-go/internal/types"
+)
+
+func setRoomOwner(t testing.TB, roomID int64, userID string) {
+	t.Helper()
+
+	_, err := DBPool.Exec(context.Background(), "UPDATE rooms SET user_id = $1 WHERE id = $2", userID, roomID)
+	require.NoError(t, err)
+}
+
+func TestUpdateRoom(t *testing.T) {
+	type customFn func(t testing.TB, method string, url string, body io.Reader) *httptest.ResponseRecorder
+
+	const (
+		baseURL = "/api/rooms/"
+		method  = http.MethodPatch
+	)
+
+	t.Run("updates the room name and description", func(t *testing.T) {
+		truncateData(t)
+
+		room := createAndGetRoom(t)
+		payload := strings.NewReader(`{"name": "  new name  ", "description": "new description"}`)
+		rr := execAuthenticatedRequest(t, method, baseURL+strconv.Itoa(int(room.ID)), payload)
+		response := rr.Result()
+		defer response.Body.Close()
+
+		var body types.RoomUpdated
+		require.NoError(t, json.NewDecoder(response.Body).Decode(&body))
+
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		assert.Equal(t, room.ID, body.ID)
+		assert.Equal(t, "new name", body.Name)
+		assert.Equal(t, "new description", body.Description)
+		assertValidDate(t, body.UpdatedAt)
+
+		updated := getRoomByName(t, "new name")
+		assert.Equal(t, "new description", updated.Description)
+	})
+
+	t.Run("keeps the fields that are not sent", func(t *testing.T) {
+		truncateData(t)
+
+		room := createAndGetRoom(t)
+		payload := strings.NewReader(`{"description": "only the description"}`)
+		rr := execAuthenticatedRequest(t, method, baseURL+strconv.Itoa(int(room.ID)), payload)
+		response := rr.Result()
+		defer response.Body.Close()
+
+		var body types.RoomUpdated
+		require.NoError(t, json.NewDecoder(response.Body).Decode(&body))
+
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		assert.Equal(t, room.Name, body.Name)
+		assert.Equal(t, "only the description", body.Description)
+	})
+
+	t.Run("notifies the rooms list subscribers", func(t *testing.T) {
+		truncateData(t)
+
+		server := httptest.NewServer(Router)
+		defer server.Close()
+
+		room := createAndGetRoom(t)
+
+		ws, err := connectAuthenticatedWS(t, "ws"+server.URL[4:]+"/subscribe")
+		require.NoError(t, err)
+		defer ws.Close()
+		waitForTopicSubscribers(t, types.TopicRooms, 1)
+
+		payload := strings.NewReader(`{"name": "renamed room"}`)
+		rr := execAuthenticatedRequest(t, method, baseURL+strconv.Itoa(int(room.ID)), payload)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		msg := readWSMessageOfKind(t, ws, types.MessageKindRoomUpdated)
+		var roomUpdated types.RoomUpdated
+		decodeMessageValue(t, msg, &roomUpdated)
+
+		assert.Equal(t, room.ID, roomUpdated.ID)
+		assert.Equal(t, "renamed room", roomUpdated.Name)
+	})
+
+	fakeRoomID := "999999"
+	longDescription := strings.Repeat("a", 256)
+
+	truncateData(t)
+	room := createAndGetRoom(t)
+	roomURL := baseURL + strconv.Itoa(int(room.ID))
+
+	errorTestCases := []struct {
+		name               string
+		fn                 customFn
+		payload            string
+		expectedMessage    string
+		expectedStatusCode int
+		url                string
+		setConstraint      func(t *testing.T)
+	}{
+		{
+			name: "returns unauthorized error if sessionID is not found",
+			fn: func(t testing.TB, method, url string, body io.Reader) *httptest.ResponseRecorder {
+				return execRequestWithoutCookie(method, url, body)
+			},
+			payload:            `{"name": "name"}`,
+			expectedMessage:    "unauthorized, session not found or invalid\n",
+			expectedStatusCode: http.StatusUnauthorized,
+			url:                roomURL,
+		},
+		{
+			name: "returns unauthorized error if cookie is different from the session",
+			fn: func(t testing.TB, method, url string, body io.Reader) *httptest.ResponseRecorder {
+				return execRequestWithInvalidCookie(method, url, body)
+			},
+			payload:            `{"name": "name"}`,
+			expectedMessage:    "unauthorized, session not found or invalid\n",
+			expectedStatusCode: http.StatusUnauthorized,
+			url:                roomURL,
+		},
+		{
+			name:               "returns an error if room id is not valid",
+			fn:                 execAuthenticatedRequest,
+			payload:            `{"name": "name"}`,
+			expectedMessage:    "invalid room id\n",
+			expectedStatusCode: http.StatusBadRequest,
+			url:                baseURL + "invalid_room_id",
+		},
+		{
+			name:               "returns an error if body is not valid",
+			fn:                 execAuthenticatedRequest,
+			payload:            `{"name": 1}`,
+			expectedMessage:    "invalid body\n",
+			expectedStatusCode: http.StatusBadRequest,
+			url:                roomURL,
+		},
+		{
+			name:               "returns an error if no field is provided",
+			fn:                 execAuthenticatedRequest,
+			payload:            `{}`,
+			expectedMessage:    "validation failed, missing required field(s): Name, Description, Visibility or Slug\n",
+			expectedStatusCode: http.StatusBadRequest,
+			url:                roomURL,
+		},
+		{
+			name:               "returns an error if name is empty",
+			fn:                 execAuthenticatedRequest,
+			payload:            `{"name": "   "}`,
+			expectedMessage:    "validation failed: name cannot be empty\n",
+			expectedStatusCode: http.StatusBadRequest,
+			url:                roomURL,
+		},
+		{
+			name:               "returns an error if description is too long",
+			fn:                 execAuthenticatedRequest,
+			payload:            `{"description": "` + longDescription + `"}`,
+			expectedMessage:    "validation failed: description must have at most 255 characters\n",
+			expectedStatusCode: http.StatusBadRequest,
+			url:                roomURL,
+		},
+		{
+			name:               "returns an error if room does not exist",
+			fn:                 execAuthenticatedRequest,
+			payload:            `{"name": "name"}`,
+			expectedMessage:    "room not found\n",
+			expectedStatusCode: http.StatusBadRequest,
+			url:                baseURL + fakeRoomID,
+		},
+		{
+			name:               "returns an error if the user is not the room owner",
+			fn:                 execAuthenticatedRequest,
+			payload:            `{"name": "name"}`,
+			expectedMessage:    "only the room owner can change the room\n",
+			expectedStatusCode: http.StatusForbidden,
+			url:                roomURL,
+			setConstraint: func(t *testing.T) {
+				otherUserID := createUser(t, "other@example.com", "other user", "google", "0987654321", "")
+				setRoomOwner(t, room.ID, otherUserID)
+			},
+		},
+		{
+			name:               "returns an error if fails to get room",
+			fn:                 execAuthenticatedRequest,
+			payload:            `{"name": "name"}`,
+			expectedMessage:    "error validating room ID\n",
+			expectedStatusCode: http.StatusInternalServerError,
+			url:                roomURL,
+			setConstraint: func(t *testing.T) {
+				setRoomsConstraintFailure(t)
+			},
+		},
+	}
+
+	for _, tc := range errorTestCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.setConstraint != nil {
+				tc.setConstraint(t)
+			}
+
+			payload := strings.NewReader(tc.payload)
+			rr := tc.fn(t, method, tc.url, payload)
+			response := rr.Result()
+			defer response.Body.Close()
+
+			body := parseResponseBody(t, response)
+
+			assert.Equal(t, tc.expectedStatusCode, response.StatusCode)
+			assert.Equal(t, tc.expectedMessage, body)
+		})
+	}
+}
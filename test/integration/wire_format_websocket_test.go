@@ -0,0 +1,130 @@
+package api_test
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vhrboliveira/ama-go/internal/service"
+	"github.com/vhrboliveira/ama-go/internal/types"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func TestWebSocketWireFormat(t *testing.T) {
+	server := httptest.NewServer(Router)
+	defer server.Close()
+
+	connect := func(t *testing.T, roomID int64, subprotocols ...string) *websocket.Conn {
+		t.Helper()
+
+		dialer := &websocket.Dialer{
+			Subprotocols:      subprotocols,
+			EnableCompression: true,
+			HandshakeTimeout:  websocket.DefaultDialer.HandshakeTimeout,
+		}
+
+		userID := generateUser(t)
+		wsURL := "ws" + server.URL[4:] + "/subscribe/room/" + strconv.Itoa(int(roomID))
+		ws, _, err := connectWSWithDialer(t, dialer, wsURL, &userID)
+		require.NoError(t, err)
+		t.Cleanup(func() { ws.Close() })
+
+		waitForRoomSubscribers(t, roomID, 1)
+
+		return ws
+	}
+
+	notifyMessageAnswered := func(roomID int64) {
+		Handler.WebsocketService.NotifyRoomClient(types.Message{
+			Kind:   types.MessageKindMessageAnswered,
+			RoomID: roomID,
+			Value:  types.MessageAnswered{ID: "message-id", Answer: "the answer"},
+		})
+	}
+
+	readMsgpackOfKind := func(t *testing.T, ws *websocket.Conn, kind string) types.Message {
+		t.Helper()
+
+		for {
+			ws.SetReadDeadline(time.Now().Add(2 * time.Second))
+			messageType, p, err := ws.ReadMessage()
+			require.NoError(t, err)
+			require.Equal(t, websocket.BinaryMessage, messageType)
+
+			dec := msgpack.NewDecoder(bytes.NewReader(p))
+			dec.SetCustomStructTag("json")
+
+			var msg types.Message
+			require.NoError(t, dec.Decode(&msg))
+
+			if msg.Kind == kind {
+				return msg
+			}
+		}
+	}
+
+	t.Run("sends JSON text frames by default", func(t *testing.T) {
+		truncateData(t)
+
+		room := createAndGetRoom(t)
+		ws := connect(t, room.ID)
+		assert.Empty(t, ws.Subprotocol())
+
+		notifyMessageAnswered(room.ID)
+
+		msg := readWSMessageOfKind(t, ws, types.MessageKindMessageAnswered)
+		assert.Equal(t, types.MessageKindMessageAnswered, msg.Kind)
+	})
+
+	t.Run("negotiates the JSON subprotocol", func(t *testing.T) {
+		truncateData(t)
+
+		room := createAndGetRoom(t)
+		ws := connect(t, room.ID, service.SubprotocolJSON)
+		assert.Equal(t, service.SubprotocolJSON, ws.Subprotocol())
+
+		notifyMessageAnswered(room.ID)
+
+		msg := readWSMessageOfKind(t, ws, types.MessageKindMessageAnswered)
+		assert.Equal(t, types.MessageKindMessageAnswered, msg.Kind)
+	})
+
+	t.Run("sends and receives MessagePack binary frames", func(t *testing.T) {
+		truncateData(t)
+
+		room := createAndGetRoom(t)
+		ws := connect(t, room.ID, service.SubprotocolMsgpack)
+		assert.Equal(t, service.SubprotocolMsgpack, ws.Subprotocol())
+
+		notifyMessageAnswered(room.ID)
+
+		msg := readMsgpackOfKind(t, ws, types.MessageKindMessageAnswered)
+		assert.Equal(t, types.RoomTopic(room.ID), msg.Topic)
+		assert.NotZero(t, msg.Seq)
+
+		value, ok := msg.Value.(map[string]any)
+		require.True(t, ok, "expected the message value to be a map")
+		assert.Equal(t, "message-id", value["id"])
+		assert.Equal(t, "the answer", value["answer"])
+
+		var buf bytes.Buffer
+		enc := msgpack.NewEncoder(&buf)
+		enc.SetCustomStructTag("json")
+		require.NoError(t, enc.Encode(map[string]any{
+			"kind":       types.CommandKindAsk,
+			"request_id": "req-1",
+			"value":      map[string]string{"message": "is this binary?"},
+		}))
+		require.NoError(t, ws.WriteMessage(websocket.BinaryMessage, buf.Bytes()))
+
+		ack := readMsgpackOfKind(t, ws, types.MessageKindCommandAck)
+		ackValue, ok := ack.Value.(map[string]any)
+		require.True(t, ok, "expected the ack value to be a map")
+		assert.Equal(t, "req-1", ackValue["request_id"])
+	})
+}